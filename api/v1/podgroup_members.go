@@ -0,0 +1,114 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"fmt"
+	"slices"
+)
+
+// MemberName 返回模板template第index个副本的成员Pod名称
+func MemberName(template string, index int) string {
+	return fmt.Sprintf("%s-%d", template, index)
+}
+
+// IsReplicated 模板是否设置了replicas
+func (t *PodTemplate) IsReplicated() bool {
+	return t.Replicas != nil
+}
+
+// IsWorkload 成员Pod是否由Deployment、StatefulSet或者Job等工作负载创建
+func (t *PodTemplate) IsWorkload() bool {
+	return t.Workload != "" && t.Workload != PodWorkload
+}
+
+// MemberNames 返回根据该模板创建的全部成员Pod名称
+func (t *PodTemplate) MemberNames() []string {
+	if !t.IsReplicated() {
+		return []string{t.Metadata.Name}
+	}
+	res := make([]string, 0, *t.Replicas)
+	for i := 0; i < int(*t.Replicas); i++ {
+		res = append(res, MemberName(t.Metadata.Name, i))
+	}
+	return res
+}
+
+// Members 将podList中的模板按照replicas展开为成员Pod模板，返回的模板不设置replicas，metadata.name为成员Pod名称
+// 结果的顺序与podList一致，同一模板的副本按照下标排列
+func (s *PodGroupSpec) Members() []PodTemplate {
+	res := make([]PodTemplate, 0, s.MemberCount())
+	for i := range s.PodList {
+		template := &s.PodList[i]
+		for _, name := range template.MemberNames() {
+			member := *template.DeepCopy()
+			member.Metadata.Name = name
+			member.Replicas = nil
+			res = append(res, member)
+		}
+	}
+	return res
+}
+
+// MemberCount 返回成员Pod的数量
+func (s *PodGroupSpec) MemberCount() int {
+	count := 0
+	for i := range s.PodList {
+		if s.PodList[i].IsReplicated() {
+			count += int(*s.PodList[i].Replicas)
+		} else {
+			count++
+		}
+	}
+	return count
+}
+
+// FindTemplate 返回名称为name的模板，不存在时返回nil
+func (s *PodGroupSpec) FindTemplate(name string) *PodTemplate {
+	for i := range s.PodList {
+		if s.PodList[i].Metadata.Name == name {
+			return &s.PodList[i]
+		}
+	}
+	return nil
+}
+
+// ResolveMembers 返回模板name中下标为index的成员Pod名称，index为nil时返回该模板的全部成员Pod
+// 模板不存在、下标越界或者对没有设置replicas的模板指定下标时返回nil
+func (s *PodGroupSpec) ResolveMembers(name string, index *int32) []string {
+	template := s.FindTemplate(name)
+	if template == nil {
+		return nil
+	}
+	if index == nil {
+		return template.MemberNames()
+	}
+	if !template.IsReplicated() || *index < 0 || *index >= *template.Replicas {
+		return nil
+	}
+	return []string{MemberName(name, int(*index))}
+}
+
+// TemplateOf 返回成员Pod member所属的模板，不存在时返回nil
+func (s *PodGroupSpec) TemplateOf(member string) *PodTemplate {
+	for i := range s.PodList {
+		if slices.Contains(s.PodList[i].MemberNames(), member) {
+			return &s.PodList[i]
+		}
+	}
+	return nil
+}
@@ -18,14 +18,51 @@ package v1
 
 import (
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// PodGroup的生命周期: Pending -> Scheduling -> Scheduled -> Running -> Succeeded/Failed
 const (
-	ScheduledPhase  = "Scheduled"
+	// PendingPhase PodGroup已被controller接收，尚未开始placement
+	PendingPhase = "Pending"
+	// SchedulingPhase 正在计算placement或者成员Pod尚未全部绑定节点
 	SchedulingPhase = "Scheduling"
-	FailedPhase     = "Failed"
-	DeletedPhase    = "Deleted"
+	// ScheduledPhase 所有成员Pod均已绑定节点
+	ScheduledPhase = "Scheduled"
+	// RunningPhase 所有成员Pod均已绑定节点并处于运行状态
+	RunningPhase = "Running"
+	// SucceededPhase 所有成员Pod均已成功结束
+	SucceededPhase = "Succeeded"
+	FailedPhase    = "Failed"
+	DeletedPhase   = "Deleted"
+)
+
+// PodGroup的Condition类型
+const (
+	// PlacementComputedCondition 已根据节点延迟计算出placement
+	PlacementComputedCondition = "PlacementComputed"
+	// PodsCreatedCondition 所有成员Pod均已创建
+	PodsCreatedCondition = "PodsCreated"
+	// AllPodsBoundCondition 所有成员Pod均已绑定节点
+	AllPodsBoundCondition = "AllPodsBound"
+	// AllPodsReadyCondition 所有成员Pod均处于Ready状态
+	AllPodsReadyCondition = "AllPodsReady"
+	// GangScheduledCondition 至少spec.minMember个成员Pod已经绑定节点，变为True之后不再改变
+	GangScheduledCondition = "GangScheduled"
+	// ReplayInputsMatchedCondition 复现placement时求解输入的摘要与原placement的摘要相同，
+	// False表示节点延迟、节点剩余资源等输入已经变化，相同的种子也无法复现原placement
+	ReplayInputsMatchedCondition = "ReplayInputsMatched"
+)
+
+// 用于复现placement的PodGroup注解，取值从需要复现的PodGroup的status.placement中复制
+const (
+	// ReplaySeedAnnotation 随机求解算法使用该随机数种子而不是新生成的种子，取值为十进制整数
+	ReplaySeedAnnotation = "core.cic.io/replay-seed"
+	// ReplayWindowEndAnnotation 求解使用截止到该时间的节点延迟而不是最近的节点延迟，取值为RFC3339格式的时间
+	ReplayWindowEndAnnotation = "core.cic.io/replay-window-end"
+	// ReplayInputDigestAnnotation 原placement的输入摘要，复现时与本次求解输入的摘要比较，结果记录在ReplayInputsMatched Condition中
+	ReplayInputDigestAnnotation = "core.cic.io/replay-input-digest"
 )
 
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
@@ -42,22 +79,294 @@ type PodGroupSpec struct {
 	Dependencies []Dependency  `json:"dependencies,omitempty"`
 	// +optional
 	NodeNum int `json:"nodeNum,omitempty"`
+	// PlacementStrategy 选择placement求解算法，未设置时使用greedy
+	// +optional
+	PlacementStrategy PlacementStrategy `json:"placementStrategy,omitempty"`
+	// SolverOptions 各求解算法的参数，未设置的参数使用默认值
+	// +optional
+	SolverOptions *SolverOptions `json:"solverOptions,omitempty"`
+	// MinMember 至少需要同时调度成功的成员Pod数量，未设置时为全部成员Pod
+	// 设置了MinMember时，集群剩余资源放不下MinMember个Pod则不会创建任何Pod
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MinMember *int32 `json:"minMember,omitempty"`
+	// ScheduleTimeoutSeconds 等待MinMember个成员Pod绑定节点的超时时间，超时后删除已经创建的成员Pod，PodGroup进入Failed阶段
+	// 未设置时一直等待
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	ScheduleTimeoutSeconds *int32 `json:"scheduleTimeoutSeconds,omitempty"`
+	// PlacementEnforcement placement结果约束成员Pod的方式，未设置时为required
+	// +kubebuilder:default=required
+	// +optional
+	PlacementEnforcement PlacementEnforcement `json:"placementEnforcement,omitempty"`
+	// PreferredNeighbors preferred模式下除计划节点外额外偏好的低延迟节点数量，
+	// podAffinity渲染方式下每个Pod偏好的依赖Pod数量，未设置时为3
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=10
+	// +optional
+	PreferredNeighbors *int32 `json:"preferredNeighbors,omitempty"`
+	// PlacementRendering 将placement渲染为成员Pod亲和性的方式，未设置时为nodeAffinity
+	// +kubebuilder:default=nodeAffinity
+	// +optional
+	PlacementRendering PlacementRendering `json:"placementRendering,omitempty"`
+	// TopologyKey 节点拓扑域标签，例如topology.kubernetes.io/zone，未设置时使用controller的--topology-key参数
+	// 设置后没有延迟采样数据的节点对使用拓扑域之间的延迟，podAffinity渲染方式下作为Pod亲和性的topologyKey
+	// +optional
+	TopologyKey string `json:"topologyKey,omitempty"`
+	// TopologyAggregation 为true时所有节点对都使用拓扑域之间延迟的中位数，即在拓扑域粒度上求解placement，适用于节点延迟噪声较大的集群
+	// 未设置topologyKey时不生效
+	// +optional
+	TopologyAggregation bool `json:"topologyAggregation,omitempty"`
+	// AntiAffinity 互斥的Pod分组，placement不会将同一组内的Pod分配到同一个节点
+	// +optional
+	AntiAffinity []AntiAffinityGroup `json:"antiAffinity,omitempty"`
+	// MaxPodsPerNode placement在每个节点上最多分配的成员Pod数量，未设置时不限制
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxPodsPerNode *int32 `json:"maxPodsPerNode,omitempty"`
+	// ServiceMode controller为依赖中接收流量的成员Pod创建Service的方式，未设置时为none
+	// 只有容器声明了ports的成员Pod才会创建Service，Service与成员Pod一样由PodGroup拥有，随PodGroup一起删除
+	// +kubebuilder:default=none
+	// +optional
+	ServiceMode ServiceMode `json:"serviceMode,omitempty"`
+	// Rescheduling 节点延迟发生变化后重新评估placement并逐个迁移成员Pod的策略，未设置时placement只在创建时计算一次
+	// +optional
+	Rescheduling *ReschedulingPolicy `json:"rescheduling,omitempty"`
+}
+
+// ReschedulingPolicy controller周期性地使用最新的节点延迟重新求解处于Running阶段的PodGroup，
+// 新placement的代价相比成员Pod当前位置的代价降低足够多时，每次迁移一个成员Pod
+type ReschedulingPolicy struct {
+	// ImprovementThreshold 新placement相比当前位置代价降低的比例超过该值时才迁移成员Pod，取值范围(0, 1)，未设置时为0.2
+	// +optional
+	ImprovementThreshold *resource.Quantity `json:"improvementThreshold,omitempty"`
+	// MaxUnavailable 与PodDisruptionBudget类似，迁移时最多允许多少个成员Pod处于不可用（未Ready）状态，未设置时为1
+	// 裸Pod成员通过Eviction迁移，因此同样遵守集群中已有的PodDisruptionBudget
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxUnavailable *int32 `json:"maxUnavailable,omitempty"`
+}
+
+// ServiceMode 表示controller为成员Pod创建Service的方式
+// +kubebuilder:validation:Enum=none;perMember;perRole
+type ServiceMode string
+
+const (
+	// NoneServiceMode 不创建Service
+	NoneServiceMode ServiceMode = "none"
+	// PerMemberServiceMode 为每个成员Pod创建一个与Pod同名的headless Service，
+	// 依赖方可以通过<pod>.<namespace>.svc稳定地访问某一个副本
+	PerMemberServiceMode ServiceMode = "perMember"
+	// PerRoleServiceMode 为podList中的每个模板创建一个与模板同名的Service，在该模板的全部副本之间负载均衡
+	PerRoleServiceMode ServiceMode = "perRole"
+)
+
+// AntiAffinityGroup 一组互斥的Pod，例如同一角色的多个副本
+type AntiAffinityGroup struct {
+	// Name 分组名称，仅用于展示
+	// +optional
+	Name string `json:"name,omitempty"`
+	// Pods podList中的模板名称，设置了replicas的模板表示其全部副本
+	Pods []string `json:"pods"`
+}
+
+// PlacementRendering 表示将placement渲染为成员Pod亲和性的方式
+// +kubebuilder:validation:Enum=nodeAffinity;podAffinity
+type PlacementRendering string
+
+const (
+	// NodeAffinityRendering 按照placementEnforcement通过节点亲和性将Pod约束到计划节点
+	NodeAffinityRendering PlacementRendering = "nodeAffinity"
+	// PodAffinityRendering 不依赖计划节点，每个Pod通过preferredDuringScheduling Pod亲和性偏好其通信权重最大的依赖所在的拓扑域
+	// 成员Pod带有controller设置的标签，使用默认调度器即可生效，节点替换后仍然有效，此时placementEnforcement不生效
+	PodAffinityRendering PlacementRendering = "podAffinity"
+)
+
+// PlacementEnforcement 表示placement结果约束成员Pod的方式
+// +kubebuilder:validation:Enum=required;preferred;none
+type PlacementEnforcement string
+
+const (
+	// RequiredEnforcement 通过requiredDuringScheduling节点亲和性将Pod绑定到计划节点，资源不足时Pod保持Pending
+	RequiredEnforcement PlacementEnforcement = "required"
+	// PreferredEnforcement 通过带权重的preferredDuringScheduling节点亲和性优先选择计划节点，其次是与计划节点延迟最低的节点
+	PreferredEnforcement PlacementEnforcement = "preferred"
+	// NoneEnforcement 不设置节点亲和性，只在status中记录计划节点，由podGroup-scheduler打分时参考
+	NoneEnforcement PlacementEnforcement = "none"
+)
+
+// PlacementStrategy 表示placement求解算法
+// +kubebuilder:validation:Enum=greedy;annealing;relative-improvement;exhaustive;branch-and-bound;partitioning
+type PlacementStrategy string
+
+const (
+	// GreedyStrategy 按度数贪心分配
+	GreedyStrategy PlacementStrategy = "greedy"
+	// AnnealingStrategy 模拟退火
+	AnnealingStrategy PlacementStrategy = "annealing"
+	// RelativeImprovementStrategy 基于归一化目标函数的模拟退火
+	RelativeImprovementStrategy PlacementStrategy = "relative-improvement"
+	// ExhaustiveStrategy 暴力枚举，仅适用于规模很小的PodGroup
+	ExhaustiveStrategy PlacementStrategy = "exhaustive"
+	// BranchAndBoundStrategy 分支定界精确求解通信延迟代价最低的placement，适用于几十个Pod以内的PodGroup
+	BranchAndBoundStrategy PlacementStrategy = "branch-and-bound"
+	// PartitioningStrategy 多层图划分，按照依赖关系将Pod划分到节点上，适用于成员Pod数量较多的PodGroup
+	PartitioningStrategy PlacementStrategy = "partitioning"
+)
+
+type SolverOptions struct {
+	// +optional
+	Annealing *AnnealingOptions `json:"annealing,omitempty"`
+	// +optional
+	RelativeImprovement *AnnealingOptions `json:"relativeImprovement,omitempty"`
+	// +optional
+	Exhaustive *ExhaustiveOptions `json:"exhaustive,omitempty"`
+	// +optional
+	BranchAndBound *BranchAndBoundOptions `json:"branchAndBound,omitempty"`
+	// +optional
+	Partitioning *PartitioningOptions `json:"partitioning,omitempty"`
+}
+
+// AnnealingOptions 模拟退火参数，小数类型的参数使用Quantity表示，例如 "0.95"
+type AnnealingOptions struct {
+	// MaxIter 最大迭代次数
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxIter *int32 `json:"maxIter,omitempty"`
+	// InitTemp 初始温度
+	// +optional
+	InitTemp *resource.Quantity `json:"initTemp,omitempty"`
+	// FinalTemp 终止温度
+	// +optional
+	FinalTemp *resource.Quantity `json:"finalTemp,omitempty"`
+	// CoolingRate 降温系数，取值范围(0, 1)
+	// +optional
+	CoolingRate *resource.Quantity `json:"coolingRate,omitempty"`
+	// Alpha 目标函数中延迟项的权重
+	// +optional
+	Alpha *resource.Quantity `json:"alpha,omitempty"`
+	// Beta 目标函数中资源均衡项的权重
+	// +optional
+	Beta *resource.Quantity `json:"beta,omitempty"`
+	// Chains 并行运行的相互独立的退火链数量，结果取所有链中目标函数最小的placement，未设置时为4
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=64
+	// +optional
+	Chains *int32 `json:"chains,omitempty"`
+	// TimeLimitSeconds 求解的最长时间，未设置时为2秒。每条链由maxIter与温度决定何时停止，相同的种子得到相同的placement，
+	// 时间限制只是上限，超出时所有链停止并使用已经找到的最优placement，此时placement无法复现
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	TimeLimitSeconds *int32 `json:"timeLimitSeconds,omitempty"`
+}
+
+// ExhaustiveOptions 暴力枚举参数
+type ExhaustiveOptions struct {
+	// Alpha 目标函数中延迟项的权重
+	// +optional
+	Alpha *resource.Quantity `json:"alpha,omitempty"`
+	// Beta 目标函数中资源均衡项的权重
+	// +optional
+	Beta *resource.Quantity `json:"beta,omitempty"`
+	// MaxPods 允许枚举的最大Pod数量，超出时拒绝求解
+	// 不超过MaxPods时，全部assign的数量(节点数量的Pod数量次方)超过一千万同样拒绝求解
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxPods *int32 `json:"maxPods,omitempty"`
+}
+
+// BranchAndBoundOptions 分支定界参数，搜索超出预算时返回目前找到的最优placement以及最优性差距
+type BranchAndBoundOptions struct {
+	// MaxNodes 最多展开的搜索树节点数量，未设置时为1000000
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxNodes *int32 `json:"maxNodes,omitempty"`
+	// TimeLimitSeconds 求解的最长时间，未设置时为5秒
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	TimeLimitSeconds *int32 `json:"timeLimitSeconds,omitempty"`
+}
+
+// PartitioningOptions 多层图划分参数
+type PartitioningOptions struct {
+	// RefinementPasses 每一层FM优化以及最后Pod粒度延迟优化的最大轮数，未设置时为10
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	RefinementPasses *int32 `json:"refinementPasses,omitempty"`
 }
 
 // PodTemplate 由于kubernetes禁止使用v1.Pod中的Metadata嵌套，因此这里我���自行定义
 type PodTemplate struct {
 	Metadata PodMetadata `json:"metadata,omitempty"`
-	Spec     v1.PodSpec  `json:"spec,omitempty"`
+	// Replicas 根据该模板创建的成员Pod数量，成员Pod依次命名为<metadata.name>-0、<metadata.name>-1 ...
+	// 未设置时只创建一个名为metadata.name的成员Pod
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+	// Workload 承载成员Pod的工作负载类型，未设置时为Pod，即由controller直接创建成员Pod
+	// 设置为Deployment、StatefulSet或者Job时，每个成员Pod对应一个与成员同名、只有一个副本的工作负载，
+	// placement通过工作负载Pod模板中的亲和性生效，成员Pod的重建与滚动更新由工作负载自身负责
+	// +kubebuilder:default=Pod
+	// +optional
+	Workload WorkloadKind `json:"workload,omitempty"`
+	Spec     v1.PodSpec   `json:"spec,omitempty"`
 }
 
+// WorkloadKind 表示承载成员Pod的工作负载类型
+// +kubebuilder:validation:Enum=Pod;Deployment;StatefulSet;Job
+type WorkloadKind string
+
+const (
+	// PodWorkload controller直接创建成员Pod，失败的成员Pod由controller重建
+	PodWorkload WorkloadKind = "Pod"
+	// DeploymentWorkload 成员Pod由replicas为1的Deployment创建，修改模板时滚动更新
+	DeploymentWorkload WorkloadKind = "Deployment"
+	// StatefulSetWorkload 成员Pod由replicas为1的StatefulSet创建，serviceName为成员名称
+	StatefulSetWorkload WorkloadKind = "StatefulSet"
+	// JobWorkload 成员Pod由completions为1的Job创建，按照Job的backoffLimit重试，创建后模板不可修改
+	JobWorkload WorkloadKind = "Job"
+)
+
 type PodMetadata struct {
 	Name   string            `json:"name,omitempty"`
 	Labels map[string]string `json:"labels,omitempty"`
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
 }
 
+// DependencyDirection 表示依赖的通信方向
+// +kubebuilder:validation:Enum=Both;P1ToP2;P2ToP1
+type DependencyDirection string
+
+const (
+	// DirectionBoth 双向通信，P1与P2互相发送流量（默认值）
+	DirectionBoth DependencyDirection = "Both"
+	// DirectionP1ToP2 单向通信，流量由P1发往P2
+	DirectionP1ToP2 DependencyDirection = "P1ToP2"
+	// DirectionP2ToP1 单向通信，流量由P2发往P1
+	DirectionP2ToP1 DependencyDirection = "P2ToP1"
+)
+
+// Dependency 两个成员Pod之间的通信依赖
+// P1、P2为podList中的模板名称，模板设置了replicas时，未设置下标表示该模板的全部副本，
+// 依赖会展开为两侧成员Pod两两之间的依赖，每一对使用相同的权重；同一模板的全部副本之间也可以互相依赖
 type Dependency struct {
 	P1 string `json:"p1,omitempty"`
-	P2 string `json:"p2,omitempty"`
+	// P1Index P1模板中副本的下标，只能用于设置了replicas的模板
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	P1Index *int32 `json:"p1Index,omitempty"`
+	P2      string `json:"p2,omitempty"`
+	// P2Index P2模板中副本的下标，只能用于设置了replicas的模板
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	P2Index *int32 `json:"p2Index,omitempty"`
+	// Weight 表示该依赖的通信权重，例如预估的请求数/s或者字节数/s，未设置时默认为1
+	// +optional
+	Weight *resource.Quantity `json:"weight,omitempty"`
+	// Direction 表示通信方向，未设置时默认为Both
+	// +optional
+	Direction DependencyDirection `json:"direction,omitempty"`
 }
 
 // PodGroupStatus defines the observed state of PodGroup.
@@ -66,21 +375,132 @@ type PodGroupStatus struct {
 	// Important: Run "make" to regenerate code after modifying this file
 
 	// Phase 表示 PodGroup 的调度状态
-	// 可选值: "Scheduling", "Scheduled", "Failed"
-	// +kubebuilder:validation:Enum=Scheduling;Scheduled;Failed
+	// 可选值: "Pending", "Scheduling", "Scheduled", "Running", "Succeeded", "Failed"
+	// +kubebuilder:validation:Enum=Pending;Scheduling;Scheduled;Running;Succeeded;Failed
 	Phase string `json:"phase,omitempty"`
+	// ObservedGeneration 为controller最近一次处理的spec的generation
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions 表示PodGroup生命周期中各个阶段的状态
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// ScheduleStartTime 为PodGroup进入Scheduling阶段的时间，用于计算spec.scheduleTimeoutSeconds
+	// +optional
+	ScheduleStartTime *metav1.Time `json:"scheduleStartTime,omitempty"`
+	// Placement 为placement的求解信息
+	// +optional
+	Placement *PlacementStatus `json:"placement,omitempty"`
+	// ScheduleResult 为每个成员Pod的计划节点与实际绑定情况
 	// +optional
 	ScheduleResult []PodNodeBinding `json:"scheduleResult,omitempty"`
+	// Rescheduling 为最近一次重新评估placement的结果以及成员Pod的迁移记录
+	// +optional
+	Rescheduling *ReschedulingStatus `json:"rescheduling,omitempty"`
+}
+
+// ReschedulingStatus 记录重新评估placement的结果
+type ReschedulingStatus struct {
+	// LastEvaluationTime 最近一次写入评估结果的时间，评估结果与上一次相同并且没有迁移时不更新
+	// +optional
+	LastEvaluationTime *metav1.Time `json:"lastEvaluationTime,omitempty"`
+	// CurrentCost 成员Pod当前位置在最新节点延迟下的通信延迟代价
+	// +optional
+	CurrentCost *resource.Quantity `json:"currentCost,omitempty"`
+	// TargetCost 使用最新节点延迟重新求解得到的placement的通信延迟代价
+	// +optional
+	TargetCost *resource.Quantity `json:"targetCost,omitempty"`
+	// Migrations 最近的迁移记录，按照时间从早到晚排列，最多保留10条
+	// +optional
+	Migrations []Migration `json:"migrations,omitempty"`
+}
+
+// Migration 一次成员Pod迁移的记录
+type Migration struct {
+	PodName  string      `json:"podName"`
+	FromNode string      `json:"fromNode"`
+	ToNode   string      `json:"toNode"`
+	Time     metav1.Time `json:"time"`
+	// Reason 迁移的原因，包括迁移前后的预估代价
+	Reason string `json:"reason"`
+}
+
+// PlacementStatus 记录placement是如何得到的
+type PlacementStatus struct {
+	// Strategy 为实际得到placement的求解算法，指定的算法求解失败时为退化后使用的greedy
+	// +optional
+	Strategy PlacementStrategy `json:"strategy,omitempty"`
+	// Fallback 为true表示placement求解失败，所有成员Pod均由默认调度器决定位置
+	// +optional
+	Fallback bool `json:"fallback,omitempty"`
+	// PredictedCost 为placement预估的通信延迟代价，即依赖的通信权重与计划节点之间延迟的乘积之和
+	// +optional
+	PredictedCost *resource.Quantity `json:"predictedCost,omitempty"`
+	// OptimalityGap 为精确求解算法给出的最优性差距，即预估代价与已证明的下界之差占预估代价的比例，
+	// 0表示placement已被证明最优，其他求解算法不设置
+	// +optional
+	OptimalityGap *resource.Quantity `json:"optimalityGap,omitempty"`
+	// Seed 为求解使用的随机数种子，只有annealing与relative-improvement使用随机数
+	// +optional
+	Seed *int64 `json:"seed,omitempty"`
+	// LatencyWindowEnd 为求解使用的节点延迟时间窗口的结束时间
+	// +optional
+	LatencyWindowEnd *metav1.Time `json:"latencyWindowEnd,omitempty"`
+	// InputDigest 为求解输入的摘要，包括成员Pod、依赖、候选节点的剩余资源、节点延迟以及硬约束，
+	// 使用相同的种子复现时，输入摘要相同则得到相同的placement
+	// +optional
+	InputDigest string `json:"inputDigest,omitempty"`
+	// Chains 为annealing与relative-improvement中每条退火链的收敛情况
+	// +optional
+	Chains []AnnealingChainStatus `json:"chains,omitempty"`
+	// MatchedPods 为实际绑定节点与计划节点一致的Pod数量
+	// +optional
+	MatchedPods int32 `json:"matchedPods,omitempty"`
+}
+
+// AnnealingChainStatus 一条退火链的收敛情况
+type AnnealingChainStatus struct {
+	// Seed 为该链使用的随机数种子，第i条链的种子为placement.seed+i
+	Seed int64 `json:"seed"`
+	// Iterations 为实际运行的迭代次数
+	Iterations int32 `json:"iterations"`
+	// BestIteration 为找到该链最优解时的迭代次数，0表示初始解即为最优
+	// +optional
+	BestIteration int32 `json:"bestIteration,omitempty"`
+	// BestScore 为该链找到的目标函数最小值，违反资源或者硬约束时为一个很大的惩罚值
+	// +optional
+	BestScore *resource.Quantity `json:"bestScore,omitempty"`
+	// StopReason 为该链停止的原因：Cooled表示温度降到finalTemp，MaxIterations表示达到maxIter，
+	// TimeLimit表示超出timeLimitSeconds，Stopped表示求解被取消，后两者的结果与机器负载有关，无法通过种子复现
+	StopReason string `json:"stopReason"`
 }
 
 type PodNodeBinding struct {
-	PodUID   string `json:"podUID,omitempty"`
-	PodName  string `json:"podName,omitempty"`
+	PodUID  string `json:"podUID,omitempty"`
+	PodName string `json:"podName,omitempty"`
+	// NodeName 为Pod实际绑定的节点
 	NodeName string `json:"nodeName,omitempty"`
+	// PlannedNodeName 为placement计划的节点，成员Pod被删除或者失败后优先在该节点上重建
+	// +optional
+	PlannedNodeName string `json:"plannedNodeName,omitempty"`
+	// Matched 表示实际绑定的节点与计划节点是否一致，没有计划节点或者尚未绑定时为空
+	// +optional
+	Matched *bool `json:"matched,omitempty"`
+	// PodPhase 为成员Pod的phase
+	// +optional
+	PodPhase v1.PodPhase `json:"podPhase,omitempty"`
+	// Ready 表示成员Pod是否处于Ready状态
+	// +optional
+	Ready bool `json:"ready,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Strategy",type=string,JSONPath=`.status.placement.strategy`,priority=1
+// +kubebuilder:printcolumn:name="Cost",type=string,JSONPath=`.status.placement.predictedCost`,priority=1
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 // +kubebuilder:webhook:path=/validate-core-cic-io-v1-podgroup,mutating=false,failurePolicy=fail,sideEffects=None,groups=core.cic.io,resources=podgroups,verbs=create;update,versions=v1,name=vpodgroup.kb.io,admissionReviewVersions=v1
 
 // PodGroup is the Schema for the podgroups API
@@ -21,12 +21,148 @@ limitations under the License.
 package v1
 
 import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AnnealingChainStatus) DeepCopyInto(out *AnnealingChainStatus) {
+	*out = *in
+	if in.BestScore != nil {
+		in, out := &in.BestScore, &out.BestScore
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AnnealingChainStatus.
+func (in *AnnealingChainStatus) DeepCopy() *AnnealingChainStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(AnnealingChainStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AnnealingOptions) DeepCopyInto(out *AnnealingOptions) {
+	*out = *in
+	if in.MaxIter != nil {
+		in, out := &in.MaxIter, &out.MaxIter
+		*out = new(int32)
+		**out = **in
+	}
+	if in.InitTemp != nil {
+		in, out := &in.InitTemp, &out.InitTemp
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.FinalTemp != nil {
+		in, out := &in.FinalTemp, &out.FinalTemp
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.CoolingRate != nil {
+		in, out := &in.CoolingRate, &out.CoolingRate
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.Alpha != nil {
+		in, out := &in.Alpha, &out.Alpha
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.Beta != nil {
+		in, out := &in.Beta, &out.Beta
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.Chains != nil {
+		in, out := &in.Chains, &out.Chains
+		*out = new(int32)
+		**out = **in
+	}
+	if in.TimeLimitSeconds != nil {
+		in, out := &in.TimeLimitSeconds, &out.TimeLimitSeconds
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AnnealingOptions.
+func (in *AnnealingOptions) DeepCopy() *AnnealingOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(AnnealingOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AntiAffinityGroup) DeepCopyInto(out *AntiAffinityGroup) {
+	*out = *in
+	if in.Pods != nil {
+		in, out := &in.Pods, &out.Pods
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AntiAffinityGroup.
+func (in *AntiAffinityGroup) DeepCopy() *AntiAffinityGroup {
+	if in == nil {
+		return nil
+	}
+	out := new(AntiAffinityGroup)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BranchAndBoundOptions) DeepCopyInto(out *BranchAndBoundOptions) {
+	*out = *in
+	if in.MaxNodes != nil {
+		in, out := &in.MaxNodes, &out.MaxNodes
+		*out = new(int32)
+		**out = **in
+	}
+	if in.TimeLimitSeconds != nil {
+		in, out := &in.TimeLimitSeconds, &out.TimeLimitSeconds
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BranchAndBoundOptions.
+func (in *BranchAndBoundOptions) DeepCopy() *BranchAndBoundOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(BranchAndBoundOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Dependency) DeepCopyInto(out *Dependency) {
 	*out = *in
+	if in.P1Index != nil {
+		in, out := &in.P1Index, &out.P1Index
+		*out = new(int32)
+		**out = **in
+	}
+	if in.P2Index != nil {
+		in, out := &in.P2Index, &out.P2Index
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Weight != nil {
+		in, out := &in.Weight, &out.Weight
+		x := (*in).DeepCopy()
+		*out = &x
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Dependency.
@@ -39,13 +175,120 @@ func (in *Dependency) DeepCopy() *Dependency {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExhaustiveOptions) DeepCopyInto(out *ExhaustiveOptions) {
+	*out = *in
+	if in.Alpha != nil {
+		in, out := &in.Alpha, &out.Alpha
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.Beta != nil {
+		in, out := &in.Beta, &out.Beta
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.MaxPods != nil {
+		in, out := &in.MaxPods, &out.MaxPods
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExhaustiveOptions.
+func (in *ExhaustiveOptions) DeepCopy() *ExhaustiveOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(ExhaustiveOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Migration) DeepCopyInto(out *Migration) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Migration.
+func (in *Migration) DeepCopy() *Migration {
+	if in == nil {
+		return nil
+	}
+	out := new(Migration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PartitioningOptions) DeepCopyInto(out *PartitioningOptions) {
+	*out = *in
+	if in.RefinementPasses != nil {
+		in, out := &in.RefinementPasses, &out.RefinementPasses
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PartitioningOptions.
+func (in *PartitioningOptions) DeepCopy() *PartitioningOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(PartitioningOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PlacementStatus) DeepCopyInto(out *PlacementStatus) {
+	*out = *in
+	if in.PredictedCost != nil {
+		in, out := &in.PredictedCost, &out.PredictedCost
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.OptimalityGap != nil {
+		in, out := &in.OptimalityGap, &out.OptimalityGap
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.Seed != nil {
+		in, out := &in.Seed, &out.Seed
+		*out = new(int64)
+		**out = **in
+	}
+	if in.LatencyWindowEnd != nil {
+		in, out := &in.LatencyWindowEnd, &out.LatencyWindowEnd
+		*out = (*in).DeepCopy()
+	}
+	if in.Chains != nil {
+		in, out := &in.Chains, &out.Chains
+		*out = make([]AnnealingChainStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlacementStatus.
+func (in *PlacementStatus) DeepCopy() *PlacementStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PlacementStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PodGroup) DeepCopyInto(out *PodGroup) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodGroup.
@@ -111,7 +354,46 @@ func (in *PodGroupSpec) DeepCopyInto(out *PodGroupSpec) {
 	if in.Dependencies != nil {
 		in, out := &in.Dependencies, &out.Dependencies
 		*out = make([]Dependency, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.SolverOptions != nil {
+		in, out := &in.SolverOptions, &out.SolverOptions
+		*out = new(SolverOptions)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.MinMember != nil {
+		in, out := &in.MinMember, &out.MinMember
+		*out = new(int32)
+		**out = **in
+	}
+	if in.ScheduleTimeoutSeconds != nil {
+		in, out := &in.ScheduleTimeoutSeconds, &out.ScheduleTimeoutSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.PreferredNeighbors != nil {
+		in, out := &in.PreferredNeighbors, &out.PreferredNeighbors
+		*out = new(int32)
+		**out = **in
+	}
+	if in.AntiAffinity != nil {
+		in, out := &in.AntiAffinity, &out.AntiAffinity
+		*out = make([]AntiAffinityGroup, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.MaxPodsPerNode != nil {
+		in, out := &in.MaxPodsPerNode, &out.MaxPodsPerNode
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Rescheduling != nil {
+		in, out := &in.Rescheduling, &out.Rescheduling
+		*out = new(ReschedulingPolicy)
+		(*in).DeepCopyInto(*out)
 	}
 }
 
@@ -128,6 +410,34 @@ func (in *PodGroupSpec) DeepCopy() *PodGroupSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PodGroupStatus) DeepCopyInto(out *PodGroupStatus) {
 	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ScheduleStartTime != nil {
+		in, out := &in.ScheduleStartTime, &out.ScheduleStartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Placement != nil {
+		in, out := &in.Placement, &out.Placement
+		*out = new(PlacementStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ScheduleResult != nil {
+		in, out := &in.ScheduleResult, &out.ScheduleResult
+		*out = make([]PodNodeBinding, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Rescheduling != nil {
+		in, out := &in.Rescheduling, &out.Rescheduling
+		*out = new(ReschedulingStatus)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodGroupStatus.
@@ -150,6 +460,13 @@ func (in *PodMetadata) DeepCopyInto(out *PodMetadata) {
 			(*out)[key] = val
 		}
 	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodMetadata.
@@ -162,10 +479,35 @@ func (in *PodMetadata) DeepCopy() *PodMetadata {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PodNodeBinding) DeepCopyInto(out *PodNodeBinding) {
+	*out = *in
+	if in.Matched != nil {
+		in, out := &in.Matched, &out.Matched
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodNodeBinding.
+func (in *PodNodeBinding) DeepCopy() *PodNodeBinding {
+	if in == nil {
+		return nil
+	}
+	out := new(PodNodeBinding)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PodTemplate) DeepCopyInto(out *PodTemplate) {
 	*out = *in
 	in.Metadata.DeepCopyInto(&out.Metadata)
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int32)
+		**out = **in
+	}
 	in.Spec.DeepCopyInto(&out.Spec)
 }
 
@@ -178,3 +520,104 @@ func (in *PodTemplate) DeepCopy() *PodTemplate {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReschedulingPolicy) DeepCopyInto(out *ReschedulingPolicy) {
+	*out = *in
+	if in.ImprovementThreshold != nil {
+		in, out := &in.ImprovementThreshold, &out.ImprovementThreshold
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.MaxUnavailable != nil {
+		in, out := &in.MaxUnavailable, &out.MaxUnavailable
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReschedulingPolicy.
+func (in *ReschedulingPolicy) DeepCopy() *ReschedulingPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(ReschedulingPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReschedulingStatus) DeepCopyInto(out *ReschedulingStatus) {
+	*out = *in
+	if in.LastEvaluationTime != nil {
+		in, out := &in.LastEvaluationTime, &out.LastEvaluationTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CurrentCost != nil {
+		in, out := &in.CurrentCost, &out.CurrentCost
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.TargetCost != nil {
+		in, out := &in.TargetCost, &out.TargetCost
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.Migrations != nil {
+		in, out := &in.Migrations, &out.Migrations
+		*out = make([]Migration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReschedulingStatus.
+func (in *ReschedulingStatus) DeepCopy() *ReschedulingStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ReschedulingStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SolverOptions) DeepCopyInto(out *SolverOptions) {
+	*out = *in
+	if in.Annealing != nil {
+		in, out := &in.Annealing, &out.Annealing
+		*out = new(AnnealingOptions)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RelativeImprovement != nil {
+		in, out := &in.RelativeImprovement, &out.RelativeImprovement
+		*out = new(AnnealingOptions)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Exhaustive != nil {
+		in, out := &in.Exhaustive, &out.Exhaustive
+		*out = new(ExhaustiveOptions)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.BranchAndBound != nil {
+		in, out := &in.BranchAndBound, &out.BranchAndBound
+		*out = new(BranchAndBoundOptions)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Partitioning != nil {
+		in, out := &in.Partitioning, &out.Partitioning
+		*out = new(PartitioningOptions)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SolverOptions.
+func (in *SolverOptions) DeepCopy() *SolverOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(SolverOptions)
+	in.DeepCopyInto(out)
+	return out
+}
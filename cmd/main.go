@@ -23,7 +23,7 @@ import (
 	"flag"
 	"os"
 	"path/filepath"
-	_ "time"
+	"time"
 
 	"github.com/SMALL-head/podGroup/internal/client/flare"
 	"github.com/SMALL-head/podGroup/internal/client/prome"
@@ -69,6 +69,8 @@ func main() {
 	var probeAddr string
 	var secureMetrics bool
 	var enableHTTP2 bool
+	var topologyKey string
+	var rescheduleInterval time.Duration
 	var tlsOpts []func(*tls.Config)
 	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metrics endpoint binds to. "+
 		"Use :8443 for HTTPS or :8080 for HTTP, or leave as 0 to disable the metrics service.")
@@ -87,6 +89,12 @@ func main() {
 	flag.StringVar(&metricsCertKey, "metrics-cert-key", "tls.key", "The name of the metrics server key file.")
 	flag.BoolVar(&enableHTTP2, "enable-http2", false,
 		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
+	flag.StringVar(&topologyKey, "topology-key", "",
+		"The node label used as the topology domain for PodGroups that do not set spec.topologyKey, "+
+			"e.g. topology.kubernetes.io/zone. Leave empty to plan on individual nodes only.")
+	flag.DurationVar(&rescheduleInterval, "reschedule-interval", 10*time.Minute,
+		"How often running PodGroups that set spec.rescheduling are re-evaluated against the latest node latencies. "+
+			"Set to 0 to disable rescheduling.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -219,15 +227,23 @@ func main() {
 		os.Exit(1)
 	}
 	c, err := prome.NewPromClient(pe)
-	if err := (&controller.PodGroupReconciler{
+	reconciler := &controller.PodGroupReconciler{
 		Client:           mgr.GetClient(),
 		Scheme:           mgr.GetScheme(),
 		PromeClient:      c,
 		FlareAdminClient: flareC,
-	}).SetupWithManager(mgr); err != nil {
+		TopologyKey:      topologyKey,
+	}
+	if err := reconciler.SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "PodGroup")
 		os.Exit(1)
 	}
+	if rescheduleInterval > 0 {
+		if err := mgr.Add(&controller.Rescheduler{Reconciler: reconciler, Interval: rescheduleInterval}); err != nil {
+			setupLog.Error(err, "unable to add rescheduler to manager")
+			os.Exit(1)
+		}
+	}
 	// nolint:goconst
 	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
 		if err := webhookv1.SetupPodGroupWebhookWithManager(mgr); err != nil {
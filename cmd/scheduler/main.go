@@ -0,0 +1,36 @@
+//go:build scheduler
+
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// podGroup-scheduler 在kube-scheduler的基础上注册PodGroupPlacement插件，调度schedulerName为podGroup-scheduler的成员Pod
+// 依赖go.mod中的k8s.io/kubernetes v1.33，staging仓库通过replace固定为对应版本，
+// 使用 -tags scheduler 构建（make build-scheduler），未设置该tag时controller的构建与测试不引入k8s.io/kubernetes
+package main
+
+import (
+	"os"
+
+	"k8s.io/component-base/cli"
+	"k8s.io/kubernetes/cmd/kube-scheduler/app"
+
+	"github.com/SMALL-head/podGroup/internal/scheduling/plugin"
+)
+
+func main() {
+	command := app.NewSchedulerCommand(app.WithPlugin(plugin.Name, plugin.New))
+	os.Exit(cli.Run(command))
+}
@@ -19,21 +19,24 @@ package controller
 import (
 	"bytes"
 	"context"
-	"slices"
 	"time"
 
 	"github.com/SMALL-head/podGroup/internal/client/flare"
 	"github.com/SMALL-head/podGroup/internal/client/prome"
-	"github.com/SMALL-head/podGroup/internal/scheduling/audit"
-	"github.com/SMALL-head/podGroup/internal/scheduling/model"
-	"github.com/SMALL-head/podGroup/internal/scheduling/planning"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/json"
 	"k8s.io/klog/v2"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 
@@ -47,21 +50,24 @@ type PodGroupReconciler struct {
 	PromeClient *prome.PromClient
 
 	FlareAdminClient *flare.Client
+	// TopologyKey 集群级别的节点拓扑域标签，PodGroup未设置spec.topologyKey时使用
+	TopologyKey string
 }
 
 // +kubebuilder:rbac:groups=core.cic.io,resources=podgroups,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core.cic.io,resources=podgroups/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=core.cic.io,resources=podgroups/finalizers,verbs=update
 // +kubebuilder:rbac:groups="",resources=nodes;pods;services,verbs=get;list;watch;delete;create
-// +kubebuilder:rbac:groups="apps",resources=deployments,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=pods;services,verbs=update;patch
+// +kubebuilder:rbac:groups="",resources=pods/eviction,verbs=create
+// +kubebuilder:rbac:groups="apps",resources=deployments;statefulsets,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups="batch",resources=jobs,verbs=get;list;watch;create;update;patch;delete
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
-// TODO(user): Modify the Reconcile function to compare the state specified by
-// the PodGroup object against the actual cluster state, and then
-// perform operations to make the cluster state reflect the state specified by
-// the user.
+// PodGroup的生命周期: Pending -> Scheduling -> Scheduled -> Running -> Succeeded/Failed，
+// 其中placement只进行一次，之后根据成员Pod的状态推进Phase与Conditions，重建被删除或者失败的成员Pod，
+// 并在spec.podList变化时增量地为新增的Pod选择节点，spec.serviceMode不为none时同步成员Pod的Service
 //
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.21.0/pkg/reconcile
@@ -78,99 +84,34 @@ func (r *PodGroupReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 		return ctrl.Result{}, nil
 	}
 
-	if podGroup.Status.Phase != "" {
-		// 已经被调度过了，直接返回
-		klog.Infof("%s-%s, 已经被调度过了", podGroup.Namespace, podGroup.Name)
+	if !podGroup.DeletionTimestamp.IsZero() {
+		// PodGroup正在被删除，成员Pod通过OwnerReference级联删除
 		return ctrl.Result{}, nil
 	}
 
-	// 更新PodGroup的Status
-	podGroup.Status.Phase = corev1.SchedulingPhase
-	err = r.Status().Update(ctx, podGroup)
-	if err != nil {
-		klog.Errorf("Failed to update status for PodGroup %s/%s, err: %v", podGroup.Namespace, podGroup.Name, err)
-		return ctrl.Result{}, err
-	}
-
-	// 1. 解析dependencies
-	pRes := planning.ParsePodGroup(podGroup)
-	if pRes == nil {
-		return ctrl.Result{}, nil
-	}
-
-	// 2. Pod按度数从高到低排序
-	podNameListByDegree := planning.SortPodNameListByDegree(pRes.PodDependencies, pRes.PodNameList)
-
-	// 3. 从可用节点中选择k的节点，保证最近延迟最小的k个节点
-	// 3.1 获取最近5分钟的节点延迟数据
-	end := time.Now()
-	start := end.Add(-5 * time.Minute)
-	resMatrix, err := r.PromeClient.GetLatencyByTimeRange(start.Format(time.RFC3339), end.Format(time.RFC3339))
-	if err != nil {
-		klog.Errorf("Failed to get latency from Prometheus, err: %v", err)
-		// 降级为普通的调度模式
-		_ = planning.NormalSchedule(ctx, r.Client, podGroup)
-		return ctrl.Result{}, err
-	}
-	// klog.Infof("PodDependencies: %v", pRes.PodDependencies)
-	// klog.Infof("PodNameList: %v", pRes.PodNameList)
-
-	//klog.Infof("PodNameListByDegree: %v", podNameListByDegree)
-
-	// 3.2 node延迟排序
-	nodeLatencies := model.PrometheusMatrix2NodeLatencies(resMatrix)
-	nodeNameList := make([]string, 0, len(nodeLatencies))
-	for k := range nodeLatencies {
-		nodeNameList = append(nodeNameList, k)
-	}
-
-	slices.SortFunc(nodeNameList, func(i, j string) int {
-		if nodeLatencies[i] < nodeLatencies[j] {
-			return -1
-		} else if nodeLatencies[i] > nodeLatencies[j] {
-			return 1
-		}
-		return 0
-	})
-
-	//klog.Infof("NodeNameList: %v\nnodeLatencies: %v", nodeNameList, nodeLatencies)
-
-	// 4. 贪心placement
-	podNodeMapper := planning.GreedyPlacement(podNameListByDegree, nodeNameList, pRes.NodeBalanceFactor)
-
-	//打印podPerNode
-	// klog.Infof("NodeBalanceFactor: %v", pRes.NodeBalanceFactor)
-
-	// 5. placement采用nodeAffinity策略绑定节点，调度器在其他条件不符合的情况(例如cpu，mem资源不够)下调度至其他节点
-	gvk, _, err := r.Scheme.ObjectKinds(podGroup)
-	// 注： 这里的gvk是一个长度为1的数组，其中Group = "core.cic.io", Version = "v1", Kind = "PodGroup"
-	if err != nil || len(gvk) == 0 {
-		klog.Errorf("Failed to get GVK from Scheme, err: %v", err)
-		return ctrl.Result{}, err
-	}
-	for podName := range podNodeMapper {
-		podTemplate := pRes.PodGroupMap[podName]
-		affinityNode := podNodeMapper[podName]
-		pod := model.PodTemplate2PodSpec(podTemplate, podGroup.ObjectMeta, affinityNode, gvk[0])
-
-		// 创建Pod
-		klog.Infof("Creating Pod %s/%s on Node (Affinity) %s", pod.Namespace, pod.Name, affinityNode)
-		err = r.Create(ctx, &pod)
-		if err != nil {
-			klog.Errorf("Failed to create Pod %s/%s, err: %v", pod.Namespace, pod.Name, err)
+	// 新创建的PodGroup进入Pending阶段
+	if podGroup.Status.Phase == "" {
+		podGroup.Status.Phase = corev1.PendingPhase
+		podGroup.Status.ObservedGeneration = podGroup.Generation
+		meta.SetStatusCondition(&podGroup.Status.Conditions, metav1.Condition{
+			Type:    corev1.PlacementComputedCondition,
+			Status:  metav1.ConditionFalse,
+			Reason:  reasonPending,
+			Message: "waiting for placement",
+		})
+		if err = r.Status().Update(ctx, podGroup); err != nil {
+			klog.Errorf("Failed to update status for PodGroup %s/%s, err: %v", podGroup.Namespace, podGroup.Name, err)
 			return ctrl.Result{}, err
 		}
 	}
 
-	// 异步上报延迟
-	go func() {
-		err = audit.ReportLatencyInfo(r.PromeClient, r.FlareAdminClient, start.Format(time.RFC3339), end.Format(time.RFC3339), podGroup)
-		if err != nil {
-			klog.Errorf("上报延迟信息出错: %v", err)
-		}
-	}()
+	// 成员Pod尚未创建时，计算placement并创建Pod
+	if !meta.IsStatusConditionTrue(podGroup.Status.Conditions, corev1.PodsCreatedCondition) {
+		return r.schedule(ctx, podGroup)
+	}
 
-	return ctrl.Result{}, nil
+	// 根据成员Pod的状态推进PodGroup的生命周期
+	return r.syncStatus(ctx, podGroup)
 }
 
 // SetupWithManager sets up the controller with the Manager.
@@ -181,10 +122,11 @@ func (r *PodGroupReconciler) SetupWithManager(mgr ctrl.Manager) error {
 			return true
 		},
 		UpdateFunc: func(e event.UpdateEvent) bool {
-			// update事件发生后，我们只需要修改数据库即可，不需要再给到Reconcile了
-			// 触发时机为scheduler通过client-go的方式PodGroup添加了status中的调度结果，添加后需要将信息写入数据库中
+			// status的变化只需要修改数据库即可，不需要再给到Reconcile了
+			// 触发时机为PodGroup的status中添加了调度结果，添加后需要将信息写入数据库中
 			go r.handleUpdate(e.ObjectOld, e.ObjectNew)
-			return false
+			// spec的变化会使generation增加，需要Reconcile创建新增的Pod并删除移除的Pod
+			return e.ObjectNew.GetGeneration() != e.ObjectOld.GetGeneration()
 		},
 		DeleteFunc: func(e event.DeleteEvent) bool {
 			go r.handleDelete(e.Object)
@@ -192,8 +134,14 @@ func (r *PodGroupReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		},
 	}
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&corev1.PodGroup{}).
-		WithEventFilter(p).
+		For(&corev1.PodGroup{}, builder.WithPredicates(p)).
+		Owns(&v1.Pod{}).
+		Owns(&v1.Service{}).
+		Owns(&appsv1.Deployment{}).
+		Owns(&appsv1.StatefulSet{}).
+		Owns(&batchv1.Job{}).
+		// 工作负载创建的成员Pod不属于PodGroup，通过PodGroupNameLabel找到其所属的PodGroup
+		Watches(&v1.Pod{}, handler.EnqueueRequestsFromMapFunc(workloadPodToPodGroup)).
 		Named("podgroup").
 		Complete(r)
 }
@@ -268,8 +216,9 @@ func (r *PodGroupReconciler) handleDelete(obj client.Object) {
 func (r *PodGroupReconciler) handleUpdate(oldObj, newObj client.Object) {
 	oldPG, newPG := oldObj.(*corev1.PodGroup), newObj.(*corev1.PodGroup)
 
-	// 只处理status phase scheduling -> scheduled 的情况
-	if !(oldPG.Status.Phase == corev1.SchedulingPhase && newPG.Status.Phase == corev1.ScheduledPhase) {
+	// 只处理status phase scheduling -> scheduled 的情况，成员Pod同时完成绑定与启动时会直接进入running
+	scheduled := newPG.Status.Phase == corev1.ScheduledPhase || newPG.Status.Phase == corev1.RunningPhase
+	if !(oldPG.Status.Phase == corev1.SchedulingPhase && scheduled) {
 		return
 	}
 
@@ -18,16 +18,32 @@ package controller
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	corev1 "github.com/SMALL-head/podGroup/api/v1"
+	"github.com/SMALL-head/podGroup/internal/client/prome"
+	"github.com/SMALL-head/podGroup/internal/scheduling/model"
+	"github.com/SMALL-head/podGroup/internal/scheduling/planning"
 )
 
 var _ = Describe("PodGroup Controller", func() {
@@ -77,8 +93,613 @@ var _ = Describe("PodGroup Controller", func() {
 				NamespacedName: typeNamespacedName,
 			})
 			Expect(err).NotTo(HaveOccurred())
-			// TODO(user): Add more specific assertions depending on your controller's reconciliation logic.
-			// Example: If you expect a certain status condition after reconciliation, verify it here.
+
+			By("Checking the PodGroup enters the Pending phase")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, podgroup)).To(Succeed())
+			Expect(podgroup.Status.Phase).To(Equal(corev1.PendingPhase))
+			Expect(podgroup.Status.ObservedGeneration).To(Equal(podgroup.Generation))
+		})
+	})
+
+	Context("When computing the phase from member pods", func() {
+		pod := func(nodeName string, phase v1.PodPhase, ready bool) v1.Pod {
+			p := v1.Pod{Spec: v1.PodSpec{NodeName: nodeName}, Status: v1.PodStatus{Phase: phase}}
+			if ready {
+				p.Status.Conditions = []v1.PodCondition{{Type: v1.PodReady, Status: v1.ConditionTrue}}
+			}
+			return p
+		}
+
+		It("should follow the PodGroup lifecycle", func() {
+			Expect(newMemberStats(2, 2, []v1.Pod{pod("", v1.PodPending, false)}).phase()).To(Equal(corev1.SchedulingPhase))
+			Expect(newMemberStats(2, 2, []v1.Pod{pod("node1", v1.PodPending, false), pod("", v1.PodPending, false)}).phase()).To(Equal(corev1.SchedulingPhase))
+			Expect(newMemberStats(2, 2, []v1.Pod{pod("node1", v1.PodPending, false), pod("node2", v1.PodRunning, true)}).phase()).To(Equal(corev1.ScheduledPhase))
+			Expect(newMemberStats(2, 2, []v1.Pod{pod("node1", v1.PodRunning, true), pod("node2", v1.PodRunning, true)}).phase()).To(Equal(corev1.RunningPhase))
+			Expect(newMemberStats(2, 2, []v1.Pod{pod("node1", v1.PodSucceeded, false), pod("node2", v1.PodSucceeded, false)}).phase()).To(Equal(corev1.SucceededPhase))
+			Expect(newMemberStats(2, 2, []v1.Pod{pod("node1", v1.PodFailed, false), pod("node2", v1.PodRunning, true)}).phase()).To(Equal(corev1.FailedPhase))
+		})
+
+		It("should treat the group as scheduled once minMember pods are bound", func() {
+			Expect(newMemberStats(3, 2, []v1.Pod{pod("node1", v1.PodPending, false), pod("", v1.PodPending, false)}).phase()).To(Equal(corev1.SchedulingPhase))
+			Expect(newMemberStats(3, 2, []v1.Pod{pod("node1", v1.PodPending, false), pod("node2", v1.PodPending, false), pod("", v1.PodPending, false)}).phase()).To(Equal(corev1.ScheduledPhase))
+			Expect(newMemberStats(3, 2, []v1.Pod{pod("node1", v1.PodRunning, true), pod("node2", v1.PodRunning, true), pod("", v1.PodPending, false)}).phase()).To(Equal(corev1.RunningPhase))
+		})
+
+		It("should only fail when failed pods leave fewer than minMember members", func() {
+			Expect(newMemberStats(3, 2, []v1.Pod{pod("node1", v1.PodFailed, false), pod("node2", v1.PodRunning, true), pod("node3", v1.PodRunning, true)}).phase()).To(Equal(corev1.RunningPhase))
+			// 失败的Pod不计入已绑定节点的Pod
+			Expect(newMemberStats(3, 2, []v1.Pod{pod("node1", v1.PodFailed, false), pod("node2", v1.PodRunning, true), pod("", v1.PodPending, false)}).phase()).To(Equal(corev1.SchedulingPhase))
+			Expect(newMemberStats(3, 2, []v1.Pod{pod("node1", v1.PodFailed, false), pod("node2", v1.PodSucceeded, false), pod("node3", v1.PodSucceeded, false)}).phase()).To(Equal(corev1.SucceededPhase))
+			Expect(newMemberStats(3, 2, []v1.Pod{pod("node1", v1.PodFailed, false), pod("node2", v1.PodFailed, false), pod("node3", v1.PodRunning, true)}).phase()).To(Equal(corev1.FailedPhase))
+		})
+	})
+
+	Context("When a member pod fails", func() {
+		It("should recreate evicted or restartable pods only", func() {
+			failed := &v1.Pod{Status: v1.PodStatus{Phase: v1.PodFailed}}
+			evicted := &v1.Pod{Status: v1.PodStatus{Phase: v1.PodFailed, Reason: podReasonEvicted}}
+			running := &v1.Pod{Status: v1.PodStatus{Phase: v1.PodRunning}}
+			always := &corev1.PodTemplate{}
+			never := &corev1.PodTemplate{Spec: v1.PodSpec{RestartPolicy: v1.RestartPolicyNever}}
+
+			Expect(needsRecreate(failed, always)).To(BeTrue())
+			Expect(needsRecreate(failed, never)).To(BeFalse())
+			Expect(needsRecreate(evicted, never)).To(BeTrue())
+			Expect(needsRecreate(running, always)).To(BeFalse())
+		})
+	})
+
+	Context("When members are added by a spec update", func() {
+		It("should record the planned nodes before creating the new members", func() {
+			ctx := context.Background()
+			scheme := runtime.NewScheme()
+			Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+			Expect(corev1.AddToScheme(scheme)).To(Succeed())
+			pg := &corev1.PodGroup{
+				ObjectMeta: metav1.ObjectMeta{Name: "pg", Namespace: "default", UID: "uid"},
+				Spec: corev1.PodGroupSpec{
+					PodList: []corev1.PodTemplate{
+						{Metadata: corev1.PodMetadata{Name: "a"}},
+						{Metadata: corev1.PodMetadata{Name: "b"}},
+					},
+				},
+				Status: corev1.PodGroupStatus{
+					Phase:          corev1.RunningPhase,
+					ScheduleResult: []corev1.PodNodeBinding{{PodName: "a", PlannedNodeName: "n1", NodeName: "n1"}},
+				},
+			}
+			a := &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            "a",
+					Namespace:       pg.Namespace,
+					OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(pg, corev1.GroupVersion.WithKind("PodGroup"))},
+				},
+				Spec:   v1.PodSpec{NodeName: "n1"},
+				Status: v1.PodStatus{Phase: v1.PodRunning},
+			}
+			// 创建Pod失败，模拟创建成员之前controller退出
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(pg, a).WithStatusSubresource(&corev1.PodGroup{}).
+				WithInterceptorFuncs(interceptor.Funcs{
+					Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+						if _, ok := obj.(*v1.Pod); ok {
+							return fmt.Errorf("create pod %s interrupted", obj.GetName())
+						}
+						return c.Create(ctx, obj, opts...)
+					},
+				}).Build()
+			r := &PodGroupReconciler{Client: c, Scheme: scheme}
+
+			podGroup := &corev1.PodGroup{}
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(pg), podGroup)).To(Succeed())
+			pods, err := r.listMemberPods(ctx, podGroup)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = r.repairMembers(ctx, podGroup, pods)
+			Expect(err).To(HaveOccurred())
+
+			// 新增成员的计划节点在创建之前已经写入status，没有Prometheus时不设置计划节点
+			got := &corev1.PodGroup{}
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(pg), got)).To(Succeed())
+			Expect(got.Status.ScheduleResult).To(HaveLen(2))
+			planned, ok := plannedNode(&got.Status, "b")
+			Expect(ok).To(BeTrue())
+			Expect(planned).To(BeEmpty())
+			planned, _ = plannedNode(&got.Status, "a")
+			Expect(planned).To(Equal("n1"))
+		})
+	})
+
+	Context("When resuming an interrupted schedule", func() {
+		It("should reuse the placement recorded in status", func() {
+			pg := &corev1.PodGroup{}
+			Expect(isPlacementRecorded(pg)).To(BeFalse())
+
+			meta.SetStatusCondition(&pg.Status.Conditions, metav1.Condition{
+				Type: corev1.PlacementComputedCondition, Status: metav1.ConditionFalse, Reason: reasonPending,
+			})
+			Expect(isPlacementRecorded(pg)).To(BeFalse())
+
+			meta.SetStatusCondition(&pg.Status.Conditions, metav1.Condition{
+				Type: corev1.PlacementComputedCondition, Status: metav1.ConditionFalse, Reason: reasonPlacementFailed,
+			})
+			Expect(isPlacementRecorded(pg)).To(BeTrue())
+
+			meta.SetStatusCondition(&pg.Status.Conditions, metav1.Condition{
+				Type: corev1.PlacementComputedCondition, Status: metav1.ConditionTrue, Reason: reasonPlacementComputed,
+			})
+			Expect(isPlacementRecorded(pg)).To(BeTrue())
+		})
+	})
+
+	Context("When replaying a recorded placement", func() {
+		It("should use the seed and latency window from the annotations", func() {
+			pg := &corev1.PodGroup{}
+			before := time.Now()
+			Expect(latencyWindowEnd(pg)).To(BeTemporally(">=", before))
+
+			pg.Annotations = map[string]string{
+				corev1.ReplaySeedAnnotation:      "42",
+				corev1.ReplayWindowEndAnnotation: "2025-06-01T08:00:00Z",
+			}
+			Expect(placementSeed(pg)).To(Equal(int64(42)))
+			Expect(latencyWindowEnd(pg)).To(BeTemporally("==", time.Date(2025, 6, 1, 8, 0, 0, 0, time.UTC)))
+
+			// 无效的注解被忽略
+			pg.Annotations[corev1.ReplayWindowEndAnnotation] = "yesterday"
+			Expect(latencyWindowEnd(pg)).To(BeTemporally(">=", before))
+		})
+
+		It("should report whether the inputs match the recorded placement", func() {
+			pg := &corev1.PodGroup{}
+			replayed := func() *metav1.Condition {
+				return meta.FindStatusCondition(pg.Status.Conditions, corev1.ReplayInputsMatchedCondition)
+			}
+			checkReplayInputs(pg, "0123456789abcdef")
+			Expect(replayed()).To(BeNil())
+
+			pg.Annotations = map[string]string{corev1.ReplaySeedAnnotation: "42"}
+			checkReplayInputs(pg, "0123456789abcdef")
+			Expect(replayed().Status).To(Equal(metav1.ConditionUnknown))
+
+			// 优先使用注解中的摘要，其次是status中记录的摘要
+			pg.Status.Placement = &corev1.PlacementStatus{InputDigest: "0123456789abcdef"}
+			checkReplayInputs(pg, "0123456789abcdef")
+			Expect(replayed().Status).To(Equal(metav1.ConditionTrue))
+			pg.Annotations[corev1.ReplayInputDigestAnnotation] = "fedcba9876543210"
+			checkReplayInputs(pg, "0123456789abcdef")
+			Expect(replayed().Status).To(Equal(metav1.ConditionFalse))
+			Expect(replayed().Reason).To(Equal(reasonInputsChanged))
+
+			// 不再复现时删除该Condition
+			pg.Annotations = nil
+			checkReplayInputs(pg, "0123456789abcdef")
+			Expect(replayed()).To(BeNil())
+		})
+	})
+
+	Context("When recording the binding of member pods", func() {
+		It("should compare the actual node with the planned node", func() {
+			status := &corev1.PodGroupStatus{
+				Placement: &corev1.PlacementStatus{Strategy: corev1.GreedyStrategy},
+				ScheduleResult: []corev1.PodNodeBinding{
+					{PodName: "a", PlannedNodeName: "node1"},
+					{PodName: "b", PlannedNodeName: "node1"},
+					{PodName: "c", PlannedNodeName: "node2"},
+					{PodName: "d"},
+				},
+			}
+			pods := []v1.Pod{
+				{ObjectMeta: metav1.ObjectMeta{Name: "a", UID: "uid-a"}, Spec: v1.PodSpec{NodeName: "node1"},
+					Status: v1.PodStatus{Phase: v1.PodRunning, Conditions: []v1.PodCondition{{Type: v1.PodReady, Status: v1.ConditionTrue}}}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "b"}, Spec: v1.PodSpec{NodeName: "node2"}, Status: v1.PodStatus{Phase: v1.PodRunning}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "c"}, Status: v1.PodStatus{Phase: v1.PodPending}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "d"}, Spec: v1.PodSpec{NodeName: "node3"}, Status: v1.PodStatus{Phase: v1.PodRunning}},
+			}
+			updateScheduleResult(status, pods)
+
+			a, b, c, d := status.ScheduleResult[0], status.ScheduleResult[1], status.ScheduleResult[2], status.ScheduleResult[3]
+			Expect(a.PodUID).To(Equal("uid-a"))
+			Expect(a.NodeName).To(Equal("node1"))
+			Expect(*a.Matched).To(BeTrue())
+			Expect(a.Ready).To(BeTrue())
+			Expect(*b.Matched).To(BeFalse())
+			Expect(b.Ready).To(BeFalse())
+			Expect(c.Matched).To(BeNil())
+			Expect(c.PodPhase).To(Equal(v1.PodPending))
+			Expect(d.Matched).To(BeNil())
+			Expect(status.Placement.MatchedPods).To(Equal(int32(1)))
+		})
+	})
+
+	Context("When rendering the placement into member pods", func() {
+		It("should follow spec.placementEnforcement", func() {
+			scheme := runtime.NewScheme()
+			Expect(corev1.AddToScheme(scheme)).To(Succeed())
+			r := &PodGroupReconciler{Scheme: scheme}
+			pg := &corev1.PodGroup{ObjectMeta: metav1.ObjectMeta{Name: "pg", Namespace: "default"}}
+			template := corev1.PodTemplate{Metadata: corev1.PodMetadata{Name: "a"}}
+			problem := &planning.Problem{
+				Nodes: []model.Node{{NodeName: "node1"}, {NodeName: "node2"}, {NodeName: "node3"}},
+				NodeLatencies: model.NodeLatencies{
+					{0, 20, 10},
+					{20, 0, 5},
+					{10, 5, 0},
+				},
+			}
+
+			pod, err := r.newMemberPod(pg, planning.ParsePodGroup(pg), template, "node1", problem)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms).To(HaveLen(1))
+
+			pg.Spec.PlacementEnforcement = corev1.PreferredEnforcement
+			neighbors := int32(1)
+			pg.Spec.PreferredNeighbors = &neighbors
+			pod, err = r.newMemberPod(pg, planning.ParsePodGroup(pg), template, "node1", problem)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution).To(BeNil())
+			terms := pod.Spec.Affinity.NodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution
+			Expect(terms).To(HaveLen(2))
+			Expect(terms[0].Weight).To(Equal(model.MaxPreferenceWeight))
+			Expect(terms[0].Preference.MatchExpressions[0].Values).To(Equal([]string{"node1"}))
+			Expect(terms[1].Preference.MatchExpressions[0].Values).To(Equal([]string{"node3"}))
+			Expect(terms[1].Weight).To(BeNumerically("<", terms[0].Weight))
+
+			// 没有节点延迟时只偏好计划节点
+			pod, err = r.newMemberPod(pg, planning.ParsePodGroup(pg), template, "node1", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pod.Spec.Affinity.NodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution).To(HaveLen(1))
+
+			pg.Spec.PlacementEnforcement = corev1.NoneEnforcement
+			pod, err = r.newMemberPod(pg, planning.ParsePodGroup(pg), template, "node1", problem)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pod.Spec.Affinity).To(BeNil())
+			Expect(pod.Spec.SchedulerName).To(Equal(model.PodGroupSchedulerName))
+		})
+
+		It("should render dependencies into PodAffinity terms", func() {
+			scheme := runtime.NewScheme()
+			Expect(corev1.AddToScheme(scheme)).To(Succeed())
+			r := &PodGroupReconciler{Scheme: scheme}
+			pg := &corev1.PodGroup{
+				ObjectMeta: metav1.ObjectMeta{Name: "pg", Namespace: "default"},
+				Spec: corev1.PodGroupSpec{
+					PodList: []corev1.PodTemplate{
+						{Metadata: corev1.PodMetadata{Name: "a"}},
+						{Metadata: corev1.PodMetadata{Name: "b"}},
+						{Metadata: corev1.PodMetadata{Name: "c"}},
+					},
+					Dependencies:       []corev1.Dependency{{P1: "a", P2: "b"}},
+					PlacementRendering: corev1.PodAffinityRendering,
+				},
+			}
+
+			pod, err := r.newMemberPod(pg, planning.ParsePodGroup(pg), pg.Spec.PodList[0], "node1", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pod.Labels).To(HaveKeyWithValue(model.PodGroupMemberLabel, "a"))
+			Expect(pod.Spec.Affinity.NodeAffinity).To(BeNil())
+			terms := pod.Spec.Affinity.PodAffinity.PreferredDuringSchedulingIgnoredDuringExecution
+			Expect(terms).To(HaveLen(1))
+			Expect(terms[0].PodAffinityTerm.LabelSelector.MatchLabels).To(HaveKeyWithValue(model.PodGroupMemberLabel, "b"))
+			Expect(terms[0].PodAffinityTerm.TopologyKey).To(Equal(model.HostnameLabel))
+
+			// 偏好依赖Pod所在的拓扑域，spec.topologyKey优先于controller的参数
+			r.TopologyKey = "topology.kubernetes.io/region"
+			pg.Spec.TopologyKey = "topology.kubernetes.io/zone"
+			pod, err = r.newMemberPod(pg, planning.ParsePodGroup(pg), pg.Spec.PodList[0], "", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pod.Spec.Affinity.PodAffinity.PreferredDuringSchedulingIgnoredDuringExecution[0].PodAffinityTerm.TopologyKey).To(Equal("topology.kubernetes.io/zone"))
+			pg.Spec.TopologyKey = ""
+			pod, err = r.newMemberPod(pg, planning.ParsePodGroup(pg), pg.Spec.PodList[0], "", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pod.Spec.Affinity.PodAffinity.PreferredDuringSchedulingIgnoredDuringExecution[0].PodAffinityTerm.TopologyKey).To(Equal("topology.kubernetes.io/region"))
+
+			// 没有依赖的Pod只设置标签
+			pod, err = r.newMemberPod(pg, planning.ParsePodGroup(pg), pg.Spec.PodList[2], "", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pod.Labels).To(HaveKeyWithValue(model.PodGroupMemberLabel, "c"))
+			Expect(pod.Spec.Affinity).To(BeNil())
+		})
+	})
+
+	Context("When creating Services for member pods", func() {
+		It("should follow spec.serviceMode and the dependency directions", func() {
+			scheme := runtime.NewScheme()
+			Expect(corev1.AddToScheme(scheme)).To(Succeed())
+			r := &PodGroupReconciler{Scheme: scheme}
+			replicas := int32(2)
+			ports := []v1.ContainerPort{{Name: "http", ContainerPort: 8080}}
+			pg := &corev1.PodGroup{
+				ObjectMeta: metav1.ObjectMeta{Name: "pg", Namespace: "default"},
+				Spec: corev1.PodGroupSpec{
+					PodList: []corev1.PodTemplate{
+						{Metadata: corev1.PodMetadata{Name: "client"}, Spec: v1.PodSpec{Containers: []v1.Container{{Name: "c", Ports: ports}}}},
+						{Metadata: corev1.PodMetadata{Name: "db"}, Replicas: &replicas, Spec: v1.PodSpec{Containers: []v1.Container{{Name: "c", Ports: ports}}}},
+						{Metadata: corev1.PodMetadata{Name: "batch"}},
+					},
+					Dependencies: []corev1.Dependency{
+						{P1: "client", P2: "db", Direction: corev1.DirectionP1ToP2},
+						{P1: "batch", P2: "client", Direction: corev1.DirectionP2ToP1},
+					},
+				},
+			}
+			gvk := corev1.GroupVersion.WithKind("PodGroup")
+			Expect(desiredServices(pg, gvk)).To(BeEmpty())
+
+			// 只有接收流量并且声明了端口的成员Pod需要Service，client只发送流量，batch没有端口
+			pg.Spec.ServiceMode = corev1.PerMemberServiceMode
+			services := desiredServices(pg, gvk)
+			Expect(services).To(HaveLen(2))
+			Expect(services[0].Name).To(Equal("db-0"))
+			Expect(services[0].Spec.ClusterIP).To(Equal(v1.ClusterIPNone))
+			Expect(services[0].Spec.Selector).To(HaveKeyWithValue(model.PodGroupMemberLabel, "db-0"))
+			Expect(services[1].Name).To(Equal("db-1"))
+
+			pg.Spec.ServiceMode = corev1.PerRoleServiceMode
+			services = desiredServices(pg, gvk)
+			Expect(services).To(HaveLen(1))
+			Expect(services[0].Name).To(Equal("db"))
+			Expect(services[0].Spec.ClusterIP).To(BeEmpty())
+			Expect(services[0].Spec.Selector).To(HaveKeyWithValue(model.PodGroupRoleLabel, "db"))
+
+			// 启用Service时成员Pod带有Service选择所需的标签
+			pod, err := r.newMemberPod(pg, planning.ParsePodGroup(pg), pg.Spec.Members()[2], "", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pod.Labels).To(HaveKeyWithValue(model.PodGroupNameLabel, "pg"))
+			Expect(pod.Labels).To(HaveKeyWithValue(model.PodGroupMemberLabel, "db-1"))
+			Expect(pod.Labels).To(HaveKeyWithValue(model.PodGroupRoleLabel, "db"))
+		})
+	})
+
+	Context("When members are backed by workloads", func() {
+		It("should pick the pod representing each workload member", func() {
+			pg := &corev1.PodGroup{
+				ObjectMeta: metav1.ObjectMeta{Name: "pg", Namespace: "default", UID: "uid"},
+				Spec: corev1.PodGroupSpec{
+					PodList: []corev1.PodTemplate{
+						{Metadata: corev1.PodMetadata{Name: "a"}},
+						{Metadata: corev1.PodMetadata{Name: "db"}, Workload: corev1.DeploymentWorkload},
+					},
+				},
+			}
+			isController := true
+			newPod := func(name, member string, created int64) *v1.Pod {
+				return &v1.Pod{ObjectMeta: metav1.ObjectMeta{
+					Name:              name,
+					Labels:            map[string]string{model.PodGroupNameLabel: "pg", model.PodGroupMemberLabel: member},
+					CreationTimestamp: metav1.Unix(created, 0),
+					OwnerReferences:   []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "db-1", Controller: &isController}},
+				}}
+			}
+
+			old, updated := newPod("db-1-x", "db", 1), newPod("db-2-y", "db", 2)
+			Expect(model.IsWorkloadPod(pg, old)).To(BeTrue())
+			Expect(model.MemberOf(old)).To(Equal("db"))
+			Expect(model.IsWorkloadPod(pg, newPod("a-x", "a", 1))).To(BeFalse())
+
+			// 滚动更新期间优先选择Ready的Pod，其次是最新创建的Pod
+			old.Status.Conditions = []v1.PodCondition{{Type: v1.PodReady, Status: v1.ConditionTrue}}
+			Expect(workloadMemberPod([]*v1.Pod{old, updated})).To(Equal(old))
+			old.Status.Conditions = nil
+			Expect(workloadMemberPod([]*v1.Pod{old, updated})).To(Equal(updated))
+
+			// 失败的Pod由工作负载重建，不代表成员的状态
+			updated.Status.Phase = v1.PodFailed
+			Expect(workloadMemberPod([]*v1.Pod{old, updated})).To(Equal(old))
+			Expect(workloadMemberPod([]*v1.Pod{updated})).To(BeNil())
+		})
+
+		It("should keep the preferred nodes when rolling out a workload", func() {
+			scheme := runtime.NewScheme()
+			Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+			Expect(corev1.AddToScheme(scheme)).To(Succeed())
+			neighbors := int32(1)
+			pg := &corev1.PodGroup{
+				ObjectMeta: metav1.ObjectMeta{Name: "pg", Namespace: "default", UID: "uid"},
+				Spec: corev1.PodGroupSpec{
+					PodList: []corev1.PodTemplate{
+						{Metadata: corev1.PodMetadata{Name: "db"}, Workload: corev1.DeploymentWorkload},
+					},
+					PlacementEnforcement: corev1.PreferredEnforcement,
+					PreferredNeighbors:   &neighbors,
+				},
+			}
+			setPlannedNode(&pg.Status, "db", "node1")
+			pRes := planning.ParsePodGroup(pg)
+			problem := &planning.Problem{
+				Nodes: []model.Node{{NodeName: "node1"}, {NodeName: "node2"}, {NodeName: "node3"}},
+				NodeLatencies: model.NodeLatencies{
+					{0, 20, 10},
+					{20, 0, 5},
+					{10, 5, 0},
+				},
+			}
+			r := &PodGroupReconciler{Scheme: scheme}
+			pod, err := r.newMemberPod(pg, pRes, pg.Spec.PodList[0], "node1", nil)
+			Expect(err).NotTo(HaveOccurred())
+			obj, err := model.PodToWorkload(corev1.DeploymentWorkload, pod, "stale")
+			Expect(err).NotTo(HaveOccurred())
+			r.Client = fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj).Build()
+
+			// 滚动更新后的Pod模板同样偏好与计划节点延迟最低的节点
+			ctx := context.Background()
+			Expect(r.rolloutMemberWorkload(ctx, pg, pRes, problem, &pg.Spec.PodList[0], obj)).To(Succeed())
+			updated := obj.DeepCopyObject().(client.Object)
+			Expect(r.Get(ctx, client.ObjectKeyFromObject(obj), updated)).To(Succeed())
+			Expect(updated.GetAnnotations()).To(HaveKeyWithValue(model.TemplateHashAnnotation, model.TemplateHash(pg.Spec.PodList[0])))
+			terms := model.WorkloadPodTemplate(updated).Spec.Affinity.NodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution
+			Expect(terms).To(HaveLen(2))
+			Expect(terms[0].Preference.MatchExpressions[0].Values).To(Equal([]string{"node1"}))
+			Expect(terms[1].Preference.MatchExpressions[0].Values).To(Equal([]string{"node3"}))
+		})
+	})
+
+	Context("When rescheduling a running PodGroup", func() {
+		var (
+			ctx     context.Context
+			prom    *httptest.Server
+			pg      *corev1.PodGroup
+			request = v1.ResourceRequirements{Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")}}
+		)
+
+		BeforeEach(func() {
+			ctx = context.Background()
+			// n1与n2、n3之间的延迟为100，n2与n3之间的延迟为2，a与b分别位于n2与n3时代价最低
+			prom = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				var series []string
+				for _, l := range [][3]string{{"n1", "n2", "100"}, {"n1", "n3", "100"}, {"n2", "n3", "2"}} {
+					for _, pair := range [][2]string{{l[0], l[1]}, {l[1], l[0]}} {
+						series = append(series, fmt.Sprintf(`{"metric":{"src":"%s","dst":"%s"},"values":[[%d,"%s"]]}`, pair[0], pair[1], time.Now().Unix(), l[2]))
+					}
+				}
+				_, _ = fmt.Fprintf(w, `{"status":"success","data":{"resultType":"matrix","result":[%s]}}`, strings.Join(series, ","))
+			}))
+			pg = &corev1.PodGroup{
+				ObjectMeta: metav1.ObjectMeta{Name: "pg", Namespace: "default", UID: "uid", Generation: 1},
+				Spec: corev1.PodGroupSpec{
+					Rescheduling: &corev1.ReschedulingPolicy{},
+					PodList: []corev1.PodTemplate{
+						{Metadata: corev1.PodMetadata{Name: "a"}, Spec: v1.PodSpec{Containers: []v1.Container{{Name: "c", Image: "x", Resources: request}}}},
+						{Metadata: corev1.PodMetadata{Name: "b"}, Spec: v1.PodSpec{Containers: []v1.Container{{Name: "c", Image: "x", Resources: request}}}},
+					},
+					Dependencies: []corev1.Dependency{{P1: "a", P2: "b"}},
+				},
+				Status: corev1.PodGroupStatus{
+					Phase: corev1.RunningPhase,
+					ScheduleResult: []corev1.PodNodeBinding{
+						{PodName: "a", PlannedNodeName: "n1", NodeName: "n1"},
+						{PodName: "b", PlannedNodeName: "n2", NodeName: "n2"},
+					},
+				},
+			}
+		})
+
+		AfterEach(func() {
+			prom.Close()
+		})
+
+		newNode := func(name string) *v1.Node {
+			return &v1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: name},
+				Status: v1.NodeStatus{
+					Allocatable: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1"), v1.ResourceMemory: resource.MustParse("8Gi")},
+					Conditions:  []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionTrue}},
+				},
+			}
+		}
+		// newWideNode 返回可以同时放下a与b的节点，与branch-and-bound一起使用时a与b都位于该节点的placement为唯一最优解
+		newWideNode := func(name string) *v1.Node {
+			node := newNode(name)
+			node.Status.Allocatable[v1.ResourceCPU] = resource.MustParse("2")
+			return node
+		}
+		newPod := func(name, nodeName string, ready bool) *v1.Pod {
+			pod := &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            name,
+					Namespace:       pg.Namespace,
+					UID:             types.UID("uid-" + name),
+					Labels:          map[string]string{model.PodGroupNameLabel: pg.Name, model.PodGroupMemberLabel: name},
+					OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(pg, corev1.GroupVersion.WithKind("PodGroup"))},
+				},
+				Spec:   v1.PodSpec{NodeName: nodeName, Containers: []v1.Container{{Name: "c", Image: "x", Resources: request}}},
+				Status: v1.PodStatus{Phase: v1.PodRunning},
+			}
+			if ready {
+				pod.Status.Conditions = []v1.PodCondition{{Type: v1.PodReady, Status: v1.ConditionTrue}}
+			}
+			return pod
+		}
+		// newReconciler 使用fake client构造Reconciler，evictErr不为nil时Eviction返回该错误，模拟被PodDisruptionBudget拒绝
+		newReconciler := func(evictErr error, objs ...client.Object) (*PodGroupReconciler, client.Client) {
+			scheme := runtime.NewScheme()
+			Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+			Expect(corev1.AddToScheme(scheme)).To(Succeed())
+			builder := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).WithStatusSubresource(&corev1.PodGroup{})
+			if evictErr != nil {
+				builder = builder.WithInterceptorFuncs(interceptor.Funcs{
+					SubResourceCreate: func(ctx context.Context, c client.Client, subResource string, obj, subResourceObj client.Object, opts ...client.SubResourceCreateOption) error {
+						if subResource == "eviction" {
+							return evictErr
+						}
+						return c.SubResource(subResource).Create(ctx, obj, subResourceObj, opts...)
+					},
+				})
+			}
+			promClient, err := prome.NewPromClient(prom.URL)
+			Expect(err).NotTo(HaveOccurred())
+			c := builder.Build()
+			return &PodGroupReconciler{Client: c, Scheme: scheme, PromeClient: promClient}, c
+		}
+		reschedule := func(r *PodGroupReconciler, c client.Client) *corev1.PodGroup {
+			podGroup := &corev1.PodGroup{}
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(pg), podGroup)).To(Succeed())
+			Expect(r.reschedule(ctx, podGroup)).To(Succeed())
+			got := &corev1.PodGroup{}
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(pg), got)).To(Succeed())
+			return got
+		}
+
+		It("should only migrate when the improvement reaches the threshold", func() {
+			threshold := resource.MustParse("0.99")
+			pg.Spec.Rescheduling.ImprovementThreshold = &threshold
+			// branch-and-bound的结果是确定的，两次评估的结果相同
+			pg.Spec.PlacementStrategy = corev1.BranchAndBoundStrategy
+			r, c := newReconciler(nil, pg, newNode("n1"), newNode("n2"), newNode("n3"), newPod("a", "n1", true), newPod("b", "n2", true))
+
+			got := reschedule(r, c)
+			Expect(got.Status.Rescheduling).NotTo(BeNil())
+			Expect(got.Status.Rescheduling.LastEvaluationTime).NotTo(BeNil())
+			Expect(got.Status.Rescheduling.CurrentCost.AsApproximateFloat64()).To(BeNumerically(">", got.Status.Rescheduling.TargetCost.AsApproximateFloat64()))
+			Expect(got.Status.Rescheduling.Migrations).To(BeEmpty())
+			Expect(c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "a"}, &v1.Pod{})).To(Succeed())
+
+			// 评估结果没有变化时不更新status
+			again := reschedule(r, c)
+			Expect(again.ResourceVersion).To(Equal(got.ResourceVersion))
+			Expect(again.Status.Rescheduling.LastEvaluationTime).To(Equal(got.Status.Rescheduling.LastEvaluationTime))
+		})
+
+		It("should not migrate while maxUnavailable members are not ready", func() {
+			r, c := newReconciler(nil, pg, newNode("n1"), newNode("n2"), newNode("n3"), newPod("a", "n1", true), newPod("b", "n2", false))
+
+			got := reschedule(r, c)
+			Expect(got.Status.Rescheduling.Migrations).To(BeEmpty())
+			Expect(c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "a"}, &v1.Pod{})).To(Succeed())
+		})
+
+		It("should evict the migrated member after recording the new planned node", func() {
+			pg.Spec.PlacementStrategy = corev1.BranchAndBoundStrategy
+			r, c := newReconciler(nil, pg, newNode("n1"), newWideNode("n2"), newNode("n3"), newPod("a", "n1", true), newPod("b", "n2", true))
+
+			got := reschedule(r, c)
+			Expect(got.Status.Rescheduling.Migrations).To(HaveLen(1))
+			// a迁移到b所在的n2后代价最低，b保持不变
+			migration := got.Status.Rescheduling.Migrations[0]
+			Expect(migration.PodName).To(Equal("a"))
+			Expect(migration.ToNode).To(Equal("n2"))
+			planned, _ := plannedNode(&got.Status, migration.PodName)
+			Expect(planned).To(Equal("n2"))
+			err := c.Get(ctx, types.NamespacedName{Namespace: "default", Name: migration.PodName}, &v1.Pod{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+
+			// 被驱逐后成员不再全部绑定节点，不会继续迁移
+			got = reschedule(r, c)
+			Expect(got.Status.Rescheduling.Migrations).To(HaveLen(1))
+		})
+
+		It("should revert the migration when the eviction is rejected", func() {
+			rejected := errors.NewTooManyRequests("Cannot evict pod as it would violate the pod's disruption budget.", 10)
+			history := corev1.Migration{PodName: "b", FromNode: "n1", ToNode: "n2", Time: metav1.Unix(1, 0), Reason: "earlier migration"}
+			pg.Status.Rescheduling = &corev1.ReschedulingStatus{Migrations: []corev1.Migration{history}}
+			pg.Spec.PlacementStrategy = corev1.BranchAndBoundStrategy
+			r, c := newReconciler(rejected, pg, newNode("n1"), newWideNode("n2"), newNode("n3"), newPod("a", "n1", true), newPod("b", "n2", true))
+
+			got := reschedule(r, c)
+			Expect(got.Status.Rescheduling.Migrations).To(HaveLen(1))
+			Expect(got.Status.Rescheduling.Migrations[0].Reason).To(Equal(history.Reason))
+			Expect(got.Status.ScheduleResult).To(Equal(pg.Status.ScheduleResult))
+			pods := &v1.PodList{}
+			Expect(c.List(ctx, pods)).To(Succeed())
+			Expect(pods.Items).To(HaveLen(2))
 		})
 	})
 })
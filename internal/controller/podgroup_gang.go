@@ -0,0 +1,188 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	corev1 "github.com/SMALL-head/podGroup/api/v1"
+	"github.com/SMALL-head/podGroup/internal/scheduling/model"
+	"github.com/SMALL-head/podGroup/internal/scheduling/planning"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// GangScheduled Condition的Reason
+const (
+	reasonInsufficientCapacity = "InsufficientCapacity"
+	reasonWaitingForQuorum     = "WaitingForQuorum"
+	reasonQuorumReached        = "QuorumReached"
+	reasonQuorumTimeout        = "QuorumTimeout"
+)
+
+// capacityRetryInterval 剩余资源放不下minMember个Pod时重新检查的间隔
+const capacityRetryInterval = 30 * time.Second
+
+// minMember 返回spec.minMember，未设置或者超过成员Pod数量时为全部成员Pod
+func minMember(podGroup *corev1.PodGroup) int {
+	total := podGroup.Spec.MemberCount()
+	if podGroup.Spec.MinMember == nil || int(*podGroup.Spec.MinMember) > total {
+		return total
+	}
+	return int(*podGroup.Spec.MinMember)
+}
+
+// scheduleDeadline 返回等待minMember个Pod绑定节点的截止时间，未设置spec.scheduleTimeoutSeconds时返回false
+func scheduleDeadline(podGroup *corev1.PodGroup) (time.Time, bool) {
+	if podGroup.Spec.ScheduleTimeoutSeconds == nil || podGroup.Status.ScheduleStartTime == nil {
+		return time.Time{}, false
+	}
+	timeout := time.Duration(*podGroup.Spec.ScheduleTimeoutSeconds) * time.Second
+	return podGroup.Status.ScheduleStartTime.Add(timeout), true
+}
+
+// isQuorumReached 成员Pod是否曾经达到minMember个绑定节点，达到之后不再进行超时回滚
+func isQuorumReached(podGroup *corev1.PodGroup) bool {
+	return meta.IsStatusConditionTrue(podGroup.Status.Conditions, corev1.GangScheduledCondition)
+}
+
+// gangRequeueAfter 等待minMember的过程中，返回距离超时的时间，使超时后即使没有Pod事件也能触发Reconcile
+func gangRequeueAfter(podGroup *corev1.PodGroup) time.Duration {
+	if isQuorumReached(podGroup) {
+		return 0
+	}
+	deadline, ok := scheduleDeadline(podGroup)
+	if !ok {
+		return 0
+	}
+	return max(time.Until(deadline), time.Second)
+}
+
+// setGangCondition 根据已经绑定节点的Pod数量设置GangScheduled Condition，已经为True时保持不变
+func setGangCondition(status *corev1.PodGroupStatus, bound, expected, quorum int) {
+	if meta.IsStatusConditionTrue(status.Conditions, corev1.GangScheduledCondition) {
+		return
+	}
+	c := metav1.Condition{
+		Type:    corev1.GangScheduledCondition,
+		Status:  metav1.ConditionFalse,
+		Reason:  reasonWaitingForQuorum,
+		Message: fmt.Sprintf("%d/%d pods bound, minMember is %d", bound, expected, quorum),
+	}
+	if bound >= quorum {
+		c.Status = metav1.ConditionTrue
+		c.Reason = reasonQuorumReached
+	}
+	meta.SetStatusCondition(&status.Conditions, c)
+}
+
+// countFittingPods 在创建Pod之前估计候选节点的剩余资源最多能够放下多少个成员Pod
+func (r *PodGroupReconciler) countFittingPods(ctx context.Context, pRes *model.PodGroupParseResult) (int, error) {
+	candidates, err := r.listCandidateNodes(ctx)
+	if err != nil {
+		return 0, err
+	}
+	nodes := make([]model.Node, 0, len(candidates))
+	for _, node := range candidates {
+		nodes = append(nodes, node)
+	}
+	slices.SortFunc(nodes, func(a, b model.Node) int {
+		return strings.Compare(a.NodeName, b.NodeName)
+	})
+	return planning.CountFittingPods(planning.PodModels(pRes), nodes), nil
+}
+
+// waitForCapacity 剩余资源放不下minMember个Pod时不创建任何Pod，稍后重新检查，超时后PodGroup进入Failed阶段
+func (r *PodGroupReconciler) waitForCapacity(ctx context.Context, podGroup *corev1.PodGroup, fitting int) (ctrl.Result, error) {
+	msg := fmt.Sprintf("only %d/%d pods fit into the cluster, minMember is %d", fitting, podGroup.Spec.MemberCount(), minMember(podGroup))
+	klog.Infof("PodGroup %s/%s waits for capacity: %s", podGroup.Namespace, podGroup.Name, msg)
+	if deadline, ok := scheduleDeadline(podGroup); ok && !time.Now().Before(deadline) {
+		return ctrl.Result{}, r.failGang(ctx, podGroup, nil, msg)
+	}
+
+	meta.SetStatusCondition(&podGroup.Status.Conditions, metav1.Condition{
+		Type:    corev1.GangScheduledCondition,
+		Status:  metav1.ConditionFalse,
+		Reason:  reasonInsufficientCapacity,
+		Message: msg,
+	})
+	if err := r.Status().Update(ctx, podGroup); err != nil {
+		klog.Errorf("Failed to update status for PodGroup %s/%s, err: %v", podGroup.Namespace, podGroup.Name, err)
+		return ctrl.Result{}, err
+	}
+	requeueAfter := capacityRetryInterval
+	if d := gangRequeueAfter(podGroup); d > 0 {
+		requeueAfter = min(requeueAfter, d)
+	}
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// checkGangTimeout 成员Pod达到minMember个绑定节点之前，超时后回滚整个PodGroup，返回是否进行了回滚
+func (r *PodGroupReconciler) checkGangTimeout(ctx context.Context, podGroup *corev1.PodGroup, pods []v1.Pod) (bool, error) {
+	if isQuorumReached(podGroup) {
+		return false, nil
+	}
+	deadline, ok := scheduleDeadline(podGroup)
+	if !ok || time.Now().Before(deadline) {
+		return false, nil
+	}
+	bound := 0
+	for i := range pods {
+		if pods[i].Spec.NodeName != "" && pods[i].DeletionTimestamp.IsZero() {
+			bound++
+		}
+	}
+	quorum := minMember(podGroup)
+	if bound >= quorum {
+		return false, nil
+	}
+	msg := fmt.Sprintf("only %d/%d pods bound within %ds, minMember is %d", bound, podGroup.Spec.MemberCount(), *podGroup.Spec.ScheduleTimeoutSeconds, quorum)
+	return true, r.failGang(ctx, podGroup, pods, msg)
+}
+
+// failGang 删除已经创建的成员Pod与工作负载，并将PodGroup置为Failed
+func (r *PodGroupReconciler) failGang(ctx context.Context, podGroup *corev1.PodGroup, pods []v1.Pod, msg string) error {
+	klog.Warningf("PodGroup %s/%s can not reach minMember, rolling back: %s", podGroup.Namespace, podGroup.Name, msg)
+	for i := range pods {
+		pod := &pods[i]
+		if !metav1.IsControlledBy(pod, podGroup) {
+			// 工作负载创建的Pod随工作负载一起删除
+			continue
+		}
+		if err := r.Delete(ctx, pod, client.Preconditions{UID: &pod.UID}); client.IgnoreNotFound(err) != nil {
+			klog.Errorf("Failed to delete Pod %s/%s, err: %v", pod.Namespace, pod.Name, err)
+			return err
+		}
+	}
+	workloads, err := r.listMemberWorkloads(ctx, podGroup)
+	if err != nil {
+		klog.Errorf("Failed to list workloads of PodGroup %s/%s, err: %v", podGroup.Namespace, podGroup.Name, err)
+		return err
+	}
+	for _, objs := range workloads {
+		for _, obj := range objs {
+			if err := r.deleteWorkload(ctx, obj); err != nil {
+				return err
+			}
+		}
+	}
+
+	podGroup.Status.Phase = corev1.FailedPhase
+	meta.SetStatusCondition(&podGroup.Status.Conditions, metav1.Condition{
+		Type:    corev1.GangScheduledCondition,
+		Status:  metav1.ConditionFalse,
+		Reason:  reasonQuorumTimeout,
+		Message: msg,
+	})
+	if err := r.Status().Update(ctx, podGroup); err != nil {
+		klog.Errorf("Failed to update status for PodGroup %s/%s, err: %v", podGroup.Namespace, podGroup.Name, err)
+		return err
+	}
+	return nil
+}
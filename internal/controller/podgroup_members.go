@@ -0,0 +1,275 @@
+package controller
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	corev1 "github.com/SMALL-head/podGroup/api/v1"
+	"github.com/SMALL-head/podGroup/internal/scheduling/model"
+	"github.com/SMALL-head/podGroup/internal/scheduling/planning"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// podReasonEvicted 被驱逐的Pod的status.reason
+const podReasonEvicted = "Evicted"
+
+// needsRecreate 判断失败的成员Pod是否需要重建
+// 被驱逐的Pod总是重建，因容器退出而失败的Pod只有在restartPolicy不为Never时才重建
+func needsRecreate(pod *v1.Pod, template *corev1.PodTemplate) bool {
+	if pod.Status.Phase != v1.PodFailed {
+		return false
+	}
+	return pod.Status.Reason == podReasonEvicted || template.Spec.RestartPolicy != v1.RestartPolicyNever
+}
+
+// repairMembers 使成员Pod与spec.podList保持一致，返回未被删除的成员Pod，由工作负载创建的成员每个成员返回一个代表其状态的Pod
+//   - 删除已经从spec.podList中移除的Pod与工作负载，以及工作负载类型发生变化的成员
+//   - 删除需要重建的失败Pod，工作负载创建的Pod由工作负载自身重建
+//   - 创建缺失的Pod与工作负载，包括被删除的Pod以及spec.podList中新增的Pod和新增的副本
+//   - 成员模板发生变化时更新工作负载的Pod模板
+//
+// 正在删除的Pod等到删除完成后，由Pod的删除事件触发下一次Reconcile重建
+func (r *PodGroupReconciler) repairMembers(ctx context.Context, podGroup *corev1.PodGroup, pods []v1.Pod) ([]v1.Pod, error) {
+	existing := make(map[string]*v1.Pod, len(pods))
+	workloadPods := make(map[string][]*v1.Pod)
+	for i := range pods {
+		if metav1.IsControlledBy(&pods[i], podGroup) {
+			existing[pods[i].Name] = &pods[i]
+		} else {
+			workloadPods[model.MemberOf(&pods[i])] = append(workloadPods[model.MemberOf(&pods[i])], &pods[i])
+		}
+	}
+	members := podGroup.Spec.Members()
+	templates := memberTemplates(members)
+	pRes := planning.ParsePodGroup(podGroup)
+	workloads, err := r.listMemberWorkloads(ctx, podGroup)
+	if err != nil {
+		klog.Errorf("Failed to list workloads of PodGroup %s/%s, err: %v", podGroup.Namespace, podGroup.Name, err)
+		return nil, err
+	}
+
+	// 删除已经移除的成员，包括replicas减少后多出的副本
+	for _, pod := range existing {
+		if template := templates[pod.Name]; template != nil && !template.IsWorkload() || !pod.DeletionTimestamp.IsZero() {
+			continue
+		}
+		klog.Infof("Pod %s/%s is removed from PodGroup %s, deleting it", pod.Namespace, pod.Name, podGroup.Name)
+		if err := r.Delete(ctx, pod, client.Preconditions{UID: &pod.UID}); client.IgnoreNotFound(err) != nil {
+			klog.Errorf("Failed to delete Pod %s/%s, err: %v", pod.Namespace, pod.Name, err)
+			return nil, err
+		}
+	}
+	for name, objs := range workloads {
+		for _, obj := range objs {
+			if template := templates[name]; template != nil && template.Workload == model.WorkloadKindOf(obj) || !obj.GetDeletionTimestamp().IsZero() {
+				continue
+			}
+			klog.Infof("%s %s/%s is removed from PodGroup %s, deleting it", model.WorkloadKindOf(obj), obj.GetNamespace(), obj.GetName(), podGroup.Name)
+			if err := r.deleteWorkload(ctx, obj); err != nil {
+				return nil, err
+			}
+		}
+	}
+	podGroup.Status.ScheduleResult = slices.DeleteFunc(podGroup.Status.ScheduleResult, func(binding corev1.PodNodeBinding) bool {
+		return templates[binding.PodName] == nil
+	})
+
+	live := make([]v1.Pod, 0, len(pods))
+	var missing []string
+	for i := range members {
+		template := &members[i]
+		if template.IsWorkload() {
+			idx := slices.IndexFunc(workloads[template.Metadata.Name], func(obj client.Object) bool {
+				return model.WorkloadKindOf(obj) == template.Workload
+			})
+			switch {
+			case idx < 0:
+				missing = append(missing, template.Metadata.Name)
+			case !workloads[template.Metadata.Name][idx].GetDeletionTimestamp().IsZero():
+				continue
+			default:
+				if err := r.updateMemberWorkload(ctx, podGroup, pRes, template, workloads[template.Metadata.Name][idx]); err != nil {
+					return nil, err
+				}
+				if pod := workloadMemberPod(workloadPods[template.Metadata.Name]); pod != nil {
+					live = append(live, *pod)
+				}
+			}
+			continue
+		}
+		pod, ok := existing[template.Metadata.Name]
+		switch {
+		case !ok:
+			missing = append(missing, template.Metadata.Name)
+		case !pod.DeletionTimestamp.IsZero():
+			continue
+		case needsRecreate(pod, template):
+			klog.Infof("Member Pod %s/%s failed (reason: %s), deleting it for recreation", pod.Namespace, pod.Name, pod.Status.Reason)
+			if err := r.Delete(ctx, pod, client.Preconditions{UID: &pod.UID}); client.IgnoreNotFound(err) != nil {
+				klog.Errorf("Failed to delete Pod %s/%s, err: %v", pod.Namespace, pod.Name, err)
+				return nil, err
+			}
+		default:
+			live = append(live, *pod)
+		}
+	}
+	if len(missing) == 0 {
+		return live, nil
+	}
+
+	nodes, problem, err := r.placeMissingMembers(ctx, podGroup, templates, live, missing)
+	if err != nil {
+		return nil, err
+	}
+	// 与schedule相同，先将计划节点写入status再创建成员，中途失败或者controller重启后使用记录的计划节点继续创建
+	oldStatus := podGroup.Status.DeepCopy()
+	for _, podName := range missing {
+		setPlannedNode(&podGroup.Status, podName, nodes[podName])
+	}
+	if !equality.Semantic.DeepEqual(&podGroup.Status, oldStatus) {
+		if err := r.Status().Update(ctx, podGroup); err != nil {
+			klog.Errorf("Failed to record planned nodes for PodGroup %s/%s, err: %v", podGroup.Namespace, podGroup.Name, err)
+			return nil, err
+		}
+	}
+	problem = r.preferenceProblem(ctx, podGroup, pRes, problem)
+	for _, podName := range missing {
+		pod, err := r.newMemberPod(podGroup, pRes, *templates[podName], nodes[podName], problem)
+		if err != nil {
+			return nil, err
+		}
+		if err = r.createMember(ctx, podGroup, templates[podName], &pod); err != nil {
+			return nil, err
+		}
+	}
+	return live, nil
+}
+
+// placeMissingMembers 为缺失的成员Pod选择节点，key为Pod名称，value为节点名称，节点为空表示由默认调度器决定
+// 计划的节点仍然可以调度并且放得下时沿用该节点，否则在其他Pod位置不变的前提下重新选择通信代价最低的节点
+// 新增的Pod同样在其他Pod位置不变的前提下选择节点，已有Pod的位置保持不变
+// 没有计划节点的Pod（placement降级时创建的Pod）不设置节点亲和性
+// 需要重新选择节点时同时返回求解使用的输入，否则为nil
+func (r *PodGroupReconciler) placeMissingMembers(ctx context.Context, podGroup *corev1.PodGroup, templates map[string]*corev1.PodTemplate, pods []v1.Pod, missing []string) (map[string]string, *planning.Problem, error) {
+	res := make(map[string]string, len(missing))
+	candidates, err := r.listCandidateNodes(ctx)
+	if err != nil {
+		klog.Errorf("Failed to list candidate nodes, err: %v", err)
+		return nil, nil, err
+	}
+
+	var replan []string
+	for _, podName := range missing {
+		planned, ok := plannedNode(&podGroup.Status, podName)
+		if !ok {
+			// spec.podList中新增的Pod
+			replan = append(replan, podName)
+			continue
+		}
+		if planned == "" {
+			continue
+		}
+		node, ok := candidates[planned]
+		cpu, mem := model.PodRequests(&templates[podName].Spec)
+		if ok && node.Fits(cpu, mem) {
+			node.Consume(cpu, mem)
+			candidates[planned] = node
+			res[podName] = planned
+			continue
+		}
+		replan = append(replan, podName)
+	}
+	if len(replan) == 0 {
+		return res, nil, nil
+	}
+
+	// 计划节点不可用的Pod需要重新选择节点，失败时不设置节点亲和性
+	pRes := planning.ParsePodGroup(podGroup)
+	end := time.Now()
+	problem, err := r.buildProblem(ctx, podGroup, pRes, end.Add(-latencyWindow), end)
+	if err != nil {
+		klog.Errorf("Failed to re-plan Pods %v of PodGroup %s/%s, recreate them without NodeAffinity, err: %v", replan, podGroup.Namespace, podGroup.Name, err)
+		return res, nil, nil
+	}
+
+	// 其他Pod的位置优先取实际绑定的节点，尚未绑定时取计划的节点
+	located := make(map[string]string, len(pods))
+	for _, binding := range podGroup.Status.ScheduleResult {
+		located[binding.PodName] = binding.PlannedNodeName
+	}
+	for i := range pods {
+		if pods[i].Spec.NodeName != "" {
+			located[model.MemberOf(&pods[i])] = pods[i].Spec.NodeName
+		}
+	}
+	for podName, nodeName := range res {
+		located[podName] = nodeName
+	}
+	nodeIndex := make(map[string]int, len(problem.Nodes))
+	for i, node := range problem.Nodes {
+		nodeIndex[node.NodeName] = i
+	}
+	podIndex := make(map[string]int, len(problem.Pods))
+	fixed := make([]int, len(problem.Pods))
+	for i, pod := range problem.Pods {
+		podIndex[pod.PodName] = i
+		fixed[i] = -1
+		if idx, ok := nodeIndex[located[pod.PodName]]; ok && !slices.Contains(replan, pod.PodName) {
+			fixed[i] = idx
+		}
+	}
+
+	// 依赖越多的Pod越先选择节点
+	for _, podName := range planning.SortPodNameListByDegree(problem.PodDependencies, pRes.PodNameList) {
+		if !slices.Contains(replan, podName) {
+			continue
+		}
+		podIdx := podIndex[podName]
+		nodeIdx := planning.ReplanPod(problem, fixed, podIdx)
+		if nodeIdx < 0 {
+			klog.Warningf("No node fits Pod %s of PodGroup %s/%s, leave it to the default scheduler", podName, podGroup.Namespace, podGroup.Name)
+			continue
+		}
+		pod := problem.Pods[podIdx]
+		problem.Nodes[nodeIdx].Consume(pod.CPUReq, pod.MemReq)
+		fixed[podIdx] = nodeIdx
+		res[podName] = problem.Nodes[nodeIdx].NodeName
+		klog.Infof("Pod %s of PodGroup %s/%s re-planned to Node %s", podName, podGroup.Namespace, podGroup.Name, res[podName])
+	}
+	return res, problem, nil
+}
+
+// memberTemplates 返回成员Pod名称到其模板的映射，members为spec.Members()的结果
+func memberTemplates(members []corev1.PodTemplate) map[string]*corev1.PodTemplate {
+	res := make(map[string]*corev1.PodTemplate, len(members))
+	for i := range members {
+		res[members[i].Metadata.Name] = &members[i]
+	}
+	return res
+}
+
+// plannedNode 返回status中记录的Pod的计划节点，status中没有该Pod的记录时返回false
+func plannedNode(status *corev1.PodGroupStatus, podName string) (string, bool) {
+	for _, binding := range status.ScheduleResult {
+		if binding.PodName == podName {
+			return binding.PlannedNodeName, true
+		}
+	}
+	return "", false
+}
+
+// setPlannedNode 更新status中记录的Pod的计划节点
+func setPlannedNode(status *corev1.PodGroupStatus, podName, nodeName string) {
+	for i := range status.ScheduleResult {
+		if status.ScheduleResult[i].PodName == podName {
+			status.ScheduleResult[i].PlannedNodeName = nodeName
+			return
+		}
+	}
+	status.ScheduleResult = append(status.ScheduleResult, corev1.PodNodeBinding{PodName: podName, PlannedNodeName: nodeName})
+}
@@ -0,0 +1,76 @@
+package controller
+
+import (
+	"context"
+
+	corev1 "github.com/SMALL-head/podGroup/api/v1"
+	"github.com/SMALL-head/podGroup/internal/scheduling/model"
+	v1 "k8s.io/api/core/v1"
+)
+
+// listNodeDomains 返回可以调度的节点所在的拓扑域，key为节点名称，value为节点topologyKey标签的值，没有该标签的节点不在结果中
+func (r *PodGroupReconciler) listNodeDomains(ctx context.Context, topologyKey string) (map[string]string, error) {
+	nodeList := &v1.NodeList{}
+	if err := r.List(ctx, nodeList); err != nil {
+		return nil, err
+	}
+	res := make(map[string]string, len(nodeList.Items))
+	for i := range nodeList.Items {
+		node := &nodeList.Items[i]
+		if domain, ok := node.Labels[topologyKey]; ok && isNodeSchedulable(node) {
+			res[node.Name] = domain
+		}
+	}
+	return res, nil
+}
+
+// topologyKey 返回PodGroup使用的节点拓扑域标签，spec.topologyKey优先，未设置时使用controller的--topology-key参数
+func (r *PodGroupReconciler) topologyKey(podGroup *corev1.PodGroup) string {
+	if podGroup.Spec.TopologyKey != "" {
+		return podGroup.Spec.TopologyKey
+	}
+	return r.TopologyKey
+}
+
+// listCandidateNodes 返回可以参与placement的节点，key为节点名称
+// 节点容量为allocatable减去已经绑定在该节点上的Pod的资源请求
+func (r *PodGroupReconciler) listCandidateNodes(ctx context.Context) (map[string]model.Node, error) {
+	nodeList := &v1.NodeList{}
+	if err := r.List(ctx, nodeList); err != nil {
+		return nil, err
+	}
+	podList := &v1.PodList{}
+	if err := r.List(ctx, podList); err != nil {
+		return nil, err
+	}
+
+	podsByNode := make(map[string][]v1.Pod)
+	for _, p := range podList.Items {
+		if p.Spec.NodeName != "" {
+			podsByNode[p.Spec.NodeName] = append(podsByNode[p.Spec.NodeName], p)
+		}
+	}
+
+	res := make(map[string]model.Node, len(nodeList.Items))
+	for i := range nodeList.Items {
+		node := &nodeList.Items[i]
+		if !isNodeSchedulable(node) {
+			continue
+		}
+		res[node.Name] = model.NodeFromK8s(node, podsByNode[node.Name])
+	}
+	return res, nil
+}
+
+// isNodeSchedulable 节点处于Ready状态且未被cordon
+func isNodeSchedulable(node *v1.Node) bool {
+	if node.Spec.Unschedulable {
+		return false
+	}
+	for _, c := range node.Status.Conditions {
+		if c.Type == v1.NodeReady {
+			return c.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
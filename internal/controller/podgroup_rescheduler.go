@@ -0,0 +1,240 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	corev1 "github.com/SMALL-head/podGroup/api/v1"
+	"github.com/SMALL-head/podGroup/internal/scheduling/model"
+	"github.com/SMALL-head/podGroup/internal/scheduling/planning"
+	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+)
+
+// spec.rescheduling未设置的参数使用的默认值
+const (
+	defaultImprovementThreshold = 0.2
+	defaultMaxUnavailable       = 1
+)
+
+// maxMigrationHistory status.rescheduling.migrations中保留的迁移记录数量
+const maxMigrationHistory = 10
+
+// Rescheduler 周期性地使用最新的节点延迟重新评估设置了spec.rescheduling并处于Running阶段的PodGroup，
+// 预估代价降低的比例超过阈值时每次迁移一个成员Pod，作为Runnable加入Manager，只在leader上运行
+type Rescheduler struct {
+	Reconciler *PodGroupReconciler
+	// Interval 两次评估之间的间隔
+	Interval time.Duration
+}
+
+// Start 实现manager.Runnable，ctx结束时返回
+func (s *Rescheduler) Start(ctx context.Context) error {
+	klog.Infof("Rescheduler started, interval: %s", s.Interval)
+	wait.UntilWithContext(ctx, s.rescheduleAll, s.Interval)
+	return nil
+}
+
+// NeedLeaderElection 实现manager.LeaderElectionRunnable，只有leader迁移成员Pod
+func (s *Rescheduler) NeedLeaderElection() bool {
+	return true
+}
+
+func (s *Rescheduler) rescheduleAll(ctx context.Context) {
+	podGroupList := &corev1.PodGroupList{}
+	if err := s.Reconciler.List(ctx, podGroupList); err != nil {
+		klog.Errorf("Failed to list PodGroups for rescheduling, err: %v", err)
+		return
+	}
+	for i := range podGroupList.Items {
+		podGroup := &podGroupList.Items[i]
+		if podGroup.Spec.Rescheduling == nil || podGroup.Status.Phase != corev1.RunningPhase || !podGroup.DeletionTimestamp.IsZero() {
+			continue
+		}
+		if err := s.Reconciler.reschedule(ctx, podGroup); err != nil {
+			klog.Errorf("Failed to reschedule PodGroup %s/%s, err: %v", podGroup.Namespace, podGroup.Name, err)
+		}
+	}
+}
+
+// improvementThreshold 返回spec.rescheduling.improvementThreshold，未设置时为defaultImprovementThreshold
+func improvementThreshold(podGroup *corev1.PodGroup) float64 {
+	if q := podGroup.Spec.Rescheduling.ImprovementThreshold; q != nil {
+		return q.AsApproximateFloat64()
+	}
+	return defaultImprovementThreshold
+}
+
+// maxUnavailable 返回spec.rescheduling.maxUnavailable，未设置时为defaultMaxUnavailable
+func maxUnavailable(podGroup *corev1.PodGroup) int {
+	if n := podGroup.Spec.Rescheduling.MaxUnavailable; n != nil {
+		return int(*n)
+	}
+	return defaultMaxUnavailable
+}
+
+// reschedule 使用最新的节点延迟重新求解placement，并与成员Pod当前位置的代价比较，评估结果写入status.rescheduling
+// 代价降低的比例达到阈值、并且迁移后不可用的成员Pod不超过maxUnavailable时，选择迁移后代价最低的一个成员Pod迁移到新placement中的节点
+//   - 裸Pod成员更新计划节点后通过Eviction删除，由Reconcile在计划节点上重建，被PodDisruptionBudget拒绝时撤销本次迁移
+//   - Deployment与StatefulSet成员更新计划节点后重新渲染Pod模板，由工作负载滚动更新
+//   - Job成员不迁移
+//
+// podAffinity渲染方式下成员Pod的位置不依赖计划节点，不进行迁移
+func (r *PodGroupReconciler) reschedule(ctx context.Context, podGroup *corev1.PodGroup) error {
+	if podGroup.Spec.PlacementRendering == corev1.PodAffinityRendering {
+		return nil
+	}
+	pRes := planning.ParsePodGroup(podGroup)
+	if pRes == nil {
+		return nil
+	}
+
+	// 1. 成员Pod当前所在的节点
+	pods, err := r.listMemberPods(ctx, podGroup)
+	if err != nil {
+		return err
+	}
+	current := make(map[string]string, len(pods))
+	bare := make(map[string]*v1.Pod, len(pods))
+	// 工作负载滚动更新期间同一成员可能有多个Pod，按成员统计Ready数量
+	ready := make(map[string]bool, len(pods))
+	for i := range pods {
+		pod := &pods[i]
+		if !pod.DeletionTimestamp.IsZero() || pod.Spec.NodeName == "" || pod.Status.Phase == v1.PodFailed {
+			continue
+		}
+		member := model.MemberOf(pod)
+		if _, ok := current[member]; ok && !isPodReady(pod) {
+			continue
+		}
+		if isPodReady(pod) {
+			ready[member] = true
+		}
+		current[member] = pod.Spec.NodeName
+		if metav1.IsControlledBy(pod, podGroup) {
+			bare[member] = pod
+		}
+	}
+	if len(current) < len(pRes.PodNameList) {
+		klog.Infof("Only %d/%d pods of PodGroup %s/%s are bound, skip rescheduling", len(current), len(pRes.PodNameList), podGroup.Namespace, podGroup.Name)
+		return nil
+	}
+
+	// 2. 使用最新的节点延迟重新求解，成员Pod自身占用的资源视为可用
+	end := time.Now()
+	problem, err := r.buildProblem(ctx, podGroup, pRes, end.Add(-latencyWindow), end)
+	if err != nil {
+		return err
+	}
+	planning.ReleasePods(problem, current)
+	plan, _, err := r.computePlan(ctx, podGroup, problem)
+	if err != nil {
+		return err
+	}
+	// 求解结果缺少的成员保持在当前节点，使target与current覆盖相同的成员，两者的代价可以比较
+	target := make(map[string]string, len(current))
+	for podName, nodeName := range current {
+		target[podName] = nodeName
+		if planned, ok := plan.Assign[podName]; ok {
+			target[podName] = planned
+		}
+	}
+	currentCost, targetCost := problem.Cost(current), problem.Cost(target)
+	improvement := 0.0
+	if currentCost > 0 {
+		improvement = (currentCost - targetCost) / currentCost
+	}
+	oldStatus := podGroup.Status.DeepCopy()
+	status := podGroup.Status.Rescheduling
+	if status == nil {
+		status = &corev1.ReschedulingStatus{}
+		podGroup.Status.Rescheduling = status
+	}
+	now := metav1.Now()
+	status.CurrentCost, status.TargetCost = float2Quantity(currentCost), float2Quantity(targetCost)
+
+	// 3. 选择需要迁移的成员Pod
+	threshold := improvementThreshold(podGroup)
+	unavailable := len(pRes.PodNameList) - len(ready)
+	var migration planning.Migration
+	migrate := false
+	switch {
+	case improvement < threshold:
+		klog.Infof("PodGroup %s/%s predicted cost %.3f -> %.3f, improvement %.1f%% is below the threshold %.1f%%",
+			podGroup.Namespace, podGroup.Name, currentCost, targetCost, improvement*100, threshold*100)
+	case unavailable+1 > maxUnavailable(podGroup):
+		klog.Infof("PodGroup %s/%s has %d unavailable pods, wait for them before migrating", podGroup.Namespace, podGroup.Name, unavailable)
+	default:
+		// Job成员不迁移，保持在当前节点
+		for podName := range target {
+			if template := pRes.PodGroupMap[podName]; template.Workload == corev1.JobWorkload {
+				target[podName] = current[podName]
+			}
+		}
+		migration, migrate = planning.NextMigration(problem, current, target)
+	}
+	if !migrate {
+		// 评估结果与上一次相同时不更新status，避免每个评估周期都写入PodGroup
+		if equality.Semantic.DeepEqual(&podGroup.Status, oldStatus) {
+			return nil
+		}
+		status.LastEvaluationTime = &now
+		return r.Status().Update(ctx, podGroup)
+	}
+	status.LastEvaluationTime = &now
+
+	// 4. 先记录迁移与新的计划节点，再迁移成员Pod，使重建的Pod使用新的计划节点
+	reason := fmt.Sprintf("latency drift: predicted cost %.3f, re-planned %.3f (%.1f%% lower, threshold %.1f%%), moving %s lowers it to %.3f",
+		currentCost, targetCost, improvement*100, threshold*100, migration.PodName, migration.Cost)
+	klog.Infof("Migrating Pod %s of PodGroup %s/%s from Node %s to Node %s, %s", migration.PodName, podGroup.Namespace, podGroup.Name, migration.FromNode, migration.ToNode, reason)
+	planned, _ := plannedNode(&podGroup.Status, migration.PodName)
+	setPlannedNode(&podGroup.Status, migration.PodName, migration.ToNode)
+	status.Migrations = append(status.Migrations, corev1.Migration{
+		PodName:  migration.PodName,
+		FromNode: migration.FromNode,
+		ToNode:   migration.ToNode,
+		Time:     now,
+		Reason:   reason,
+	})
+	if len(status.Migrations) > maxMigrationHistory {
+		status.Migrations = slices.Clone(status.Migrations[len(status.Migrations)-maxMigrationHistory:])
+	}
+	if err := r.Status().Update(ctx, podGroup); err != nil {
+		return err
+	}
+
+	template := pRes.PodGroupMap[migration.PodName]
+	if template.IsWorkload() {
+		workloads, err := r.listMemberWorkloads(ctx, podGroup)
+		if err != nil {
+			return err
+		}
+		for _, obj := range workloads[migration.PodName] {
+			if model.WorkloadKindOf(obj) == template.Workload && obj.GetDeletionTimestamp().IsZero() {
+				return r.rolloutMemberWorkload(ctx, podGroup, pRes, problem, &template, obj)
+			}
+		}
+		return nil
+	}
+	pod := bare[migration.PodName]
+	eviction := &policyv1.Eviction{
+		ObjectMeta:    metav1.ObjectMeta{Name: pod.Name, Namespace: pod.Namespace},
+		DeleteOptions: &metav1.DeleteOptions{Preconditions: &metav1.Preconditions{UID: &pod.UID}},
+	}
+	if err := r.SubResource("eviction").Create(ctx, pod, eviction); err != nil {
+		// 被PodDisruptionBudget拒绝等情况下撤销本次迁移，下一次评估时重新尝试
+		klog.Warningf("Failed to evict Pod %s/%s, revert the migration, err: %v", pod.Namespace, pod.Name, err)
+		// Update会将返回的对象写回podGroup，status可能已经不再指向podGroup.Status.Rescheduling
+		setPlannedNode(&podGroup.Status, migration.PodName, planned)
+		status = podGroup.Status.Rescheduling
+		status.Migrations = status.Migrations[:len(status.Migrations)-1]
+		return r.Status().Update(ctx, podGroup)
+	}
+	return nil
+}
@@ -0,0 +1,441 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "github.com/SMALL-head/podGroup/api/v1"
+	"github.com/SMALL-head/podGroup/internal/scheduling/audit"
+	"github.com/SMALL-head/podGroup/internal/scheduling/model"
+	"github.com/SMALL-head/podGroup/internal/scheduling/planning"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// latencyWindow placement使用最近一段时间内的节点延迟数据
+const latencyWindow = 5 * time.Minute
+
+// defaultPreferredNeighbors 未设置spec.preferredNeighbors时，preferred模式下额外偏好的节点数量
+const defaultPreferredNeighbors = 3
+
+// schedule 计算PodGroup的placement并创建成员Pod，完成后设置PlacementComputed与PodsCreated两个Condition
+// placement在创建Pod之前写入status，中途失败或者controller重启后再次进入时使用status中记录的placement继续创建剩余的Pod
+func (r *PodGroupReconciler) schedule(ctx context.Context, podGroup *corev1.PodGroup) (ctrl.Result, error) {
+	// 1. 解析dependencies
+	pRes := planning.ParsePodGroup(podGroup)
+	if pRes == nil {
+		return ctrl.Result{}, nil
+	}
+
+	// 进入Scheduling阶段
+	if podGroup.Status.Phase != corev1.SchedulingPhase {
+		podGroup.Status.Phase = corev1.SchedulingPhase
+		if podGroup.Status.ScheduleStartTime == nil {
+			now := metav1.Now()
+			podGroup.Status.ScheduleStartTime = &now
+		}
+		if err := r.Status().Update(ctx, podGroup); err != nil {
+			klog.Errorf("Failed to update status for PodGroup %s/%s, err: %v", podGroup.Namespace, podGroup.Name, err)
+			return ctrl.Result{}, err
+		}
+	}
+
+	// 2. 获取最近5分钟的节点延迟数据并求解placement，求解结果写入status
+	end := latencyWindowEnd(podGroup)
+	start := end.Add(-latencyWindow)
+	var problem *planning.Problem
+	if !isPlacementRecorded(podGroup) {
+		// 设置了spec.minMember时，创建任何Pod之前检查剩余资源能否放下minMember个Pod
+		if podGroup.Spec.MinMember != nil {
+			fitting, err := r.countFittingPods(ctx, pRes)
+			if err != nil {
+				klog.Errorf("Failed to list candidate nodes, err: %v", err)
+				return ctrl.Result{}, err
+			}
+			if fitting < minMember(podGroup) {
+				return r.waitForCapacity(ctx, podGroup, fitting)
+			}
+		}
+		var err error
+		if problem, err = r.recordPlacement(ctx, podGroup, pRes, start, end); err != nil {
+			return ctrl.Result{}, err
+		}
+	} else {
+		klog.Infof("PodGroup %s/%s resumes creating pods from the recorded placement", podGroup.Namespace, podGroup.Name)
+	}
+
+	// 3. placement按照spec.placementEnforcement通过nodeAffinity约束Pod，放不下的Pod不设置节点亲和性，由默认调度器决定其位置
+	// placement求解失败时降级为普通的调度模式，所有Pod都不设置节点亲和性
+	problem = r.preferenceProblem(ctx, podGroup, pRes, problem)
+	for _, podName := range pRes.PodNameList {
+		node, ok := plannedNode(&podGroup.Status, podName)
+		if !ok {
+			// 记录placement之后新增的Pod
+			setPlannedNode(&podGroup.Status, podName, "")
+		}
+		template := pRes.PodGroupMap[podName]
+		pod, err := r.newMemberPod(podGroup, pRes, template, node, problem)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		// 创建Pod，设置了工作负载的成员创建对应的工作负载
+		if err = r.createMember(ctx, podGroup, &template, &pod); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	// 为依赖中接收流量的成员Pod创建Service
+	if err := r.syncServices(ctx, podGroup, nil); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// 异步上报延迟
+	if meta.IsStatusConditionTrue(podGroup.Status.Conditions, corev1.PlacementComputedCondition) && r.PromeClient != nil {
+		go func(pg *corev1.PodGroup) {
+			if err := audit.ReportLatencyInfo(r.PromeClient, r.FlareAdminClient, start.Format(time.RFC3339), end.Format(time.RFC3339), pg); err != nil {
+				klog.Errorf("上报延迟信息出错: %v", err)
+			}
+		}(podGroup.DeepCopy())
+	}
+
+	if _, err := r.markPodsCreated(ctx, podGroup, len(pRes.PodNameList)); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: gangRequeueAfter(podGroup)}, nil
+}
+
+// isPlacementRecorded placement的结果（包括降级为默认调度器）是否已经写入status
+func isPlacementRecorded(podGroup *corev1.PodGroup) bool {
+	c := meta.FindStatusCondition(podGroup.Status.Conditions, corev1.PlacementComputedCondition)
+	return c != nil && (c.Reason == reasonPlacementComputed || c.Reason == reasonPlacementFailed)
+}
+
+// recordPlacement 求解placement，并将每个Pod的计划节点以及PlacementComputed Condition写入status，返回求解使用的输入
+// 求解失败时降级为默认调度器，此时所有Pod的计划节点均为空，获取节点延迟失败时返回的求解输入为nil
+func (r *PodGroupReconciler) recordPlacement(ctx context.Context, podGroup *corev1.PodGroup, pRes *model.PodGroupParseResult, start, end time.Time) (*planning.Problem, error) {
+	var plan *planning.Plan
+	var placement *corev1.PlacementStatus
+	problem, err := r.buildProblem(ctx, podGroup, pRes, start, end)
+	if err == nil {
+		plan, placement, err = r.computePlan(ctx, podGroup, problem)
+	}
+	if err != nil {
+		klog.Errorf("Failed to compute placement for PodGroup %s/%s, err: %v", podGroup.Namespace, podGroup.Name, err)
+		meta.SetStatusCondition(&podGroup.Status.Conditions, metav1.Condition{
+			Type:    corev1.PlacementComputedCondition,
+			Status:  metav1.ConditionFalse,
+			Reason:  reasonPlacementFailed,
+			Message: fmt.Sprintf("fallback to the default scheduler: %v", err),
+		})
+		podGroup.Status.Placement = &corev1.PlacementStatus{Fallback: true}
+		podGroup.Status.ScheduleResult = newScheduleResult(pRes.PodNameList, nil)
+	} else {
+		meta.SetStatusCondition(&podGroup.Status.Conditions, metav1.Condition{
+			Type:    corev1.PlacementComputedCondition,
+			Status:  metav1.ConditionTrue,
+			Reason:  reasonPlacementComputed,
+			Message: fmt.Sprintf("%d/%d pods placed with affinity", len(plan.Assign), len(pRes.PodNameList)),
+		})
+		checkReplayInputs(podGroup, placement.InputDigest)
+		windowEnd := metav1.NewTime(end)
+		placement.LatencyWindowEnd = &windowEnd
+		podGroup.Status.Placement = placement
+		podGroup.Status.ScheduleResult = newScheduleResult(pRes.PodNameList, plan.Assign)
+	}
+	if err := r.Status().Update(ctx, podGroup); err != nil {
+		klog.Errorf("Failed to update status for PodGroup %s/%s, err: %v", podGroup.Namespace, podGroup.Name, err)
+		return nil, err
+	}
+	return problem, nil
+}
+
+// computePlan 使用spec.placementStrategy指定的算法求解placement，并返回placement的求解信息
+func (r *PodGroupReconciler) computePlan(ctx context.Context, podGroup *corev1.PodGroup, problem *planning.Problem) (*planning.Plan, *corev1.PlacementStatus, error) {
+	// 1. 根据spec.placementStrategy选择求解算法
+	solver, err := planning.NewSolver(&podGroup.Spec)
+	if err != nil {
+		return nil, nil, err
+	}
+	seed := placementSeed(podGroup)
+	problem.Seed = seed
+	digest := problem.Digest()
+
+	// 2. 求解placement，求解失败时退化为贪心placement
+	plan, err := solver.Solve(ctx, problem)
+	if err != nil {
+		klog.Errorf("Solver %s failed for PodGroup %s/%s, fallback to greedy, err: %v", solver.Name(), podGroup.Namespace, podGroup.Name, err)
+		solver, _ = planning.NewSolver(&corev1.PodGroupSpec{PlacementStrategy: corev1.GreedyStrategy})
+		if plan, err = solver.Solve(ctx, problem); err != nil {
+			return nil, nil, err
+		}
+	}
+	cost := problem.Cost(plan.Assign)
+	if !plan.Replayable() {
+		klog.Warningf("Solver %s stopped early for PodGroup %s/%s, the placement cannot be replayed with seed %d", solver.Name(), podGroup.Namespace, podGroup.Name, seed)
+	}
+	klog.Infof("PodGroup %s/%s placement computed by %s, seed: %d, input digest: %s, score: %f, predicted cost: %f",
+		podGroup.Namespace, podGroup.Name, solver.Name(), seed, digest, plan.Score, cost)
+	placement := &corev1.PlacementStatus{
+		Strategy:      solver.Name(),
+		PredictedCost: float2Quantity(cost),
+		Seed:          &seed,
+		InputDigest:   digest,
+	}
+	if plan.Gap != nil {
+		placement.OptimalityGap = float2Quantity(*plan.Gap)
+	}
+	for _, c := range plan.Chains {
+		placement.Chains = append(placement.Chains, corev1.AnnealingChainStatus{
+			Seed:          c.Seed,
+			Iterations:    int32(c.Iterations),
+			BestIteration: int32(c.BestIteration),
+			BestScore:     float2Quantity(c.BestScore),
+			StopReason:    string(c.StopReason),
+		})
+	}
+	return plan, placement, nil
+}
+
+// placementSeed 返回求解使用的随机数种子，设置了ReplaySeedAnnotation时使用注解中的种子复现placement，否则使用新的种子
+func placementSeed(podGroup *corev1.PodGroup) int64 {
+	if value, ok := podGroup.Annotations[corev1.ReplaySeedAnnotation]; ok {
+		seed, err := strconv.ParseInt(value, 10, 64)
+		if err == nil {
+			klog.Infof("PodGroup %s/%s replays the placement with seed %d", podGroup.Namespace, podGroup.Name, seed)
+			return seed
+		}
+		klog.Warningf("Ignore invalid annotation %s=%s of PodGroup %s/%s, err: %v", corev1.ReplaySeedAnnotation, value, podGroup.Namespace, podGroup.Name, err)
+	}
+	return time.Now().UnixNano()
+}
+
+// checkReplayInputs 复现placement时比较本次求解输入的摘要与原placement的摘要，结果写入ReplayInputsMatched Condition
+// 原placement的摘要取自ReplayInputDigestAnnotation，未设置时使用status中记录的摘要，没有设置ReplaySeedAnnotation时删除该Condition
+func checkReplayInputs(podGroup *corev1.PodGroup, digest string) {
+	if _, ok := podGroup.Annotations[corev1.ReplaySeedAnnotation]; !ok {
+		meta.RemoveStatusCondition(&podGroup.Status.Conditions, corev1.ReplayInputsMatchedCondition)
+		return
+	}
+	recorded := podGroup.Annotations[corev1.ReplayInputDigestAnnotation]
+	if recorded == "" && podGroup.Status.Placement != nil {
+		recorded = podGroup.Status.Placement.InputDigest
+	}
+	c := metav1.Condition{
+		Type:    corev1.ReplayInputsMatchedCondition,
+		Status:  metav1.ConditionTrue,
+		Reason:  reasonInputsMatched,
+		Message: fmt.Sprintf("input digest %s matches the recorded placement", digest),
+	}
+	switch {
+	case recorded == "":
+		c.Status, c.Reason = metav1.ConditionUnknown, reasonDigestNotRecorded
+		c.Message = fmt.Sprintf("no input digest recorded to compare with %s", digest)
+	case recorded != digest:
+		c.Status, c.Reason = metav1.ConditionFalse, reasonInputsChanged
+		c.Message = fmt.Sprintf("input digest %s differs from the recorded %s, the placement may not be reproduced", digest, recorded)
+		klog.Warningf("PodGroup %s/%s replays the placement with different inputs, digest: %s, recorded: %s", podGroup.Namespace, podGroup.Name, digest, recorded)
+	}
+	meta.SetStatusCondition(&podGroup.Status.Conditions, c)
+}
+
+// latencyWindowEnd 返回placement使用的节点延迟时间窗口的结束时间，设置了ReplayWindowEndAnnotation时使用注解中的时间，否则为当前时间
+func latencyWindowEnd(podGroup *corev1.PodGroup) time.Time {
+	if value, ok := podGroup.Annotations[corev1.ReplayWindowEndAnnotation]; ok {
+		end, err := time.Parse(time.RFC3339, value)
+		if err == nil {
+			klog.Infof("PodGroup %s/%s replays the placement with node latencies until %s", podGroup.Namespace, podGroup.Name, value)
+			return end
+		}
+		klog.Warningf("Ignore invalid annotation %s=%s of PodGroup %s/%s, err: %v", corev1.ReplayWindowEndAnnotation, value, podGroup.Namespace, podGroup.Name, err)
+	}
+	return time.Now()
+}
+
+// buildProblem 获取[start, end]时间段内两两节点之间的延迟以及节点剩余资源，构造placement的求解输入
+// 设置了拓扑域标签时，没有延迟数据的节点也参与求解，其延迟由拓扑域之间的延迟补全
+func (r *PodGroupReconciler) buildProblem(ctx context.Context, podGroup *corev1.PodGroup, pRes *model.PodGroupParseResult, start, end time.Time) (*planning.Problem, error) {
+	// 1. 获取节点延迟
+	if r.PromeClient == nil {
+		return nil, fmt.Errorf("prometheus client is not configured")
+	}
+	resMatrix, err := r.PromeClient.GetLatencyByTimeRange(start.Format(time.RFC3339), end.Format(time.RFC3339))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latency from Prometheus: %w", err)
+	}
+
+	// 1.1 构造两两节点之间的延迟矩阵
+	latencyMatrix := model.PrometheusMatrix2LatencyMatrix(resMatrix)
+
+	// 2. 获取节点剩余资源
+	candidates, err := r.listCandidateNodes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list candidate nodes: %w", err)
+	}
+	if topologyKey := r.topologyKey(podGroup); topologyKey != "" {
+		// 2.1 所有可以调度的节点都参与求解，缺失的延迟由拓扑域之间的延迟补全
+		domains, err := r.listNodeDomains(ctx, topologyKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list node topology domains: %w", err)
+		}
+		candidateNames := slices.Sorted(maps.Keys(candidates))
+		latencyMatrix = latencyMatrix.WithTopology(candidateNames, domains, podGroup.Spec.TopologyAggregation)
+	} else {
+		// 2.2 只保留既有延迟数据又可以调度的节点
+		candidateNames := make([]string, 0, len(candidates))
+		for _, nodeName := range latencyMatrix.NodeNameList {
+			if _, ok := candidates[nodeName]; ok {
+				candidateNames = append(candidateNames, nodeName)
+			}
+		}
+		latencyMatrix = latencyMatrix.Subset(candidateNames)
+	}
+	if latencyMatrix.MissingPairs > 0 {
+		klog.Warningf("%d node pairs have no latency samples, fallback to the max observed latency", latencyMatrix.MissingPairs)
+	}
+	nodes := make([]model.Node, len(latencyMatrix.NodeNameList))
+	for i, nodeName := range latencyMatrix.NodeNameList {
+		nodes[i] = candidates[nodeName]
+	}
+	return planning.NewProblem(pRes, nodes, latencyMatrix.Latencies), nil
+}
+
+// preferenceProblem 返回preferred模式下用于选择偏好节点的节点延迟，其他模式或者获取节点延迟失败时返回nil
+// problem不为nil时直接复用，否则重新获取最近一段时间内的节点延迟
+func (r *PodGroupReconciler) preferenceProblem(ctx context.Context, podGroup *corev1.PodGroup, pRes *model.PodGroupParseResult, problem *planning.Problem) *planning.Problem {
+	if podGroup.Spec.PlacementEnforcement != corev1.PreferredEnforcement || problem != nil {
+		return problem
+	}
+	end := time.Now()
+	problem, err := r.buildProblem(ctx, podGroup, pRes, end.Add(-latencyWindow), end)
+	if err != nil {
+		klog.Warningf("Failed to get node latencies for PodGroup %s/%s, only the planned nodes are preferred, err: %v", podGroup.Namespace, podGroup.Name, err)
+		return nil
+	}
+	return problem
+}
+
+// preferredNeighbors 返回spec.preferredNeighbors，未设置时为defaultPreferredNeighbors
+func preferredNeighbors(podGroup *corev1.PodGroup) int {
+	if podGroup.Spec.PreferredNeighbors == nil {
+		return defaultPreferredNeighbors
+	}
+	return int(*podGroup.Spec.PreferredNeighbors)
+}
+
+// newMemberPod 根据PodTemplate构造成员Pod，成员由工作负载创建或者启用了spec.serviceMode时，
+// 设置PodGroup名称、成员名称与所属模板三个标签供工作负载与Service选择
+// podAffinity渲染方式下，Pod通过Pod亲和性偏好其通信权重最大的依赖所在的节点，与node无关
+// 否则node不为空时按照spec.placementEnforcement通过节点亲和性约束Pod
+//   - required: 通过requiredDuringScheduling节点亲和性将Pod绑定到node
+//   - preferred: 优先node，其次是problem中与node延迟最低的节点，problem为nil时只偏好node
+//   - none: 不设置节点亲和性
+//
+// pRes为调用方解析得到的PodGroup，podAffinity渲染方式下从中选择依赖
+func (r *PodGroupReconciler) newMemberPod(podGroup *corev1.PodGroup, pRes *model.PodGroupParseResult, template corev1.PodTemplate, node string, problem *planning.Problem) (v1.Pod, error) {
+	gvk, _, err := r.Scheme.ObjectKinds(podGroup)
+	// 注： 这里的gvk是一个长度为1的数组，其中Group = "core.cic.io", Version = "v1", Kind = "PodGroup"
+	if err != nil || len(gvk) == 0 {
+		klog.Errorf("Failed to get GVK from Scheme, err: %v", err)
+		return v1.Pod{}, fmt.Errorf("failed to get GVK of PodGroup: %v", err)
+	}
+	var pod v1.Pod
+	switch {
+	case podGroup.Spec.PlacementRendering == corev1.PodAffinityRendering:
+		prefs := planning.HeaviestDependencies(pRes, template.Metadata.Name, preferredNeighbors(podGroup))
+		topologyKey := r.topologyKey(podGroup)
+		if topologyKey == "" {
+			topologyKey = model.HostnameLabel
+		}
+		pod = model.PodTemplate2PodSpecWithPodAffinity(template, podGroup.ObjectMeta, prefs, topologyKey, gvk[0])
+		klog.Infof("Creating Pod %s/%s with PodAffinity %v", pod.Namespace, pod.Name, prefs)
+	case node == "":
+		pod = model.CreatePodWithoutAffinity(template, podGroup.ObjectMeta, gvk[0])
+		klog.Infof("Creating Pod %s/%s without NodeAffinity", pod.Namespace, pod.Name)
+	case podGroup.Spec.PlacementEnforcement == corev1.PreferredEnforcement:
+		nodes := []string{node}
+		if problem != nil {
+			nodes = append(nodes, problem.NearestNodes(node, preferredNeighbors(podGroup))...)
+		}
+		pod = model.PodTemplate2PodSpecWithPreferences(template, podGroup.ObjectMeta, model.RankNodePreferences(nodes), gvk[0])
+		klog.Infof("Creating Pod %s/%s preferring Nodes %v", pod.Namespace, pod.Name, nodes)
+	case podGroup.Spec.PlacementEnforcement == corev1.NoneEnforcement:
+		pod = model.PodTemplate2PodSpecWithPreferences(template, podGroup.ObjectMeta, nil, gvk[0])
+		klog.Infof("Creating Pod %s/%s without NodeAffinity, planned Node %s", pod.Namespace, pod.Name, node)
+	default:
+		pod = model.PodTemplate2PodSpec(template, podGroup.ObjectMeta, node, gvk[0])
+		klog.Infof("Creating Pod %s/%s on Node (Affinity) %s", pod.Namespace, pod.Name, node)
+	}
+	if template.IsWorkload() || podGroup.Spec.ServiceMode != "" && podGroup.Spec.ServiceMode != corev1.NoneServiceMode {
+		model.SetMemberLabels(&pod, podGroup.Name, template.Metadata.Name, memberRole(podGroup, template.Metadata.Name))
+	}
+	return pod, nil
+}
+
+// createMemberPod 创建成员Pod，同名的成员Pod已经存在时视为创建成功
+func (r *PodGroupReconciler) createMemberPod(ctx context.Context, podGroup *corev1.PodGroup, pod *v1.Pod) error {
+	return r.createControlled(ctx, podGroup, pod, "Pod")
+}
+
+// createControlled 创建由PodGroup控制的对象，同名对象已经存在时视为创建成功，但是不属于该PodGroup时返回错误
+func (r *PodGroupReconciler) createControlled(ctx context.Context, podGroup *corev1.PodGroup, obj client.Object, kind string) error {
+	err := r.Create(ctx, obj)
+	if err == nil {
+		return nil
+	}
+	if !errors.IsAlreadyExists(err) {
+		klog.Errorf("Failed to create %s %s/%s, err: %v", kind, obj.GetNamespace(), obj.GetName(), err)
+		return err
+	}
+	existing := obj.DeepCopyObject().(client.Object)
+	if err := r.Get(ctx, client.ObjectKeyFromObject(obj), existing); err != nil {
+		klog.Errorf("Failed to get %s %s/%s, err: %v", kind, obj.GetNamespace(), obj.GetName(), err)
+		return err
+	}
+	if !metav1.IsControlledBy(existing, podGroup) {
+		return fmt.Errorf("%s %s/%s already exists and is not controlled by PodGroup %s", strings.ToLower(kind), obj.GetNamespace(), obj.GetName(), podGroup.Name)
+	}
+	klog.Infof("%s %s/%s already exists, skip creating it", kind, obj.GetNamespace(), obj.GetName())
+	return nil
+}
+
+// float2Quantity 将浮点数保留三位小数转换为Quantity
+func float2Quantity(v float64) *resource.Quantity {
+	return resource.NewMilliQuantity(int64(math.Round(v*1000)), resource.DecimalSI)
+}
+
+// newScheduleResult 按照Pod名称记录placement计划的节点，assign中没有的Pod计划节点为空
+func newScheduleResult(podNameList []string, assign map[string]string) []corev1.PodNodeBinding {
+	res := make([]corev1.PodNodeBinding, 0, len(podNameList))
+	for _, podName := range podNameList {
+		res = append(res, corev1.PodNodeBinding{PodName: podName, PlannedNodeName: assign[podName]})
+	}
+	return res
+}
+
+// markPodsCreated 设置PodsCreated Condition并更新status
+func (r *PodGroupReconciler) markPodsCreated(ctx context.Context, podGroup *corev1.PodGroup, podCount int) (ctrl.Result, error) {
+	meta.SetStatusCondition(&podGroup.Status.Conditions, metav1.Condition{
+		Type:    corev1.PodsCreatedCondition,
+		Status:  metav1.ConditionTrue,
+		Reason:  reasonPodsCreated,
+		Message: fmt.Sprintf("%d pods created", podCount),
+	})
+	podGroup.Status.ObservedGeneration = podGroup.Generation
+	if err := r.Status().Update(ctx, podGroup); err != nil {
+		klog.Errorf("Failed to update status for PodGroup %s/%s, err: %v", podGroup.Namespace, podGroup.Name, err)
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
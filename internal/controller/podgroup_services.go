@@ -0,0 +1,176 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "github.com/SMALL-head/podGroup/api/v1"
+	"github.com/SMALL-head/podGroup/internal/scheduling/model"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// memberRole 返回成员Pod所属的模板名称，不存在时返回空字符串
+func memberRole(podGroup *corev1.PodGroup, member string) string {
+	if template := podGroup.Spec.TemplateOf(member); template != nil {
+		return template.Metadata.Name
+	}
+	return ""
+}
+
+// serviceReceivers 返回spec.dependencies中接收流量的成员Pod以及模板，双向依赖的两侧都接收流量
+func serviceReceivers(spec *corev1.PodGroupSpec) (members, roles map[string]bool) {
+	members, roles = make(map[string]bool), make(map[string]bool)
+	receive := func(name string, index *int32) {
+		roles[name] = true
+		for _, member := range spec.ResolveMembers(name, index) {
+			members[member] = true
+		}
+	}
+	for _, dep := range spec.Dependencies {
+		if dep.Direction != corev1.DirectionP1ToP2 {
+			receive(dep.P1, dep.P1Index)
+		}
+		if dep.Direction != corev1.DirectionP2ToP1 {
+			receive(dep.P2, dep.P2Index)
+		}
+	}
+	return members, roles
+}
+
+// desiredServices 根据spec.serviceMode返回PodGroup需要的Service，只有依赖中接收流量并且容器声明了ports的成员Pod才需要Service
+//   - perMember: 每个成员Pod一个与Pod同名的headless Service，通过PodGroupMemberLabel选择该Pod
+//   - perRole: 每个模板一个与模板同名的Service，通过PodGroupRoleLabel选择该模板的全部副本
+func desiredServices(podGroup *corev1.PodGroup, gvk schema.GroupVersionKind) []v1.Service {
+	members, roles := serviceReceivers(&podGroup.Spec)
+	var res []v1.Service
+	switch podGroup.Spec.ServiceMode {
+	case corev1.PerMemberServiceMode:
+		for _, member := range podGroup.Spec.Members() {
+			ports := model.ServicePorts(&member.Spec)
+			if !members[member.Metadata.Name] || len(ports) == 0 {
+				continue
+			}
+			selector := map[string]string{
+				model.PodGroupNameLabel:   podGroup.Name,
+				model.PodGroupMemberLabel: member.Metadata.Name,
+			}
+			res = append(res, model.NewMemberService(member.Metadata.Name, selector, ports, true, podGroup.ObjectMeta, gvk))
+		}
+	case corev1.PerRoleServiceMode:
+		for i := range podGroup.Spec.PodList {
+			template := &podGroup.Spec.PodList[i]
+			ports := model.ServicePorts(&template.Spec)
+			if !roles[template.Metadata.Name] || len(ports) == 0 {
+				continue
+			}
+			selector := map[string]string{
+				model.PodGroupNameLabel: podGroup.Name,
+				model.PodGroupRoleLabel: template.Metadata.Name,
+			}
+			res = append(res, model.NewMemberService(template.Metadata.Name, selector, ports, false, podGroup.ObjectMeta, gvk))
+		}
+	}
+	return res
+}
+
+// syncServices 使PodGroup拥有的Service与spec.serviceMode保持一致，pods为未被删除的成员Pod
+//   - 为缺少成员标签的Pod（启用Service之前创建的Pod）补充标签，使Service能够选择到这些Pod
+//   - 创建缺失的Service，更新端口或者selector发生变化的Service
+//   - 删除不再需要的Service，以及headless与否发生变化的Service，后者由Service的删除事件触发下一次Reconcile重建
+func (r *PodGroupReconciler) syncServices(ctx context.Context, podGroup *corev1.PodGroup, pods []v1.Pod) error {
+	gvk, _, err := r.Scheme.ObjectKinds(podGroup)
+	if err != nil || len(gvk) == 0 {
+		klog.Errorf("Failed to get GVK from Scheme, err: %v", err)
+		return fmt.Errorf("failed to get GVK of PodGroup: %v", err)
+	}
+	desired := desiredServices(podGroup, gvk[0])
+	if len(desired) > 0 {
+		if err := r.labelMemberPods(ctx, podGroup, pods); err != nil {
+			return err
+		}
+	}
+
+	// Service创建时带有PodGroupNameLabel，只列出带有该标签的Service
+	serviceList := &v1.ServiceList{}
+	if err := r.List(ctx, serviceList, client.InNamespace(podGroup.Namespace), client.MatchingLabels{model.PodGroupNameLabel: podGroup.Name}); err != nil {
+		klog.Errorf("Failed to list services of PodGroup %s/%s, err: %v", podGroup.Namespace, podGroup.Name, err)
+		return err
+	}
+	existing := make(map[string]*v1.Service, len(serviceList.Items))
+	for i := range serviceList.Items {
+		svc := &serviceList.Items[i]
+		if metav1.IsControlledBy(svc, podGroup) && svc.DeletionTimestamp.IsZero() {
+			existing[svc.Name] = svc
+		}
+	}
+
+	wanted := make(map[string]bool, len(desired))
+	for i := range desired {
+		svc := &desired[i]
+		wanted[svc.Name] = true
+		current, ok := existing[svc.Name]
+		switch {
+		case !ok:
+			if err := r.createMemberService(ctx, podGroup, svc); err != nil {
+				return err
+			}
+		case (current.Spec.ClusterIP == v1.ClusterIPNone) != (svc.Spec.ClusterIP == v1.ClusterIPNone):
+			wanted[svc.Name] = false
+		case !equality.Semantic.DeepEqual(current.Spec.Ports, svc.Spec.Ports) || !equality.Semantic.DeepEqual(current.Spec.Selector, svc.Spec.Selector):
+			current.Spec.Ports, current.Spec.Selector = svc.Spec.Ports, svc.Spec.Selector
+			klog.Infof("Updating Service %s/%s of PodGroup %s", current.Namespace, current.Name, podGroup.Name)
+			if err := r.Update(ctx, current); err != nil {
+				klog.Errorf("Failed to update Service %s/%s, err: %v", current.Namespace, current.Name, err)
+				return err
+			}
+		}
+	}
+	for name, svc := range existing {
+		if wanted[name] {
+			continue
+		}
+		klog.Infof("Service %s/%s is no longer needed by PodGroup %s, deleting it", svc.Namespace, svc.Name, podGroup.Name)
+		if err := r.Delete(ctx, svc, client.Preconditions{UID: &svc.UID}); client.IgnoreNotFound(err) != nil {
+			klog.Errorf("Failed to delete Service %s/%s, err: %v", svc.Namespace, svc.Name, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// createMemberService 创建Service，同名的Service已经存在时视为创建成功，但是不属于该PodGroup时返回错误
+func (r *PodGroupReconciler) createMemberService(ctx context.Context, podGroup *corev1.PodGroup, svc *v1.Service) error {
+	if err := r.createControlled(ctx, podGroup, svc, "Service"); err != nil {
+		return err
+	}
+	klog.Infof("Service %s/%s of PodGroup %s created", svc.Namespace, svc.Name, podGroup.Name)
+	return nil
+}
+
+// labelMemberPods 为缺少成员标签的Pod补充PodGroupNameLabel、PodGroupMemberLabel与PodGroupRoleLabel
+func (r *PodGroupReconciler) labelMemberPods(ctx context.Context, podGroup *corev1.PodGroup, pods []v1.Pod) error {
+	for i := range pods {
+		pod := &pods[i]
+		if !metav1.IsControlledBy(pod, podGroup) {
+			// 工作负载创建的Pod使用工作负载Pod模板中的标签
+			continue
+		}
+		role := memberRole(podGroup, pod.Name)
+		if pod.Labels[model.PodGroupNameLabel] == podGroup.Name && pod.Labels[model.PodGroupMemberLabel] == pod.Name &&
+			(role == "" || pod.Labels[model.PodGroupRoleLabel] == role) {
+			continue
+		}
+		patch := client.MergeFrom(pod.DeepCopy())
+		model.SetMemberLabels(pod, podGroup.Name, pod.Name, role)
+		if err := r.Patch(ctx, pod, patch); client.IgnoreNotFound(err) != nil {
+			klog.Errorf("Failed to label Pod %s/%s, err: %v", pod.Namespace, pod.Name, err)
+			return err
+		}
+	}
+	return nil
+}
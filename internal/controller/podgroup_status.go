@@ -0,0 +1,211 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "github.com/SMALL-head/podGroup/api/v1"
+	"github.com/SMALL-head/podGroup/internal/scheduling/model"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Condition的Reason
+const (
+	reasonPending           = "Pending"
+	reasonPlacementComputed = "PlacementComputed"
+	reasonPlacementFailed   = "PlacementFailed"
+	reasonPodsCreated       = "PodsCreated"
+	reasonPodsPending       = "PodsPending"
+	reasonAllPodsBound      = "AllPodsBound"
+	reasonPodsNotReady      = "PodsNotReady"
+	reasonAllPodsReady      = "AllPodsReady"
+	reasonInputsMatched     = "InputsMatched"
+	reasonInputsChanged     = "InputsChanged"
+	reasonDigestNotRecorded = "DigestNotRecorded"
+)
+
+// memberStats 成员Pod的状态统计
+type memberStats struct {
+	expected  int
+	minMember int
+	existing  int
+	bound     int
+	ready     int
+	running   int
+	succeeded int
+	// failed 不会被重建的失败Pod，需要重建的失败Pod已经在repairMembers中删除，工作负载的失败Pod由工作负载重建，不计入统计
+	failed int
+}
+
+func newMemberStats(expected, minMember int, pods []v1.Pod) memberStats {
+	res := memberStats{expected: expected, minMember: minMember, existing: len(pods)}
+	for i := range pods {
+		pod := &pods[i]
+		if pod.Spec.NodeName != "" && pod.Status.Phase != v1.PodFailed {
+			res.bound++
+		}
+		if isPodReady(pod) {
+			res.ready++
+		}
+		switch pod.Status.Phase {
+		case v1.PodRunning:
+			res.running++
+		case v1.PodSucceeded:
+			res.succeeded++
+		case v1.PodFailed:
+			res.failed++
+		}
+	}
+	return res
+}
+
+// phase 根据成员Pod的状态计算PodGroup所处的阶段，绑定节点的Pod达到minMember个即视为调度完成
+// 失败的Pod使剩余的成员不足minMember个时PodGroup失败，否则其余Pod全部成功时PodGroup成功
+func (s memberStats) phase() string {
+	switch {
+	case s.expected-s.failed < s.minMember:
+		return corev1.FailedPhase
+	case s.expected > 0 && s.succeeded+s.failed == s.expected:
+		return corev1.SucceededPhase
+	case s.bound < s.minMember:
+		return corev1.SchedulingPhase
+	case s.running > 0 && s.running+s.succeeded >= s.minMember:
+		return corev1.RunningPhase
+	default:
+		return corev1.ScheduledPhase
+	}
+}
+
+// isTerminalPhase PodGroup进入Succeeded或者Failed后不再变化
+func isTerminalPhase(phase string) bool {
+	return phase == corev1.SucceededPhase || phase == corev1.FailedPhase
+}
+
+func isPodReady(pod *v1.Pod) bool {
+	for _, c := range pod.Status.Conditions {
+		if c.Type == v1.PodReady {
+			return c.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// listMemberPods 返回由该PodGroup创建的Pod，以及由成员的工作负载创建的Pod
+func (r *PodGroupReconciler) listMemberPods(ctx context.Context, podGroup *corev1.PodGroup) ([]v1.Pod, error) {
+	podList := &v1.PodList{}
+	if err := r.List(ctx, podList, client.InNamespace(podGroup.Namespace)); err != nil {
+		return nil, err
+	}
+	res := make([]v1.Pod, 0, podGroup.Spec.MemberCount())
+	for _, pod := range podList.Items {
+		if model.IsMemberPod(podGroup, &pod) {
+			res = append(res, pod)
+		}
+	}
+	return res, nil
+}
+
+// syncStatus 重建缺失或者失败的成员Pod，并根据成员Pod的状态更新PodGroup的Phase以及AllPodsBound、AllPodsReady、GangScheduled三个Condition
+func (r *PodGroupReconciler) syncStatus(ctx context.Context, podGroup *corev1.PodGroup) (ctrl.Result, error) {
+	if isTerminalPhase(podGroup.Status.Phase) {
+		return ctrl.Result{}, nil
+	}
+
+	pods, err := r.listMemberPods(ctx, podGroup)
+	if err != nil {
+		klog.Errorf("Failed to list pods of PodGroup %s/%s, err: %v", podGroup.Namespace, podGroup.Name, err)
+		return ctrl.Result{}, err
+	}
+	// 超时仍未达到minMember时回滚整个PodGroup
+	if rolledBack, err := r.checkGangTimeout(ctx, podGroup, pods); rolledBack || err != nil {
+		return ctrl.Result{}, err
+	}
+	// 重建被删除或者失败的成员Pod，重建中的Pod不计入统计，PodGroup回到Scheduling阶段
+	oldStatus := podGroup.Status.DeepCopy()
+	if pods, err = r.repairMembers(ctx, podGroup, pods); err != nil {
+		klog.Errorf("Failed to repair pods of PodGroup %s/%s, err: %v", podGroup.Namespace, podGroup.Name, err)
+		return ctrl.Result{}, err
+	}
+	if err = r.syncServices(ctx, podGroup, pods); err != nil {
+		klog.Errorf("Failed to sync services of PodGroup %s/%s, err: %v", podGroup.Namespace, podGroup.Name, err)
+		return ctrl.Result{}, err
+	}
+	stats := newMemberStats(podGroup.Spec.MemberCount(), minMember(podGroup), pods)
+
+	newStatus := podGroup.Status.DeepCopy()
+	newStatus.Phase = stats.phase()
+	newStatus.ObservedGeneration = podGroup.Generation
+	setCountCondition(newStatus, corev1.AllPodsBoundCondition, stats.bound, stats.expected, reasonAllPodsBound, reasonPodsPending, "bound")
+	setCountCondition(newStatus, corev1.AllPodsReadyCondition, stats.ready, stats.expected, reasonAllPodsReady, reasonPodsNotReady, "ready")
+	setGangCondition(newStatus, stats.bound, stats.expected, stats.minMember)
+	updateScheduleResult(newStatus, pods)
+
+	result := ctrl.Result{}
+	if !meta.IsStatusConditionTrue(newStatus.Conditions, corev1.GangScheduledCondition) {
+		result.RequeueAfter = gangRequeueAfter(podGroup)
+	}
+	if equality.Semantic.DeepEqual(newStatus, oldStatus) {
+		return result, nil
+	}
+	if newStatus.Phase != oldStatus.Phase {
+		klog.Infof("PodGroup %s/%s phase changed: %s -> %s", podGroup.Namespace, podGroup.Name, oldStatus.Phase, newStatus.Phase)
+	}
+	podGroup.Status = *newStatus
+	if err := r.Status().Update(ctx, podGroup); err != nil {
+		klog.Errorf("Failed to update status for PodGroup %s/%s, err: %v", podGroup.Namespace, podGroup.Name, err)
+		return ctrl.Result{}, err
+	}
+	return result, nil
+}
+
+// updateScheduleResult 将成员Pod的实际绑定节点、phase与Ready状态写入ScheduleResult，并统计实际绑定节点与计划节点一致的Pod数量
+func updateScheduleResult(status *corev1.PodGroupStatus, pods []v1.Pod) {
+	podMap := make(map[string]*v1.Pod, len(pods))
+	for i := range pods {
+		podMap[model.MemberOf(&pods[i])] = &pods[i]
+	}
+	var matchedPods int32
+	for i := range status.ScheduleResult {
+		binding := &status.ScheduleResult[i]
+		binding.PodUID, binding.NodeName, binding.Matched, binding.PodPhase, binding.Ready = "", "", nil, "", false
+		pod, ok := podMap[binding.PodName]
+		if !ok {
+			continue
+		}
+		binding.PodUID = string(pod.UID)
+		binding.NodeName = pod.Spec.NodeName
+		binding.PodPhase = pod.Status.Phase
+		binding.Ready = isPodReady(pod)
+		if binding.PlannedNodeName != "" && binding.NodeName != "" {
+			matched := binding.PlannedNodeName == binding.NodeName
+			binding.Matched = &matched
+			if matched {
+				matchedPods++
+			}
+		}
+	}
+	if status.Placement != nil {
+		status.Placement.MatchedPods = matchedPods
+	}
+}
+
+// setCountCondition 当count达到expected时将Condition设置为True
+func setCountCondition(status *corev1.PodGroupStatus, conditionType string, count, expected int, trueReason, falseReason, what string) {
+	c := metav1.Condition{
+		Type:    conditionType,
+		Status:  metav1.ConditionFalse,
+		Reason:  falseReason,
+		Message: fmt.Sprintf("%d/%d pods %s", count, expected, what),
+	}
+	if count >= expected {
+		c.Status = metav1.ConditionTrue
+		c.Reason = trueReason
+	}
+	meta.SetStatusCondition(&status.Conditions, c)
+}
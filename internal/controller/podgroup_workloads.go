@@ -0,0 +1,162 @@
+package controller
+
+import (
+	"context"
+
+	corev1 "github.com/SMALL-head/podGroup/api/v1"
+	"github.com/SMALL-head/podGroup/internal/scheduling/model"
+	"github.com/SMALL-head/podGroup/internal/scheduling/planning"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// workloadPodToPodGroup 将工作负载创建的成员Pod的事件转换为其所属PodGroup的Reconcile请求
+// 由PodGroup直接创建的Pod通过Owns触发Reconcile，这里不再重复处理
+func workloadPodToPodGroup(_ context.Context, obj client.Object) []reconcile.Request {
+	name := obj.GetLabels()[model.PodGroupNameLabel]
+	if name == "" {
+		return nil
+	}
+	if ref := metav1.GetControllerOf(obj); ref == nil || ref.Kind == "PodGroup" {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: obj.GetNamespace(), Name: name}}}
+}
+
+// listMemberWorkloads 返回由该PodGroup创建的Deployment、StatefulSet与Job，key为工作负载名称，即成员名称
+// 工作负载创建时带有PodGroupNameLabel，只列出带有该标签的工作负载
+func (r *PodGroupReconciler) listMemberWorkloads(ctx context.Context, podGroup *corev1.PodGroup) (map[string][]client.Object, error) {
+	res := make(map[string][]client.Object)
+	opts := []client.ListOption{client.InNamespace(podGroup.Namespace), client.MatchingLabels{model.PodGroupNameLabel: podGroup.Name}}
+	deployments := &appsv1.DeploymentList{}
+	if err := r.List(ctx, deployments, opts...); err != nil {
+		return nil, err
+	}
+	for i := range deployments.Items {
+		res[deployments.Items[i].Name] = append(res[deployments.Items[i].Name], &deployments.Items[i])
+	}
+	statefulSets := &appsv1.StatefulSetList{}
+	if err := r.List(ctx, statefulSets, opts...); err != nil {
+		return nil, err
+	}
+	for i := range statefulSets.Items {
+		res[statefulSets.Items[i].Name] = append(res[statefulSets.Items[i].Name], &statefulSets.Items[i])
+	}
+	jobs := &batchv1.JobList{}
+	if err := r.List(ctx, jobs, opts...); err != nil {
+		return nil, err
+	}
+	for i := range jobs.Items {
+		res[jobs.Items[i].Name] = append(res[jobs.Items[i].Name], &jobs.Items[i])
+	}
+	for name, objs := range res {
+		owned := objs[:0]
+		for _, obj := range objs {
+			if metav1.IsControlledBy(obj, podGroup) {
+				owned = append(owned, obj)
+			}
+		}
+		if len(owned) == 0 {
+			delete(res, name)
+		} else {
+			res[name] = owned
+		}
+	}
+	return res, nil
+}
+
+// createMember 创建成员，template设置了工作负载时将pod包装为工作负载后创建，否则直接创建pod
+func (r *PodGroupReconciler) createMember(ctx context.Context, podGroup *corev1.PodGroup, template *corev1.PodTemplate, pod *v1.Pod) error {
+	if !template.IsWorkload() {
+		return r.createMemberPod(ctx, podGroup, pod)
+	}
+	obj, err := model.PodToWorkload(template.Workload, *pod, model.TemplateHash(*template))
+	if err != nil {
+		return err
+	}
+	klog.Infof("Creating %s %s/%s for member %s", template.Workload, pod.Namespace, pod.Name, pod.Name)
+	return r.createControlled(ctx, podGroup, obj, string(template.Workload))
+}
+
+// updateMemberWorkload 成员模板发生变化时使用计划节点重新渲染工作负载的Pod模板，由工作负载完成滚动更新
+// Job的Pod模板不可修改，webhook拒绝修改Job成员的模板
+func (r *PodGroupReconciler) updateMemberWorkload(ctx context.Context, podGroup *corev1.PodGroup, pRes *model.PodGroupParseResult, template *corev1.PodTemplate, obj client.Object) error {
+	hash := model.TemplateHash(*template)
+	if obj.GetAnnotations()[model.TemplateHashAnnotation] == hash || template.Workload == corev1.JobWorkload {
+		return nil
+	}
+	klog.Infof("Template of member %s changed, updating %s %s/%s", template.Metadata.Name, template.Workload, obj.GetNamespace(), obj.GetName())
+	// preferred模式下重新获取节点延迟，使Pod模板同样偏好与计划节点延迟最低的节点
+	problem := r.preferenceProblem(ctx, podGroup, pRes, nil)
+	return r.rolloutMemberWorkload(ctx, podGroup, pRes, problem, template, obj)
+}
+
+// rolloutMemberWorkload 使用status中记录的计划节点重新渲染工作负载的Pod模板，由工作负载完成滚动更新
+// problem用于preferred模式下选择偏好节点，与newMemberPod相同
+func (r *PodGroupReconciler) rolloutMemberWorkload(ctx context.Context, podGroup *corev1.PodGroup, pRes *model.PodGroupParseResult, problem *planning.Problem, template *corev1.PodTemplate, obj client.Object) error {
+	hash := model.TemplateHash(*template)
+	node, _ := plannedNode(&podGroup.Status, template.Metadata.Name)
+	pod, err := r.newMemberPod(podGroup, pRes, *template, node, problem)
+	if err != nil {
+		return err
+	}
+	desired, err := model.PodToWorkload(template.Workload, pod, hash)
+	if err != nil {
+		return err
+	}
+	*model.WorkloadPodTemplate(obj) = *model.WorkloadPodTemplate(desired)
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string, 1)
+	}
+	annotations[model.TemplateHashAnnotation] = hash
+	obj.SetAnnotations(annotations)
+	if err := r.Update(ctx, obj); err != nil {
+		klog.Errorf("Failed to update %s %s/%s, err: %v", template.Workload, obj.GetNamespace(), obj.GetName(), err)
+		return err
+	}
+	return nil
+}
+
+// deleteWorkload 删除工作负载，由工作负载创建的Pod在后台级联删除
+func (r *PodGroupReconciler) deleteWorkload(ctx context.Context, obj client.Object) error {
+	uid := obj.GetUID()
+	err := r.Delete(ctx, obj, client.Preconditions{UID: &uid}, client.PropagationPolicy(metav1.DeletePropagationBackground))
+	if client.IgnoreNotFound(err) != nil {
+		klog.Errorf("Failed to delete %s %s/%s, err: %v", model.WorkloadKindOf(obj), obj.GetNamespace(), obj.GetName(), err)
+		return err
+	}
+	return nil
+}
+
+// workloadMemberPod 从成员的工作负载创建的Pod中选择一个代表该成员状态的Pod，没有可用的Pod时返回nil
+// 忽略正在删除的Pod以及失败的Pod，失败后的重试由工作负载负责；滚动更新期间优先选择Ready的Pod，其次是已经绑定节点的Pod，其次是最新创建的Pod
+func workloadMemberPod(pods []*v1.Pod) *v1.Pod {
+	var best *v1.Pod
+	rank := func(pod *v1.Pod) int {
+		res := 0
+		if isPodReady(pod) {
+			res += 2
+		}
+		if pod.Spec.NodeName != "" {
+			res++
+		}
+		return res
+	}
+	for _, pod := range pods {
+		if !pod.DeletionTimestamp.IsZero() || pod.Status.Phase == v1.PodFailed {
+			continue
+		}
+		if best == nil || rank(pod) > rank(best) ||
+			rank(pod) == rank(best) && best.CreationTimestamp.Before(&pod.CreationTimestamp) {
+			best = pod
+		}
+	}
+	return best
+}
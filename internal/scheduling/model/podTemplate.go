@@ -1,62 +1,201 @@
 package model
 
 import (
+	"maps"
+
 	podGroupv1 "github.com/SMALL-head/podGroup/api/v1"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
+const (
+	// PodGroupSchedulerName PodTemplate未指定schedulerName时使用的调度器
+	PodGroupSchedulerName = "podGroup-scheduler"
+	// HostnameLabel placement绑定节点时使用的节点标签
+	HostnameLabel = "kubernetes.io/hostname"
+	// MaxPreferenceWeight preferredDuringScheduling节点亲和性与Pod亲和性的最大权重
+	MaxPreferenceWeight int32 = 100
+	// PodGroupNameLabel controller为成员Pod设置的标签，值为PodGroup名称
+	PodGroupNameLabel = "core.cic.io/podgroup"
+	// PodGroupMemberLabel controller为成员Pod设置的标签，值为Pod在PodGroup中的名称
+	PodGroupMemberLabel = "core.cic.io/member"
+	// PodGroupRoleLabel controller为成员Pod设置的标签，值为成员Pod所属的模板名称
+	PodGroupRoleLabel = "core.cic.io/role"
+)
+
+// PodPreference 带权重的依赖Pod偏好，PodName为同一PodGroup中的成员Pod名称
+type PodPreference struct {
+	PodName string
+	Weight  int32
+}
+
+// NodePreference 带权重的节点偏好
+type NodePreference struct {
+	NodeName string
+	Weight   int32
+}
+
+// RankNodePreferences 为按照偏好程度从高到低排列的节点分配权重
+// 第一个节点（计划节点）的权重为MaxPreferenceWeight，之后的节点权重线性递减，最小为1
+func RankNodePreferences(nodes []string) []NodePreference {
+	res := make([]NodePreference, 0, len(nodes))
+	for i, node := range nodes {
+		weight := MaxPreferenceWeight * int32(len(nodes)-i) / int32(len(nodes))
+		res = append(res, NodePreference{NodeName: node, Weight: max(weight, 1)})
+	}
+	return res
+}
+
+// PodTemplate2PodSpec 根据PodTemplate创建Pod，保留PodTemplate中的全部PodSpec与元数据，并将placement得到的节点合并进已有的节点亲和性中
 func PodTemplate2PodSpec(template podGroupv1.PodTemplate, podgroupMetadata metav1.ObjectMeta, affinityNode string, ownerRefGVK schema.GroupVersionKind) v1.Pod {
-	pod := v1.Pod{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      template.Metadata.Name,
-			Namespace: podgroupMetadata.Namespace,
-			Labels:    template.Metadata.Labels,
-			OwnerReferences: []metav1.OwnerReference{
-				*metav1.NewControllerRef(&podgroupMetadata, ownerRefGVK),
-			},
-		},
-		Spec: v1.PodSpec{
-			Containers:    template.Spec.Containers,
-			SchedulerName: "podGroup-scheduler",
-			Affinity: &v1.Affinity{
-				NodeAffinity: &v1.NodeAffinity{
-					RequiredDuringSchedulingIgnoredDuringExecution: &v1.NodeSelector{
-						NodeSelectorTerms: []v1.NodeSelectorTerm{
-							{
-								MatchExpressions: []v1.NodeSelectorRequirement{
-									{
-										Key:      "kubernetes.io/hostname",
-										Operator: v1.NodeSelectorOpIn,
-										Values:   []string{affinityNode},
-									},
-								},
-							},
-						},
-					},
-				},
-			},
-		},
+	pod := newPodFromTemplate(template, podgroupMetadata, ownerRefGVK)
+	if pod.Spec.SchedulerName == "" {
+		pod.Spec.SchedulerName = PodGroupSchedulerName
+	}
+	pod.Spec.Affinity = MergeNodeAffinity(pod.Spec.Affinity, affinityNode)
+	return pod
+}
+
+// PodTemplate2PodSpecWithPreferences 根据PodTemplate创建Pod，并将节点偏好以preferredDuringScheduling节点亲和性的形式合并进已有的亲和性中
+// preferences为空时不修改Pod的亲和性
+func PodTemplate2PodSpecWithPreferences(template podGroupv1.PodTemplate, podgroupMetadata metav1.ObjectMeta, preferences []NodePreference, ownerRefGVK schema.GroupVersionKind) v1.Pod {
+	pod := newPodFromTemplate(template, podgroupMetadata, ownerRefGVK)
+	if pod.Spec.SchedulerName == "" {
+		pod.Spec.SchedulerName = PodGroupSchedulerName
 	}
+	pod.Spec.Affinity = MergePreferredNodeAffinity(pod.Spec.Affinity, preferences)
+	return pod
+}
+
+// PodTemplate2PodSpecWithPodAffinity 根据PodTemplate创建Pod，设置成员标签，并将依赖Pod偏好以preferredDuringScheduling
+// Pod亲和性的形式合并进已有的亲和性中，使Pod优先调度到依赖Pod所在的topologyKey拓扑域，不修改schedulerName
+func PodTemplate2PodSpecWithPodAffinity(template podGroupv1.PodTemplate, podgroupMetadata metav1.ObjectMeta, preferences []PodPreference, topologyKey string, ownerRefGVK schema.GroupVersionKind) v1.Pod {
+	pod := newPodFromTemplate(template, podgroupMetadata, ownerRefGVK)
+	SetMemberLabels(&pod, podgroupMetadata.Name, template.Metadata.Name, "")
+	pod.Spec.Affinity = MergePodAffinity(pod.Spec.Affinity, podgroupMetadata.Name, preferences, topologyKey)
 	return pod
 }
 
 // createPodWithoutAffinity 创建不设置节点亲和性的Pod
 func CreatePodWithoutAffinity(template podGroupv1.PodTemplate, metadata metav1.ObjectMeta, gvk schema.GroupVersionKind) v1.Pod {
-	pod := v1.Pod{
+	// 不额外设置Affinity，让k8s默认调度器决定Pod调度，用户自行设置的亲和性保持不变
+	return newPodFromTemplate(template, metadata, gvk)
+}
+
+// SetMemberLabels 为成员Pod设置PodGroupNameLabel、PodGroupMemberLabel与PodGroupRoleLabel三个标签，role为空时不设置PodGroupRoleLabel
+func SetMemberLabels(pod *v1.Pod, podGroupName, member, role string) {
+	if pod.Labels == nil {
+		pod.Labels = make(map[string]string, 3)
+	}
+	pod.Labels[PodGroupNameLabel] = podGroupName
+	pod.Labels[PodGroupMemberLabel] = member
+	if role != "" {
+		pod.Labels[PodGroupRoleLabel] = role
+	}
+}
+
+// newPodFromTemplate 深拷贝PodTemplate中的元数据与PodSpec，并设置指向PodGroup的OwnerReference
+func newPodFromTemplate(template podGroupv1.PodTemplate, podgroupMetadata metav1.ObjectMeta, ownerRefGVK schema.GroupVersionKind) v1.Pod {
+	return v1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      template.Metadata.Name,
-			Namespace: metadata.Namespace,
-			Labels:    template.Metadata.Labels,
+			Name:        template.Metadata.Name,
+			Namespace:   podgroupMetadata.Namespace,
+			Labels:      maps.Clone(template.Metadata.Labels),
+			Annotations: maps.Clone(template.Metadata.Annotations),
 			OwnerReferences: []metav1.OwnerReference{
-				*metav1.NewControllerRef(&metadata, gvk),
+				*metav1.NewControllerRef(&podgroupMetadata, ownerRefGVK),
 			},
 		},
-		Spec: v1.PodSpec{
-			Containers: template.Spec.Containers,
-			// 不设置Affinity，让k8s默认调度器决定Pod调度
-		},
+		Spec: *template.Spec.DeepCopy(),
 	}
-	return pod
+}
+
+// MergeNodeAffinity 将绑定到affinityNode的节点亲和性合并进已有的Affinity，返回新的Affinity，不修改入参
+// NodeSelectorTerms之间是或的关系，因此hostname的约束需要追加到每一个已有的term中
+func MergeNodeAffinity(affinity *v1.Affinity, affinityNode string) *v1.Affinity {
+	hostnameReq := v1.NodeSelectorRequirement{
+		Key:      HostnameLabel,
+		Operator: v1.NodeSelectorOpIn,
+		Values:   []string{affinityNode},
+	}
+
+	res := affinity.DeepCopy()
+	if res == nil {
+		res = &v1.Affinity{}
+	}
+	if res.NodeAffinity == nil {
+		res.NodeAffinity = &v1.NodeAffinity{}
+	}
+	required := res.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+	if required == nil || len(required.NodeSelectorTerms) == 0 {
+		res.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution = &v1.NodeSelector{
+			NodeSelectorTerms: []v1.NodeSelectorTerm{
+				{MatchExpressions: []v1.NodeSelectorRequirement{hostnameReq}},
+			},
+		}
+		return res
+	}
+	for i := range required.NodeSelectorTerms {
+		term := &required.NodeSelectorTerms[i]
+		term.MatchExpressions = append(term.MatchExpressions, hostnameReq)
+	}
+	return res
+}
+
+// MergePreferredNodeAffinity 将节点偏好追加到已有Affinity的preferredDuringScheduling节点亲和性中，返回新的Affinity，不修改入参
+func MergePreferredNodeAffinity(affinity *v1.Affinity, preferences []NodePreference) *v1.Affinity {
+	res := affinity.DeepCopy()
+	if len(preferences) == 0 {
+		return res
+	}
+	if res == nil {
+		res = &v1.Affinity{}
+	}
+	if res.NodeAffinity == nil {
+		res.NodeAffinity = &v1.NodeAffinity{}
+	}
+	for _, pref := range preferences {
+		res.NodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution = append(res.NodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution, v1.PreferredSchedulingTerm{
+			Weight: pref.Weight,
+			Preference: v1.NodeSelectorTerm{
+				MatchExpressions: []v1.NodeSelectorRequirement{{
+					Key:      HostnameLabel,
+					Operator: v1.NodeSelectorOpIn,
+					Values:   []string{pref.NodeName},
+				}},
+			},
+		})
+	}
+	return res
+}
+
+// MergePodAffinity 将依赖Pod偏好追加到已有Affinity的preferredDuringScheduling Pod亲和性中，返回新的Affinity，不修改入参
+// 依赖Pod通过PodGroupNameLabel与PodGroupMemberLabel两个标签选择
+func MergePodAffinity(affinity *v1.Affinity, podGroupName string, preferences []PodPreference, topologyKey string) *v1.Affinity {
+	res := affinity.DeepCopy()
+	if len(preferences) == 0 {
+		return res
+	}
+	if res == nil {
+		res = &v1.Affinity{}
+	}
+	if res.PodAffinity == nil {
+		res.PodAffinity = &v1.PodAffinity{}
+	}
+	for _, pref := range preferences {
+		res.PodAffinity.PreferredDuringSchedulingIgnoredDuringExecution = append(res.PodAffinity.PreferredDuringSchedulingIgnoredDuringExecution, v1.WeightedPodAffinityTerm{
+			Weight: pref.Weight,
+			PodAffinityTerm: v1.PodAffinityTerm{
+				LabelSelector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{
+						PodGroupNameLabel:   podGroupName,
+						PodGroupMemberLabel: pref.PodName,
+					},
+				},
+				TopologyKey: topologyKey,
+			},
+		})
+	}
+	return res
 }
@@ -0,0 +1,154 @@
+package model
+
+import (
+	"testing"
+
+	podGroupv1 "github.com/SMALL-head/podGroup/api/v1"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestPodTemplate2PodSpec(t *testing.T) {
+	template := podGroupv1.PodTemplate{
+		Metadata: podGroupv1.PodMetadata{
+			Name:        "pod1",
+			Labels:      map[string]string{"app": "demo"},
+			Annotations: map[string]string{"note": "keep"},
+		},
+		Spec: v1.PodSpec{
+			InitContainers:     []v1.Container{{Name: "init", Image: "busybox"}},
+			Containers:         []v1.Container{{Name: "nginx", Image: "nginx"}},
+			Volumes:            []v1.Volume{{Name: "data"}},
+			ServiceAccountName: "demo-sa",
+			PriorityClassName:  "high",
+			Tolerations:        []v1.Toleration{{Key: "dedicated", Operator: v1.TolerationOpExists}},
+			Affinity: &v1.Affinity{
+				NodeAffinity: &v1.NodeAffinity{
+					RequiredDuringSchedulingIgnoredDuringExecution: &v1.NodeSelector{
+						NodeSelectorTerms: []v1.NodeSelectorTerm{
+							{MatchExpressions: []v1.NodeSelectorRequirement{{Key: "zone", Operator: v1.NodeSelectorOpIn, Values: []string{"a"}}}},
+							{MatchExpressions: []v1.NodeSelectorRequirement{{Key: "zone", Operator: v1.NodeSelectorOpIn, Values: []string{"b"}}}},
+						},
+					},
+				},
+				PodAntiAffinity: &v1.PodAntiAffinity{},
+			},
+		},
+	}
+	owner := metav1.ObjectMeta{Name: "pg", Namespace: "default", UID: "uid"}
+	gvk := schema.GroupVersionKind{Group: "core.cic.io", Version: "v1", Kind: "PodGroup"}
+
+	pod := PodTemplate2PodSpec(template, owner, "node1", gvk)
+
+	require.Equal(t, "pod1", pod.Name)
+	require.Equal(t, "default", pod.Namespace)
+	require.Equal(t, "keep", pod.Annotations["note"])
+	require.Equal(t, "demo", pod.Labels["app"])
+	require.Len(t, pod.OwnerReferences, 1)
+	require.Equal(t, PodGroupSchedulerName, pod.Spec.SchedulerName)
+	require.Equal(t, template.Spec.InitContainers, pod.Spec.InitContainers)
+	require.Equal(t, template.Spec.Volumes, pod.Spec.Volumes)
+	require.Equal(t, "demo-sa", pod.Spec.ServiceAccountName)
+	require.Equal(t, "high", pod.Spec.PriorityClassName)
+	require.Equal(t, template.Spec.Tolerations, pod.Spec.Tolerations)
+	require.NotNil(t, pod.Spec.Affinity.PodAntiAffinity)
+
+	// hostname约束被追加到每一个已有的term中
+	terms := pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	require.Len(t, terms, 2)
+	for _, term := range terms {
+		require.Len(t, term.MatchExpressions, 2)
+		require.Equal(t, HostnameLabel, term.MatchExpressions[1].Key)
+		require.Equal(t, []string{"node1"}, term.MatchExpressions[1].Values)
+	}
+
+	// 模板本身不被修改
+	require.Len(t, template.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms[0].MatchExpressions, 1)
+	pod.Labels["app"] = "changed"
+	require.Equal(t, "demo", template.Metadata.Labels["app"])
+
+	plain := CreatePodWithoutAffinity(template, owner, gvk)
+	require.Empty(t, plain.Spec.SchedulerName)
+	require.Len(t, plain.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms[0].MatchExpressions, 1)
+}
+
+func TestPodTemplate2PodSpecWithPreferences(t *testing.T) {
+	template := podGroupv1.PodTemplate{
+		Metadata: podGroupv1.PodMetadata{Name: "pod1"},
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{{Name: "nginx", Image: "nginx"}},
+			Affinity: &v1.Affinity{
+				NodeAffinity: &v1.NodeAffinity{
+					PreferredDuringSchedulingIgnoredDuringExecution: []v1.PreferredSchedulingTerm{
+						{Weight: 10, Preference: v1.NodeSelectorTerm{MatchExpressions: []v1.NodeSelectorRequirement{{Key: "zone", Operator: v1.NodeSelectorOpIn, Values: []string{"a"}}}}},
+					},
+				},
+			},
+		},
+	}
+	owner := metav1.ObjectMeta{Name: "pg", Namespace: "default", UID: "uid"}
+	gvk := schema.GroupVersionKind{Group: "core.cic.io", Version: "v1", Kind: "PodGroup"}
+
+	prefs := RankNodePreferences([]string{"node1", "node2", "node3", "node4"})
+	require.Equal(t, []NodePreference{{"node1", 100}, {"node2", 75}, {"node3", 50}, {"node4", 25}}, prefs)
+
+	pod := PodTemplate2PodSpecWithPreferences(template, owner, prefs, gvk)
+	require.Equal(t, PodGroupSchedulerName, pod.Spec.SchedulerName)
+	require.Nil(t, pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution)
+	terms := pod.Spec.Affinity.NodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution
+	require.Len(t, terms, 5)
+	require.Equal(t, int32(10), terms[0].Weight)
+	for i, pref := range prefs {
+		require.Equal(t, pref.Weight, terms[i+1].Weight)
+		require.Equal(t, HostnameLabel, terms[i+1].Preference.MatchExpressions[0].Key)
+		require.Equal(t, []string{pref.NodeName}, terms[i+1].Preference.MatchExpressions[0].Values)
+	}
+	// 模板本身不被修改
+	require.Len(t, template.Spec.Affinity.NodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution, 1)
+
+	// 没有节点偏好时不设置亲和性
+	template.Spec.Affinity = nil
+	pod = PodTemplate2PodSpecWithPreferences(template, owner, nil, gvk)
+	require.Equal(t, PodGroupSchedulerName, pod.Spec.SchedulerName)
+	require.Nil(t, pod.Spec.Affinity)
+
+	// 节点很多时权重最小为1
+	many := make([]string, 200)
+	for i := range many {
+		many[i] = "node"
+	}
+	prefs = RankNodePreferences(many)
+	require.Equal(t, int32(1), prefs[len(prefs)-1].Weight)
+}
+
+func TestPodTemplate2PodSpecWithPodAffinity(t *testing.T) {
+	template := podGroupv1.PodTemplate{
+		Metadata: podGroupv1.PodMetadata{Name: "pod1", Labels: map[string]string{"app": "demo"}},
+		Spec: v1.PodSpec{
+			SchedulerName: "default-scheduler",
+			Containers:    []v1.Container{{Name: "nginx", Image: "nginx"}},
+			Affinity:      &v1.Affinity{PodAffinity: &v1.PodAffinity{}},
+		},
+	}
+	owner := metav1.ObjectMeta{Name: "pg", Namespace: "default", UID: "uid"}
+	gvk := schema.GroupVersionKind{Group: "core.cic.io", Version: "v1", Kind: "PodGroup"}
+	prefs := []PodPreference{{PodName: "pod2", Weight: 100}, {PodName: "pod3", Weight: 40}}
+
+	pod := PodTemplate2PodSpecWithPodAffinity(template, owner, prefs, HostnameLabel, gvk)
+	require.Equal(t, "default-scheduler", pod.Spec.SchedulerName)
+	require.Equal(t, map[string]string{"app": "demo", PodGroupNameLabel: "pg", PodGroupMemberLabel: "pod1"}, pod.Labels)
+	require.Nil(t, pod.Spec.Affinity.NodeAffinity)
+	terms := pod.Spec.Affinity.PodAffinity.PreferredDuringSchedulingIgnoredDuringExecution
+	require.Len(t, terms, 2)
+	for i, pref := range prefs {
+		require.Equal(t, pref.Weight, terms[i].Weight)
+		require.Equal(t, HostnameLabel, terms[i].PodAffinityTerm.TopologyKey)
+		require.Equal(t, map[string]string{PodGroupNameLabel: "pg", PodGroupMemberLabel: pref.PodName}, terms[i].PodAffinityTerm.LabelSelector.MatchLabels)
+	}
+
+	// 模板本身不被修改
+	require.Empty(t, template.Spec.Affinity.PodAffinity.PreferredDuringSchedulingIgnoredDuringExecution)
+	require.NotContains(t, template.Metadata.Labels, PodGroupMemberLabel)
+}
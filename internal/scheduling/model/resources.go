@@ -0,0 +1,101 @@
+package model
+
+import (
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// 资源统一使用的单位：CPU为核数，内存为GiB
+const gib = 1 << 30
+
+func cpuCores(q resource.Quantity) float64 {
+	return q.AsApproximateFloat64()
+}
+
+func memGiB(q resource.Quantity) float64 {
+	return q.AsApproximateFloat64() / gib
+}
+
+// PodRequests 计算PodSpec的资源请求量，与kube-scheduler的计算方式一致：
+// max(所有容器请求之和, 任一init容器的请求) + Overhead
+func PodRequests(spec *v1.PodSpec) (cpu, mem float64) {
+	for _, c := range spec.Containers {
+		cpu += cpuCores(c.Resources.Requests[v1.ResourceCPU])
+		mem += memGiB(c.Resources.Requests[v1.ResourceMemory])
+	}
+	// sidecar类型的init容器会一直运行，需要累加到常驻请求中
+	var sidecarCPU, sidecarMem float64
+	var initCPU, initMem float64
+	for _, c := range spec.InitContainers {
+		c1 := cpuCores(c.Resources.Requests[v1.ResourceCPU])
+		m1 := memGiB(c.Resources.Requests[v1.ResourceMemory])
+		if c.RestartPolicy != nil && *c.RestartPolicy == v1.ContainerRestartPolicyAlways {
+			sidecarCPU += c1
+			sidecarMem += m1
+			continue
+		}
+		initCPU = max(initCPU, sidecarCPU+c1)
+		initMem = max(initMem, sidecarMem+m1)
+	}
+	cpu = max(cpu+sidecarCPU, initCPU)
+	mem = max(mem+sidecarMem, initMem)
+
+	cpu += cpuCores(spec.Overhead[v1.ResourceCPU])
+	mem += memGiB(spec.Overhead[v1.ResourceMemory])
+	return
+}
+
+// NodeFromK8s 根据Node的allocatable以及已经绑定在该节点上的Pod计算节点的剩余容量
+// boundPods中已经结束(Succeeded/Failed)的Pod不占用资源
+func NodeFromK8s(node *v1.Node, boundPods []v1.Pod) Node {
+	res := Node{
+		NodeName: node.Name,
+		CPUCap:   cpuCores(node.Status.Allocatable[v1.ResourceCPU]),
+		MemCap:   memGiB(node.Status.Allocatable[v1.ResourceMemory]),
+	}
+	for i := range boundPods {
+		p := &boundPods[i]
+		if p.Spec.NodeName != node.Name || p.Status.Phase == v1.PodSucceeded || p.Status.Phase == v1.PodFailed {
+			continue
+		}
+		cpu, mem := PodRequests(&p.Spec)
+		res.CPUCap -= cpu
+		res.MemCap -= mem
+	}
+	// 节点已经超卖时，剩余容量记为一个极小的正数，避免被视为容量未知
+	if _, ok := node.Status.Allocatable[v1.ResourceCPU]; ok {
+		res.CPUCap = max(res.CPUCap, minCapacity)
+	}
+	if _, ok := node.Status.Allocatable[v1.ResourceMemory]; ok {
+		res.MemCap = max(res.MemCap, minCapacity)
+	}
+	return res
+}
+
+// minCapacity 已经耗尽的节点容量，任何有资源请求的Pod都无法放入
+const minCapacity = 1e-9
+
+// Fits 判断节点的剩余容量能否放下给定的资源请求，容量未知(<=0)的维度视为不受限
+func (n *Node) Fits(cpu, mem float64) bool {
+	return (n.CPUCap <= 0 || cpu <= n.CPUCap) && (n.MemCap <= 0 || mem <= n.MemCap)
+}
+
+// Consume 从节点的剩余容量中扣除资源请求，容量未知的维度保持不变
+func (n *Node) Consume(cpu, mem float64) {
+	if n.CPUCap > 0 {
+		n.CPUCap = max(n.CPUCap-cpu, minCapacity)
+	}
+	if n.MemCap > 0 {
+		n.MemCap = max(n.MemCap-mem, minCapacity)
+	}
+}
+
+// Release 将资源请求归还到节点的剩余容量中，容量未知的维度保持不变
+func (n *Node) Release(cpu, mem float64) {
+	if n.CPUCap > 0 {
+		n.CPUCap += cpu
+	}
+	if n.MemCap > 0 {
+		n.MemCap += mem
+	}
+}
@@ -0,0 +1,69 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func requests(cpu, mem string) v1.ResourceRequirements {
+	return v1.ResourceRequirements{Requests: v1.ResourceList{
+		v1.ResourceCPU:    resource.MustParse(cpu),
+		v1.ResourceMemory: resource.MustParse(mem),
+	}}
+}
+
+func TestPodRequests(t *testing.T) {
+	always := v1.ContainerRestartPolicyAlways
+	spec := &v1.PodSpec{
+		Containers: []v1.Container{
+			{Name: "a", Resources: requests("500m", "1Gi")},
+			{Name: "b", Resources: requests("1", "1Gi")},
+		},
+		InitContainers: []v1.Container{
+			{Name: "init", Resources: requests("2", "512Mi")},
+			{Name: "sidecar", Resources: requests("250m", "512Mi"), RestartPolicy: &always},
+		},
+		Overhead: v1.ResourceList{v1.ResourceCPU: resource.MustParse("100m")},
+	}
+
+	cpu, mem := PodRequests(spec)
+	// cpu: max(1.5 + 0.25, 2) + 0.1
+	require.InDelta(t, 2.1, cpu, 1e-9)
+	// mem: max(2 + 0.5, 0.5) GiB
+	require.InDelta(t, 2.5, mem, 1e-9)
+}
+
+func TestNodeFromK8s(t *testing.T) {
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node1"},
+		Status: v1.NodeStatus{Allocatable: v1.ResourceList{
+			v1.ResourceCPU:    resource.MustParse("4"),
+			v1.ResourceMemory: resource.MustParse("8Gi"),
+		}},
+	}
+	pods := []v1.Pod{
+		{Spec: v1.PodSpec{NodeName: "node1", Containers: []v1.Container{{Resources: requests("1", "2Gi")}}}},
+		{
+			Spec:   v1.PodSpec{NodeName: "node1", Containers: []v1.Container{{Resources: requests("2", "2Gi")}}},
+			Status: v1.PodStatus{Phase: v1.PodSucceeded},
+		},
+		{Spec: v1.PodSpec{NodeName: "node2", Containers: []v1.Container{{Resources: requests("2", "2Gi")}}}},
+	}
+
+	n := NodeFromK8s(node, pods)
+	require.InDelta(t, 3.0, n.CPUCap, 1e-9)
+	require.InDelta(t, 6.0, n.MemCap, 1e-9)
+	require.True(t, n.Fits(3, 6))
+	require.False(t, n.Fits(3.5, 1))
+
+	n.Consume(3, 6)
+	require.False(t, n.Fits(0.1, 0))
+	require.True(t, n.Fits(0, 0))
+
+	n.Release(3, 6)
+	require.True(t, n.Fits(3, 6))
+}
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"slices"
 	"strings"
 
 	podGroupv1 "github.com/SMALL-head/podGroup/api/v1"
@@ -55,9 +56,13 @@ type PodGroupParseResult struct {
 	PodDependencies   PodDependencies
 	PodNameList       []string // 顺序与PodDependencies矩阵的行列顺序一致
 	NodeBalanceFactor int
+	// AntiAffinity 互斥的Pod名称分组，对应spec.antiAffinity
+	AntiAffinity [][]string
+	// MaxPodsPerNode 每个节点上最多分配的Pod数量，0表示不限制
+	MaxPodsPerNode int
 }
 
-// PodDependencies 表示了Pod之间的通信关系
+// PodDependencies 表示了Pod之间的通信关系，[i][j]为Pod i发往Pod j的通信权重
 type PodDependencies = Matrix
 
 // NodeLatencies 表示Node之间的通信延迟
@@ -88,3 +93,131 @@ func PrometheusMatrix2NodeLatencies(matrix model.Matrix) NodeTotalLatencies {
 	}
 	return res
 }
+
+// LabelledNodeLatencies 带节点名称的两两节点延迟矩阵
+// Latencies[i][j]表示从NodeNameList[i]发往NodeNameList[j]的延迟，两个方向的延迟可以不相等
+type LabelledNodeLatencies struct {
+	// NodeNameList 顺序与Latencies矩阵的行列顺序一致
+	NodeNameList []string
+	NodeIndex    map[string]int
+	Latencies    NodeLatencies
+	// MissingPairs 没有任何方向采样数据、由默认值填充的有序节点对数量
+	MissingPairs int
+
+	missing [][]bool
+}
+
+// PrometheusMatrix2LatencyMatrix 将node_network_latency_ms{src,dst}的采样数据转换为两两节点的延迟矩阵，每个方向取时间窗口内的平均值
+// 若某个方向没有采样数据，则使用反方向的平均延迟；若两个方向都没有数据，则使用观测到的最大延迟作为保守估计
+func PrometheusMatrix2LatencyMatrix(matrix model.Matrix) *LabelledNodeLatencies {
+	type pair struct{ src, dst string }
+	sum := make(map[pair]float64)
+	cnt := make(map[pair]int)
+	nodeSet := make(map[string]struct{})
+	for _, sample := range matrix {
+		src := string(sample.Metric["src"])
+		dst := string(sample.Metric["dst"])
+		// 我们不计算control-plane节点的延迟，因为它通常不参与实际的工作负载通信
+		if strings.Contains(src, "control") || strings.Contains(dst, "control") {
+			continue
+		}
+		if src == "" || dst == "" {
+			continue
+		}
+		nodeSet[src] = struct{}{}
+		nodeSet[dst] = struct{}{}
+		if src == dst {
+			continue
+		}
+		for _, v := range sample.Values {
+			sum[pair{src, dst}] += float64(v.Value)
+		}
+		cnt[pair{src, dst}] += len(sample.Values)
+	}
+
+	nodeNameList := make([]string, 0, len(nodeSet))
+	for n := range nodeSet {
+		nodeNameList = append(nodeNameList, n)
+	}
+	slices.Sort(nodeNameList)
+
+	avg := func(p pair) (float64, bool) {
+		if cnt[p] == 0 {
+			return 0, false
+		}
+		return sum[p] / float64(cnt[p]), true
+	}
+	maxLatency := 0.0
+	for p := range cnt {
+		if v, ok := avg(p); ok {
+			maxLatency = max(maxLatency, v)
+		}
+	}
+
+	res := &LabelledNodeLatencies{
+		NodeNameList: nodeNameList,
+		NodeIndex:    make(map[string]int, len(nodeNameList)),
+		Latencies:    make(NodeLatencies, len(nodeNameList)),
+		missing:      make([][]bool, len(nodeNameList)),
+	}
+	for i, src := range nodeNameList {
+		res.NodeIndex[src] = i
+		res.Latencies[i] = make([]float64, len(nodeNameList))
+		res.missing[i] = make([]bool, len(nodeNameList))
+		for j, dst := range nodeNameList {
+			if i == j {
+				continue
+			}
+			if v, ok := avg(pair{src, dst}); ok {
+				res.Latencies[i][j] = v
+			} else if v, ok := avg(pair{dst, src}); ok {
+				res.Latencies[i][j] = v
+			} else {
+				res.Latencies[i][j] = maxLatency
+				res.missing[i][j] = true
+				res.MissingPairs++
+			}
+		}
+	}
+	return res
+}
+
+// Get 返回从src发往dst的延迟，节点不存在时返回false
+func (l *LabelledNodeLatencies) Get(src, dst string) (float64, bool) {
+	i, ok1 := l.NodeIndex[src]
+	j, ok2 := l.NodeIndex[dst]
+	if !ok1 || !ok2 {
+		return 0, false
+	}
+	return l.Latencies[i][j], true
+}
+
+// Subset 返回只包含给定节点的延迟矩阵，不存在于原矩阵中的节点会被忽略，结果的节点顺序与nodeNameList一致
+func (l *LabelledNodeLatencies) Subset(nodeNameList []string) *LabelledNodeLatencies {
+	names := make([]string, 0, len(nodeNameList))
+	for _, n := range nodeNameList {
+		if _, ok := l.NodeIndex[n]; ok {
+			names = append(names, n)
+		}
+	}
+	res := &LabelledNodeLatencies{
+		NodeNameList: names,
+		NodeIndex:    make(map[string]int, len(names)),
+		Latencies:    make(NodeLatencies, len(names)),
+		missing:      make([][]bool, len(names)),
+	}
+	for i, src := range names {
+		res.NodeIndex[src] = i
+		res.Latencies[i] = make([]float64, len(names))
+		res.missing[i] = make([]bool, len(names))
+		for j, dst := range names {
+			si, sj := l.NodeIndex[src], l.NodeIndex[dst]
+			res.Latencies[i][j] = l.Latencies[si][sj]
+			res.missing[i][j] = l.missing[si][sj]
+			if res.missing[i][j] {
+				res.MissingPairs++
+			}
+		}
+	}
+	return res
+}
@@ -0,0 +1,51 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+)
+
+func latencySample(src, dst string, values ...float64) *model.SampleStream {
+	s := &model.SampleStream{
+		Metric: model.Metric{"src": model.LabelValue(src), "dst": model.LabelValue(dst)},
+	}
+	for i, v := range values {
+		s.Values = append(s.Values, model.SamplePair{Timestamp: model.Time(i), Value: model.SampleValue(v)})
+	}
+	return s
+}
+
+func TestPrometheusMatrix2LatencyMatrix(t *testing.T) {
+	matrix := model.Matrix{
+		latencySample("node1", "node2", 10, 20),
+		latencySample("node2", "node1", 30),
+		latencySample("node1", "node3", 40),
+		latencySample("node3", "node4", 5),
+		latencySample("control-plane", "node1", 1000),
+	}
+
+	res := PrometheusMatrix2LatencyMatrix(matrix)
+	require.Equal(t, []string{"node1", "node2", "node3", "node4"}, res.NodeNameList)
+
+	get := func(src, dst string) float64 {
+		v, ok := res.Get(src, dst)
+		require.True(t, ok)
+		return v
+	}
+	// 非对称链路保留各自方向的平均值
+	require.Equal(t, 15.0, get("node1", "node2"))
+	require.Equal(t, 30.0, get("node2", "node1"))
+	// 缺少反方向数据时使用已有方向的延迟
+	require.Equal(t, 40.0, get("node3", "node1"))
+	// 两个方向都缺失时使用观测到的最大延迟
+	require.Equal(t, 40.0, get("node2", "node4"))
+	require.Equal(t, 40.0, get("node4", "node2"))
+	require.Equal(t, 0.0, get("node1", "node1"))
+	// node1-node4, node2-node3, node2-node4 三对节点的两个方向均缺失
+	require.Equal(t, 6, res.MissingPairs)
+
+	_, ok := res.Get("control-plane", "node1")
+	require.False(t, ok)
+}
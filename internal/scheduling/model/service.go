@@ -0,0 +1,68 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// ServicePorts 将PodSpec中各个容器声明的端口转换为ServicePort，相同端口与协议只保留一个
+// 端口未命名时使用<协议>-<端口>作为名称，例如tcp-8080
+func ServicePorts(spec *v1.PodSpec) []v1.ServicePort {
+	var res []v1.ServicePort
+	seen := make(map[string]bool)
+	for _, container := range spec.Containers {
+		for _, port := range container.Ports {
+			protocol := port.Protocol
+			if protocol == "" {
+				protocol = v1.ProtocolTCP
+			}
+			name := port.Name
+			if name == "" {
+				name = fmt.Sprintf("%s-%d", strings.ToLower(string(protocol)), port.ContainerPort)
+			}
+			key := fmt.Sprintf("%s/%d", protocol, port.ContainerPort)
+			if seen[key] || seen[name] {
+				continue
+			}
+			seen[key], seen[name] = true, true
+			res = append(res, v1.ServicePort{
+				Name:       name,
+				Protocol:   protocol,
+				Port:       port.ContainerPort,
+				TargetPort: intstr.FromInt32(port.ContainerPort),
+			})
+		}
+	}
+	return res
+}
+
+// NewMemberService 创建选择selector所匹配的成员Pod的Service，并设置指向PodGroup的OwnerReference
+// headless为true时不分配ClusterIP，并且未Ready的Pod同样发布DNS记录，使依赖方在成员Pod启动过程中即可解析其地址
+func NewMemberService(name string, selector map[string]string, ports []v1.ServicePort, headless bool, podgroupMetadata metav1.ObjectMeta, ownerRefGVK schema.GroupVersionKind) v1.Service {
+	svc := v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: podgroupMetadata.Namespace,
+			Labels: map[string]string{
+				PodGroupNameLabel: podgroupMetadata.Name,
+			},
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(&podgroupMetadata, ownerRefGVK),
+			},
+		},
+		Spec: v1.ServiceSpec{
+			Selector: selector,
+			Ports:    ports,
+		},
+	}
+	if headless {
+		svc.Spec.ClusterIP = v1.ClusterIPNone
+		svc.Spec.PublishNotReadyAddresses = true
+	}
+	return svc
+}
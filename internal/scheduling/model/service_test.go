@@ -0,0 +1,58 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestServicePorts(t *testing.T) {
+	spec := &v1.PodSpec{
+		InitContainers: []v1.Container{{Name: "init", Ports: []v1.ContainerPort{{ContainerPort: 9000}}}},
+		Containers: []v1.Container{
+			{Name: "app", Ports: []v1.ContainerPort{
+				{Name: "http", ContainerPort: 8080},
+				{ContainerPort: 53, Protocol: v1.ProtocolUDP},
+			}},
+			{Name: "sidecar", Ports: []v1.ContainerPort{
+				{ContainerPort: 8080},
+				{ContainerPort: 53},
+			}},
+		},
+	}
+
+	ports := ServicePorts(spec)
+	require.Equal(t, []v1.ServicePort{
+		{Name: "http", Protocol: v1.ProtocolTCP, Port: 8080, TargetPort: intstr.FromInt32(8080)},
+		{Name: "udp-53", Protocol: v1.ProtocolUDP, Port: 53, TargetPort: intstr.FromInt32(53)},
+		{Name: "tcp-53", Protocol: v1.ProtocolTCP, Port: 53, TargetPort: intstr.FromInt32(53)},
+	}, ports)
+
+	require.Empty(t, ServicePorts(&v1.PodSpec{Containers: []v1.Container{{Name: "app"}}}))
+}
+
+func TestNewMemberService(t *testing.T) {
+	owner := metav1.ObjectMeta{Name: "pg", Namespace: "default", UID: "uid"}
+	gvk := schema.GroupVersionKind{Group: "core.cic.io", Version: "v1", Kind: "PodGroup"}
+	selector := map[string]string{PodGroupNameLabel: "pg", PodGroupMemberLabel: "db-0"}
+	ports := []v1.ServicePort{{Name: "tcp-5432", Protocol: v1.ProtocolTCP, Port: 5432}}
+
+	svc := NewMemberService("db-0", selector, ports, true, owner, gvk)
+	require.Equal(t, "db-0", svc.Name)
+	require.Equal(t, "default", svc.Namespace)
+	require.Equal(t, "pg", svc.Labels[PodGroupNameLabel])
+	require.Len(t, svc.OwnerReferences, 1)
+	require.True(t, *svc.OwnerReferences[0].Controller)
+	require.Equal(t, selector, svc.Spec.Selector)
+	require.Equal(t, ports, svc.Spec.Ports)
+	require.Equal(t, v1.ClusterIPNone, svc.Spec.ClusterIP)
+	require.True(t, svc.Spec.PublishNotReadyAddresses)
+
+	svc = NewMemberService("db", selector, ports, false, owner, gvk)
+	require.Empty(t, svc.Spec.ClusterIP)
+	require.False(t, svc.Spec.PublishNotReadyAddresses)
+}
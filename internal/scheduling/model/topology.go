@@ -0,0 +1,92 @@
+package model
+
+import "slices"
+
+// WithTopology 根据节点所在的拓扑域补全延迟矩阵，返回只包含nodeNameList中节点的新矩阵，结果的节点顺序与nodeNameList一致
+// domains为节点名称到拓扑域的映射，没有拓扑域的节点单独作为一个拓扑域。nodeNameList中的节点可以不在原矩阵中
+//   - 有采样数据的节点对保持原延迟，aggregate为true时同样使用拓扑域之间的延迟，即在拓扑域粒度上求解placement
+//   - 没有采样数据的节点对使用两个拓扑域之间已观测延迟的中位数，同一拓扑域内没有观测时使用所有拓扑域的域内延迟中位数
+//   - 拓扑域之间也没有观测时使用观测到的最大延迟，此时计入MissingPairs
+func (l *LabelledNodeLatencies) WithTopology(nodeNameList []string, domains map[string]string, aggregate bool) *LabelledNodeLatencies {
+	domainOf := func(node string) string {
+		if d := domains[node]; d != "" {
+			return d
+		}
+		// 标签值中不会出现":"，不会与真实的拓扑域重名
+		return "node:" + node
+	}
+	type domainPair struct{ src, dst string }
+
+	// 1. 按照拓扑域对汇总有采样数据的节点对
+	observed := make(map[domainPair][]float64)
+	var intra []float64
+	maxLatency := 0.0
+	for i, src := range l.NodeNameList {
+		for j, dst := range l.NodeNameList {
+			if i == j || l.missing[i][j] {
+				continue
+			}
+			v := l.Latencies[i][j]
+			p := domainPair{domainOf(src), domainOf(dst)}
+			observed[p] = append(observed[p], v)
+			if p.src == p.dst {
+				intra = append(intra, v)
+			}
+			maxLatency = max(maxLatency, v)
+		}
+	}
+	domainLatency := func(p domainPair) (float64, bool) {
+		if vs := observed[p]; len(vs) > 0 {
+			return median(vs), true
+		}
+		if vs := observed[domainPair{p.dst, p.src}]; len(vs) > 0 {
+			return median(vs), true
+		}
+		if p.src == p.dst && len(intra) > 0 {
+			return median(intra), true
+		}
+		return maxLatency, false
+	}
+
+	// 2. 构造新的延迟矩阵
+	res := &LabelledNodeLatencies{
+		NodeNameList: slices.Clone(nodeNameList),
+		NodeIndex:    make(map[string]int, len(nodeNameList)),
+		Latencies:    make(NodeLatencies, len(nodeNameList)),
+		missing:      make([][]bool, len(nodeNameList)),
+	}
+	for i, src := range nodeNameList {
+		res.NodeIndex[src] = i
+		res.Latencies[i] = make([]float64, len(nodeNameList))
+		res.missing[i] = make([]bool, len(nodeNameList))
+		for j, dst := range nodeNameList {
+			if i == j {
+				continue
+			}
+			si, ok1 := l.NodeIndex[src]
+			sj, ok2 := l.NodeIndex[dst]
+			if ok1 && ok2 && !l.missing[si][sj] && !aggregate {
+				res.Latencies[i][j] = l.Latencies[si][sj]
+				continue
+			}
+			v, ok := domainLatency(domainPair{domainOf(src), domainOf(dst)})
+			res.Latencies[i][j] = v
+			if !ok {
+				res.missing[i][j] = true
+				res.MissingPairs++
+			}
+		}
+	}
+	return res
+}
+
+// median 返回中位数，不修改入参
+func median(values []float64) float64 {
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
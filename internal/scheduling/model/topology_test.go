@@ -0,0 +1,52 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithTopology(t *testing.T) {
+	matrix := model.Matrix{
+		latencySample("node1", "node2", 2),
+		latencySample("node1", "node3", 50),
+		latencySample("node2", "node4", 60),
+	}
+	// node5没有拓扑域，node6没有任何延迟数据
+	domains := map[string]string{
+		"node1": "zone-a", "node2": "zone-a",
+		"node3": "zone-b", "node4": "zone-b", "node6": "zone-b",
+	}
+	nodes := []string{"node1", "node2", "node3", "node4", "node5", "node6"}
+	latencies := PrometheusMatrix2LatencyMatrix(matrix)
+
+	res := latencies.WithTopology(nodes, domains, false)
+	require.Equal(t, nodes, res.NodeNameList)
+	get := func(src, dst string) float64 {
+		v, ok := res.Get(src, dst)
+		require.True(t, ok)
+		return v
+	}
+	// 有采样数据的节点对保持不变
+	require.Equal(t, 2.0, get("node1", "node2"))
+	require.Equal(t, 50.0, get("node1", "node3"))
+	// 没有采样数据时使用拓扑域之间延迟的中位数
+	require.Equal(t, 55.0, get("node1", "node4"))
+	require.Equal(t, 55.0, get("node6", "node2"))
+	// zone-b内部没有观测，使用所有拓扑域的域内延迟
+	require.Equal(t, 2.0, get("node3", "node4"))
+	require.Equal(t, 2.0, get("node6", "node3"))
+	// 没有拓扑域的节点使用观测到的最大延迟
+	require.Equal(t, 60.0, get("node5", "node1"))
+	require.Equal(t, 10, res.MissingPairs)
+
+	// 在拓扑域粒度上求解时所有节点对都使用拓扑域之间的延迟
+	res = latencies.WithTopology(nodes, domains, true)
+	require.Equal(t, 55.0, get("node1", "node3"))
+	require.Equal(t, 2.0, get("node1", "node2"))
+	require.Equal(t, 0.0, get("node1", "node1"))
+
+	// Subset保留缺失信息
+	require.Equal(t, 2, res.Subset([]string{"node1", "node5"}).MissingPairs)
+}
@@ -0,0 +1,128 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"maps"
+	"strconv"
+
+	podGroupv1 "github.com/SMALL-head/podGroup/api/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// TemplateHashAnnotation controller在工作负载上记录的成员模板哈希，模板变化时更新工作负载的Pod模板
+const TemplateHashAnnotation = "core.cic.io/template-hash"
+
+// TemplateHash 计算成员模板的哈希，用于判断工作负载的Pod模板是否需要更新
+func TemplateHash(template podGroupv1.PodTemplate) string {
+	data, _ := json.Marshal(template)
+	h := fnv.New32a()
+	_, _ = h.Write(data)
+	return strconv.FormatUint(uint64(h.Sum32()), 16)
+}
+
+// MemberOf 返回Pod在PodGroup中的成员名称，工作负载创建的Pod通过PodGroupMemberLabel确定，其余Pod即为Pod名称
+func MemberOf(pod *v1.Pod) string {
+	if member := pod.Labels[PodGroupMemberLabel]; member != "" {
+		return member
+	}
+	return pod.Name
+}
+
+// IsWorkloadPod 判断Pod是否由PodGroup中某个成员的工作负载创建
+func IsWorkloadPod(podGroup *podGroupv1.PodGroup, pod *v1.Pod) bool {
+	if pod.Labels[PodGroupNameLabel] != podGroup.Name || metav1.GetControllerOf(pod) == nil || metav1.IsControlledBy(pod, podGroup) {
+		return false
+	}
+	template := podGroup.Spec.TemplateOf(MemberOf(pod))
+	return template != nil && template.IsWorkload()
+}
+
+// IsMemberPod 判断Pod是否为PodGroup的成员Pod，即由PodGroup直接创建，或者由成员的工作负载创建
+// controller与podGroup-scheduler使用同样的规则统计成员Pod
+func IsMemberPod(podGroup *podGroupv1.PodGroup, pod *v1.Pod) bool {
+	return metav1.IsControlledBy(pod, podGroup) || IsWorkloadPod(podGroup, pod)
+}
+
+// PodToWorkload 将渲染好的成员Pod包装为kind类型、只有一个副本的工作负载
+// 工作负载与成员Pod同名，继承成员Pod的OwnerReference，成员Pod的标签、注解、亲和性等全部写入工作负载的Pod模板
+// Deployment与StatefulSet通过PodGroupNameLabel与PodGroupMemberLabel选择Pod，因此pod需要带有这两个标签
+// Job未设置restartPolicy或者为Always时使用OnFailure
+func PodToWorkload(kind podGroupv1.WorkloadKind, pod v1.Pod, templateHash string) (client.Object, error) {
+	meta := metav1.ObjectMeta{
+		Name:            pod.Name,
+		Namespace:       pod.Namespace,
+		Labels:          map[string]string{PodGroupNameLabel: pod.Labels[PodGroupNameLabel]},
+		Annotations:     map[string]string{TemplateHashAnnotation: templateHash},
+		OwnerReferences: pod.OwnerReferences,
+	}
+	template := v1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels:      maps.Clone(pod.Labels),
+			Annotations: maps.Clone(pod.Annotations),
+		},
+		Spec: *pod.Spec.DeepCopy(),
+	}
+	selector := &metav1.LabelSelector{
+		MatchLabels: map[string]string{
+			PodGroupNameLabel:   pod.Labels[PodGroupNameLabel],
+			PodGroupMemberLabel: pod.Labels[PodGroupMemberLabel],
+		},
+	}
+	one := int32(1)
+	switch kind {
+	case podGroupv1.DeploymentWorkload:
+		return &appsv1.Deployment{
+			ObjectMeta: meta,
+			Spec:       appsv1.DeploymentSpec{Replicas: &one, Selector: selector, Template: template},
+		}, nil
+	case podGroupv1.StatefulSetWorkload:
+		return &appsv1.StatefulSet{
+			ObjectMeta: meta,
+			Spec:       appsv1.StatefulSetSpec{Replicas: &one, Selector: selector, Template: template, ServiceName: pod.Name},
+		}, nil
+	case podGroupv1.JobWorkload:
+		if template.Spec.RestartPolicy == "" || template.Spec.RestartPolicy == v1.RestartPolicyAlways {
+			template.Spec.RestartPolicy = v1.RestartPolicyOnFailure
+		}
+		return &batchv1.Job{
+			ObjectMeta: meta,
+			Spec:       batchv1.JobSpec{Parallelism: &one, Completions: &one, Template: template},
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported workload kind %s", kind)
+	}
+}
+
+// WorkloadKindOf 返回工作负载对象对应的WorkloadKind，不支持的对象返回空字符串
+func WorkloadKindOf(obj client.Object) podGroupv1.WorkloadKind {
+	switch obj.(type) {
+	case *appsv1.Deployment:
+		return podGroupv1.DeploymentWorkload
+	case *appsv1.StatefulSet:
+		return podGroupv1.StatefulSetWorkload
+	case *batchv1.Job:
+		return podGroupv1.JobWorkload
+	default:
+		return ""
+	}
+}
+
+// WorkloadPodTemplate 返回工作负载中Pod模板的指针，不支持的对象返回nil
+func WorkloadPodTemplate(obj client.Object) *v1.PodTemplateSpec {
+	switch w := obj.(type) {
+	case *appsv1.Deployment:
+		return &w.Spec.Template
+	case *appsv1.StatefulSet:
+		return &w.Spec.Template
+	case *batchv1.Job:
+		return &w.Spec.Template
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,60 @@
+package model
+
+import (
+	"testing"
+
+	podGroupv1 "github.com/SMALL-head/podGroup/api/v1"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestPodToWorkload(t *testing.T) {
+	template := podGroupv1.PodTemplate{
+		Metadata: podGroupv1.PodMetadata{Name: "db", Labels: map[string]string{"app": "demo"}},
+		Spec:     v1.PodSpec{Containers: []v1.Container{{Name: "db", Image: "postgres"}}},
+	}
+	owner := metav1.ObjectMeta{Name: "pg", Namespace: "default", UID: "uid"}
+	gvk := schema.GroupVersionKind{Group: "core.cic.io", Version: "v1", Kind: "PodGroup"}
+	pod := PodTemplate2PodSpec(template, owner, "node1", gvk)
+	SetMemberLabels(&pod, "pg", "db", "db")
+	hash := TemplateHash(template)
+
+	obj, err := PodToWorkload(podGroupv1.DeploymentWorkload, pod, hash)
+	require.NoError(t, err)
+	deploy, ok := obj.(*appsv1.Deployment)
+	require.True(t, ok)
+	require.Equal(t, "db", deploy.Name)
+	require.Equal(t, "default", deploy.Namespace)
+	require.Equal(t, hash, deploy.Annotations[TemplateHashAnnotation])
+	require.Equal(t, pod.OwnerReferences, deploy.OwnerReferences)
+	require.Equal(t, int32(1), *deploy.Spec.Replicas)
+	require.Equal(t, map[string]string{PodGroupNameLabel: "pg", PodGroupMemberLabel: "db"}, deploy.Spec.Selector.MatchLabels)
+	require.Equal(t, "demo", deploy.Spec.Template.Labels["app"])
+	// placement通过Pod模板的节点亲和性生效
+	terms := deploy.Spec.Template.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	require.Equal(t, []string{"node1"}, terms[0].MatchExpressions[0].Values)
+	require.Equal(t, podGroupv1.DeploymentWorkload, WorkloadKindOf(deploy))
+	require.Same(t, &deploy.Spec.Template, WorkloadPodTemplate(deploy))
+
+	obj, err = PodToWorkload(podGroupv1.StatefulSetWorkload, pod, hash)
+	require.NoError(t, err)
+	require.Equal(t, "db", obj.(*appsv1.StatefulSet).Spec.ServiceName)
+
+	obj, err = PodToWorkload(podGroupv1.JobWorkload, pod, hash)
+	require.NoError(t, err)
+	job := obj.(*batchv1.Job)
+	require.Equal(t, v1.RestartPolicyOnFailure, job.Spec.Template.Spec.RestartPolicy)
+	require.Nil(t, job.Spec.Selector)
+	require.Empty(t, pod.Spec.RestartPolicy)
+
+	_, err = PodToWorkload(podGroupv1.PodWorkload, pod, hash)
+	require.Error(t, err)
+
+	// 模板变化时哈希变化
+	template.Spec.Containers[0].Image = "postgres:17"
+	require.NotEqual(t, hash, TemplateHash(template))
+}
@@ -0,0 +1,43 @@
+package planning
+
+import (
+	"cmp"
+	"math"
+	"slices"
+
+	"github.com/SMALL-head/podGroup/internal/scheduling/model"
+)
+
+// HeaviestDependencies 返回与podName之间通信权重（两个方向之和）最大的至多n个依赖Pod，按照权重从高到低排列，权重相同时按照Pod名称排列
+// 通信权重最大的依赖Pod的偏好权重为model.MaxPreferenceWeight，其余依赖Pod按照通信权重等比例缩放，最小为1
+func HeaviestDependencies(pRes *model.PodGroupParseResult, podName string, n int) []model.PodPreference {
+	src := slices.Index(pRes.PodNameList, podName)
+	if src < 0 || n <= 0 {
+		return nil
+	}
+	type dependency struct {
+		podName string
+		weight  float64
+	}
+	var deps []dependency
+	for j, name := range pRes.PodNameList {
+		w := pRes.PodDependencies.Get(src, j) + pRes.PodDependencies.Get(j, src)
+		if j != src && w > 0 {
+			deps = append(deps, dependency{podName: name, weight: w})
+		}
+	}
+	if len(deps) == 0 {
+		return nil
+	}
+	slices.SortFunc(deps, func(a, b dependency) int {
+		return cmp.Or(cmp.Compare(b.weight, a.weight), cmp.Compare(a.podName, b.podName))
+	})
+
+	deps = deps[:min(n, len(deps))]
+	res := make([]model.PodPreference, 0, len(deps))
+	for _, dep := range deps {
+		weight := int32(math.Round(float64(model.MaxPreferenceWeight) * dep.weight / deps[0].weight))
+		res = append(res, model.PodPreference{PodName: dep.podName, Weight: max(weight, 1)})
+	}
+	return res
+}
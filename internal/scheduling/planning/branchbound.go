@@ -0,0 +1,388 @@
+package planning
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"math"
+	"slices"
+
+	"github.com/SMALL-head/podGroup/internal/scheduling/model"
+)
+
+// ErrNoFeasiblePlacement 在节点剩余资源与硬约束下不存在所有Pod都能分配的placement
+var ErrNoFeasiblePlacement = errors.New("no placement fits every pod without violating constraints")
+
+// ErrSearchBudgetExceeded 搜索超出预算时仍然没有找到所有Pod都能分配的placement
+var ErrSearchBudgetExceeded = errors.New("no feasible placement found within the search budget")
+
+// BranchAndBoundResult 分支定界的求解结果
+type BranchAndBoundResult struct {
+	// Assign Assign[i]为Pod i分配的节点下标
+	Assign []int
+	// Cost 为Assign的通信延迟代价，与Problem.Cost一致
+	Cost float64
+	// Gap 为(Cost-下界)/Cost，完成搜索时为0，即Assign已被证明最优
+	Gap float64
+	// Explored 为展开的搜索树节点数量
+	Explored int
+}
+
+// bnbChild 搜索树中一个Pod分配到某个节点得到的子节点
+type bnbChild struct {
+	node int
+	// inc 为分配后与已分配Pod之间增加的代价
+	inc float64
+	// lb 为子树中所有完整assign代价的下界
+	lb float64
+}
+
+type bnbSearch struct {
+	ctx     context.Context
+	problem *Problem
+	// order Pod的分配顺序，按照加权度数从高到低
+	order []int
+	// located located[p]为Pod p分配的节点下标，-1表示尚未分配
+	located []int
+	// nodes 节点剩余容量
+	nodes []model.Node
+	// podCount 每个节点上已分配的Pod数量
+	podCount []int
+	// inc inc[depth][p][n]为搜索深度depth时未分配的Pod p分配到节点n与已分配Pod之间增加的代价
+	inc [][][]float64
+	// pairRest pairRest[depth]为order[depth:]中的Pod两两之间代价的下界
+	pairRest []float64
+	// conflict conflict[p][q]表示Pod p与Pod q属于同一个反亲和分组
+	conflict [][]bool
+	// twins twins[n]为与节点n可以互换的节点，不包括n本身
+	twins [][]int
+
+	maxNodes int
+	explored int
+	aborted  bool
+	// openBound 超出预算时未展开的子树的下界中的最小值
+	openBound float64
+	best      []int
+	bestCost  float64
+}
+
+// BranchAndBoundAssign 使用分支定界求解通信延迟代价(Problem.Cost)最低、所有Pod都放得下并且满足problem.Constraints的assign
+//   - Pod按照加权度数从高到低依次分配，每个Pod按照下界从低到高依次尝试可用的节点，尽早得到较好的上界用于剪枝
+//   - 下界为已分配Pod之间的代价，加上每个未分配Pod分配到最好的可用节点时与已分配Pod之间增加的代价，
+//     再加上根据节点容量与反亲和约束得到的未分配Pod两两之间代价的下界
+//   - 延迟与容量完全相同的空节点可以互换，只尝试其中下标最小的一个
+//
+// 展开的搜索树节点超过maxNodes或者ctx结束时停止搜索，返回目前找到的最优assign以及最优性差距
+// 不存在可行的assign时返回ErrNoFeasiblePlacement，停止搜索时仍然没有找到可行的assign时返回ErrSearchBudgetExceeded
+func BranchAndBoundAssign(ctx context.Context, problem *Problem, maxNodes int) (*BranchAndBoundResult, error) {
+	podSize, nodeSize := len(problem.Pods), len(problem.Nodes)
+	if nodeSize == 0 {
+		return nil, ErrNoAvailableNode
+	}
+	s := &bnbSearch{
+		ctx:       ctx,
+		problem:   problem,
+		order:     degreeOrder(problem.PodDependencies, podSize),
+		located:   make([]int, podSize),
+		nodes:     slices.Clone(problem.Nodes),
+		podCount:  make([]int, nodeSize),
+		inc:       make([][][]float64, podSize+1),
+		conflict:  make([][]bool, podSize),
+		twins:     twinNodes(problem),
+		maxNodes:  maxNodes,
+		openBound: math.Inf(1),
+		bestCost:  math.Inf(1),
+	}
+	for p := range s.located {
+		s.located[p] = -1
+		s.conflict[p] = make([]bool, podSize)
+	}
+	for depth := range s.inc {
+		s.inc[depth] = make([][]float64, podSize)
+		for p := range s.inc[depth] {
+			s.inc[depth][p] = make([]float64, nodeSize)
+		}
+	}
+	for _, group := range problem.Constraints.AntiAffinity {
+		for _, p := range group {
+			for _, q := range group {
+				if p != q {
+					s.conflict[p][q] = true
+				}
+			}
+		}
+	}
+	s.pairRest = s.pairBounds()
+
+	s.dfs(0, 0)
+	if s.best == nil {
+		if s.aborted {
+			return nil, ErrSearchBudgetExceeded
+		}
+		return nil, ErrNoFeasiblePlacement
+	}
+	res := &BranchAndBoundResult{Assign: s.best, Cost: s.bestCost, Explored: s.explored}
+	if lb := min(s.openBound, s.bestCost); s.aborted && s.bestCost > 0 {
+		res.Gap = (s.bestCost - lb) / s.bestCost
+	}
+	return res, nil
+}
+
+// degreeOrder 返回按照加权度数从高到低排列的Pod下标，出边与入边的权重均计入，度数相同时按照下标排列
+func degreeOrder(dependencies model.PodDependencies, podSize int) []int {
+	degrees := make([]float64, podSize)
+	for i := 0; i < podSize; i++ {
+		for j := 0; j < podSize; j++ {
+			degrees[i] += dependencies.Get(i, j)
+			degrees[j] += dependencies.Get(i, j)
+		}
+	}
+	order := make([]int, podSize)
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		return cmp.Compare(degrees[b], degrees[a])
+	})
+	return order
+}
+
+// twinNodes 返回每个节点可以互换的节点，即容量相同、与其他节点之间的延迟相同、并且彼此之间延迟对称的节点
+func twinNodes(problem *Problem) [][]int {
+	lat := &problem.NodeLatencies
+	twin := func(a, b int) bool {
+		if problem.Nodes[a].CPUCap != problem.Nodes[b].CPUCap || problem.Nodes[a].MemCap != problem.Nodes[b].MemCap ||
+			lat.Get(a, a) != lat.Get(b, b) || lat.Get(a, b) != lat.Get(b, a) {
+			return false
+		}
+		for x := range problem.Nodes {
+			if x != a && x != b && (lat.Get(a, x) != lat.Get(b, x) || lat.Get(x, a) != lat.Get(x, b)) {
+				return false
+			}
+		}
+		return true
+	}
+	res := make([][]int, len(problem.Nodes))
+	for a := range problem.Nodes {
+		for b := range problem.Nodes {
+			if a != b && twin(a, b) {
+				res[a] = append(res[a], b)
+			}
+		}
+	}
+	return res
+}
+
+// pairBounds 计算pairRest，一个节点上最多只能同时放下nodePodLimit个Pod，
+// 因此每个Pod最多与其中通信权重最大的nodePodLimit-1个Pod位于同一个节点，与其余Pod之间至少是不同节点之间的最低延迟，
+// 同一个反亲和分组内的Pod之间同样至少是不同节点之间的最低延迟，每一对Pod在两个Pod上各计一次，因此取一半
+// 节点自身的延迟可能高于不同节点之间的延迟，可以共存的Pod之间取两者中较低的值
+func (s *bnbSearch) pairBounds() []float64 {
+	lat := &s.problem.NodeLatencies
+	dep := &s.problem.PodDependencies
+	sameNode, otherNode := math.Inf(1), math.Inf(1)
+	for a := range s.problem.Nodes {
+		for b := range s.problem.Nodes {
+			if a == b {
+				sameNode = min(sameNode, lat.Get(a, b))
+			} else {
+				otherNode = min(otherNode, lat.Get(a, b))
+			}
+		}
+	}
+	if math.IsInf(otherNode, 1) {
+		// 只有一个节点时不能共存的Pod没有可行的assign，由搜索本身发现
+		otherNode = sameNode
+	}
+	sameNode = min(sameNode, otherNode)
+	limit := nodePodLimit(s.problem)
+	res := make([]float64, len(s.order)+1)
+	weights := make([]float64, 0, len(s.order))
+	for k := range s.order {
+		total := 0.0
+		for _, p := range s.order[k:] {
+			weights = weights[:0]
+			for _, q := range s.order[k:] {
+				w := dep.Get(p, q) + dep.Get(q, p)
+				switch {
+				case p == q || w == 0:
+				case s.conflict[p][q]:
+					total += w * otherNode
+				default:
+					weights = append(weights, w)
+				}
+			}
+			slices.SortFunc(weights, func(a, b float64) int { return cmp.Compare(b, a) })
+			shared := min(limit-1, len(weights))
+			for i, w := range weights {
+				if i < shared {
+					total += w * sameNode
+				} else {
+					total += w * otherNode
+				}
+			}
+		}
+		res[k] = total / 2
+	}
+	return res
+}
+
+// nodePodLimit 返回任意一个节点上最多能够同时放下的Pod数量的上界
+// 分别只考虑CPU与内存时，按照请求量从小到大放入能够得到各自的最大数量，两者中较小的值即为该节点的上界
+func nodePodLimit(problem *Problem) int {
+	limit := 0
+	for _, node := range problem.Nodes {
+		count := min(fittingCount(problem.Pods, node.CPUCap, func(pod model.PodModel) float64 { return pod.CPUReq }),
+			fittingCount(problem.Pods, node.MemCap, func(pod model.PodModel) float64 { return pod.MemReq }))
+		limit = max(limit, count)
+	}
+	if maxPods := problem.Constraints.MaxPodsPerNode; maxPods > 0 {
+		limit = min(limit, maxPods)
+	}
+	return max(limit, 1)
+}
+
+// fittingCount 返回容量为capacity的一个维度上最多能够放下的Pod数量，容量未知(<=0)时不受限
+func fittingCount(pods []model.PodModel, capacity float64, request func(model.PodModel) float64) int {
+	if capacity <= 0 {
+		return len(pods)
+	}
+	reqs := make([]float64, len(pods))
+	for i, pod := range pods {
+		reqs[i] = request(pod)
+	}
+	slices.Sort(reqs)
+	count, used := 0, 0.0
+	for _, req := range reqs {
+		if used+req > capacity {
+			break
+		}
+		used += req
+		count++
+	}
+	return count
+}
+
+// available 判断Pod p能否分配到节点n，与n可以互换的空节点中只有下标最小的一个可用
+func (s *bnbSearch) available(p, n int) bool {
+	pod := s.problem.Pods[p]
+	if !s.nodes[n].Fits(pod.CPUReq, pod.MemReq) || !s.problem.Constraints.Allows(s.located, p, n) {
+		return false
+	}
+	if s.podCount[n] == 0 {
+		for _, m := range s.twins[n] {
+			if m < n && s.podCount[m] == 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// children 返回深度为depth时下一个Pod的所有可用节点，按照下界从低到高排列
+// 分配之后某个未分配的Pod没有任何节点可用时，该子节点不会被返回
+func (s *bnbSearch) children(depth int, cost float64) []bnbChild {
+	lat := &s.problem.NodeLatencies
+	dep := &s.problem.PodDependencies
+	maxPods := s.problem.Constraints.MaxPodsPerNode
+	p := s.order[depth]
+	pod := s.problem.Pods[p]
+	inc := s.inc[depth]
+	var res []bnbChild
+	for n := range s.nodes {
+		if !s.available(p, n) {
+			continue
+		}
+		after := s.nodes[n]
+		after.Consume(pod.CPUReq, pod.MemReq)
+		lb := cost + inc[p][n] + s.pairRest[depth+1]
+		feasible := true
+		for _, q := range s.order[depth+1:] {
+			other := s.problem.Pods[q]
+			best := math.Inf(1)
+			for m := range s.nodes {
+				// 这里只检查容量、maxPodsPerNode以及与Pod p之间的反亲和，得到的仍然是下界
+				switch {
+				case m == n && (!after.Fits(other.CPUReq, other.MemReq) || s.conflict[p][q] || maxPods > 0 && s.podCount[m]+1 >= maxPods):
+					continue
+				case m != n && (!s.nodes[m].Fits(other.CPUReq, other.MemReq) || maxPods > 0 && s.podCount[m] >= maxPods):
+					continue
+				}
+				best = min(best, inc[q][m]+dep.Get(q, p)*lat.Get(m, n)+dep.Get(p, q)*lat.Get(n, m))
+			}
+			if math.IsInf(best, 1) {
+				feasible = false
+				break
+			}
+			lb += best
+		}
+		if feasible {
+			res = append(res, bnbChild{node: n, inc: inc[p][n], lb: lb})
+		}
+	}
+	slices.SortStableFunc(res, func(a, b bnbChild) int {
+		return cmp.Compare(a.lb, b.lb)
+	})
+	return res
+}
+
+// exceeded 记录展开的搜索树节点，超出maxNodes或者ctx结束时返回true
+func (s *bnbSearch) exceeded() bool {
+	if s.aborted {
+		return true
+	}
+	s.explored++
+	if s.explored > s.maxNodes || s.explored%1024 == 0 && s.ctx.Err() != nil {
+		s.aborted = true
+	}
+	return s.aborted
+}
+
+func (s *bnbSearch) dfs(depth int, cost float64) {
+	if depth == len(s.order) {
+		if cost < s.bestCost {
+			s.bestCost = cost
+			s.best = slices.Clone(s.located)
+		}
+		return
+	}
+	lat := &s.problem.NodeLatencies
+	dep := &s.problem.PodDependencies
+	p := s.order[depth]
+	pod := s.problem.Pods[p]
+	children := s.children(depth, cost)
+	for i, child := range children {
+		// 子节点按照下界排列，之后的子节点同样可以剪枝
+		if child.lb >= s.bestCost-1e-9 {
+			return
+		}
+		if s.exceeded() {
+			for _, rest := range children[i:] {
+				s.openBound = min(s.openBound, rest.lb)
+			}
+			return
+		}
+
+		n := child.node
+		saved := s.nodes[n]
+		s.located[p] = n
+		s.podCount[n]++
+		s.nodes[n].Consume(pod.CPUReq, pod.MemReq)
+		for _, q := range s.order[depth+1:] {
+			for m := range s.nodes {
+				s.inc[depth+1][q][m] = s.inc[depth][q][m] + dep.Get(q, p)*lat.Get(m, n) + dep.Get(p, q)*lat.Get(n, m)
+			}
+		}
+		s.dfs(depth+1, cost+child.inc)
+		s.nodes[n] = saved
+		s.podCount[n]--
+		s.located[p] = -1
+
+		if s.aborted {
+			for _, rest := range children[i+1:] {
+				s.openBound = min(s.openBound, rest.lb)
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package planning
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// ErrConstraintViolated 求解结果违反了spec.antiAffinity或者spec.maxPodsPerNode
+var ErrConstraintViolated = errors.New("placement violates constraints")
+
+// Constraints placement的硬约束，违反任意一条约束的placement不会被采用
+type Constraints struct {
+	// AntiAffinity 每一组为Pod在Problem.Pods中的下标，同一组内的Pod不能分配到同一个节点
+	AntiAffinity [][]int
+	// MaxPodsPerNode 每个节点上最多分配的Pod数量，0表示不限制
+	MaxPodsPerNode int
+}
+
+// newConstraints 将按照Pod名称描述的约束转换为按照下标描述的约束，不存在的Pod会被忽略
+func newConstraints(podNameList []string, antiAffinity [][]string, maxPodsPerNode int) Constraints {
+	res := Constraints{MaxPodsPerNode: maxPodsPerNode}
+	for _, group := range antiAffinity {
+		var idx []int
+		for _, podName := range group {
+			if i := slices.Index(podNameList, podName); i >= 0 && !slices.Contains(idx, i) {
+				idx = append(idx, i)
+			}
+		}
+		if len(idx) > 1 {
+			res.AntiAffinity = append(res.AntiAffinity, idx)
+		}
+	}
+	return res
+}
+
+// Violations 返回assign违反约束的次数，assign[i]小于0表示Pod i没有分配节点
+//   - 节点上的Pod超过MaxPodsPerNode时，每多一个Pod计一次
+//   - 同一组内的Pod分配到同一个节点时，除第一个Pod外每个Pod计一次
+func (c *Constraints) Violations(assign []int) int {
+	count := 0
+	if c.MaxPodsPerNode > 0 {
+		perNode := make(map[int]int)
+		for _, n := range assign {
+			if n >= 0 {
+				perNode[n]++
+			}
+		}
+		for _, cnt := range perNode {
+			count += max(cnt-c.MaxPodsPerNode, 0)
+		}
+	}
+	for _, group := range c.AntiAffinity {
+		perNode := make(map[int]int, len(group))
+		for _, p := range group {
+			if n := assign[p]; n >= 0 {
+				perNode[n]++
+			}
+		}
+		for _, cnt := range perNode {
+			count += cnt - 1
+		}
+	}
+	return count
+}
+
+// Allows 判断在assign的基础上将Pod podIdx分配到节点nodeIdx是否违反约束，assign[podIdx]本身不参与判断
+func (c *Constraints) Allows(assign []int, podIdx, nodeIdx int) bool {
+	if c.MaxPodsPerNode > 0 {
+		cnt := 0
+		for p, n := range assign {
+			if p != podIdx && n == nodeIdx {
+				cnt++
+			}
+		}
+		if cnt >= c.MaxPodsPerNode {
+			return false
+		}
+	}
+	for _, group := range c.AntiAffinity {
+		if !slices.Contains(group, podIdx) {
+			continue
+		}
+		for _, p := range group {
+			if p != podIdx && assign[p] == nodeIdx {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// CheckPlan 检查Plan是否满足problem的约束，Plan中没有的Pod不参与检查
+func (p *Problem) CheckPlan(plan *Plan) error {
+	nodeIdx := make(map[string]int, len(p.Nodes))
+	for i, n := range p.Nodes {
+		nodeIdx[n.NodeName] = i
+	}
+	assign := make([]int, len(p.Pods))
+	for i, pod := range p.Pods {
+		assign[i] = -1
+		if idx, ok := nodeIdx[plan.Assign[pod.PodName]]; ok {
+			assign[i] = idx
+		}
+	}
+	if v := p.Constraints.Violations(assign); v > 0 {
+		return fmt.Errorf("%w: %d violations of antiAffinity or maxPodsPerNode", ErrConstraintViolated, v)
+	}
+	return nil
+}
@@ -0,0 +1,396 @@
+package planning
+
+import (
+	"cmp"
+	"math/rand"
+	"slices"
+
+	"github.com/SMALL-head/podGroup/internal/scheduling/model"
+)
+
+// usageEpsilon 增量更新节点资源用量时累积的浮点误差，使用率超过1+usageEpsilon才认为超出节点资源
+const usageEpsilon = 1e-9
+
+// evalEdge Pod之间的依赖，out与in分别为dependencies[p][pod]与dependencies[pod][p]
+type evalEdge struct {
+	pod     int
+	out, in float64
+}
+
+// nodeUsage 节点上已分配Pod的资源用量
+type nodeUsage struct {
+	cpu, mem float64
+}
+
+// nodeSnapshot Move或者Swap之前节点的资源用量，用于Undo
+type nodeSnapshot struct {
+	node  int
+	usage nodeUsage
+}
+
+// podMove Move或者Swap中Pod原先所在的节点，用于Undo
+type podMove struct {
+	pod, from int
+}
+
+// evalTotals 目标函数的各项汇总值
+type evalTotals struct {
+	// latency 与computeTotalLatency相同
+	latency float64
+	// penalty 与computeResourceBalancePenalty相同
+	penalty float64
+	// weighted 各节点平均资源使用率与节点延迟之和的乘积之和，computeAllocBalancePenalty中的miu*nodeSize
+	weighted float64
+	// overloaded 超出资源的节点数量
+	overloaded int
+	// violations 与Constraints.Violations相同
+	violations int
+}
+
+// evalState 模拟退火目标函数的增量计算状态，保存当前assign下每个节点的资源用量、Pod数量以及延迟与惩罚的汇总值。
+// 移动一个Pod时只需要遍历该Pod的依赖与它所在的反亲和组，代价为O(degree)，不需要像objectiveFunc一样重新计算O(podSize^2)的延迟。
+// move与swap之后可以调用undo撤销，只保留最近一次操作
+type evalState struct {
+	latencies   model.NodeLatencies
+	pods        []model.PodModel
+	nodes       []model.Node
+	constraints *Constraints
+
+	assign []int
+	// adj[p] Pod p与其他Pod之间的依赖，self[p]为dependencies[p][p]
+	adj  [][]evalEdge
+	self []float64
+
+	usage    []nodeUsage
+	podCount []int
+	// groups[p] Pod p所在的反亲和组下标，groupCount[g][n]为反亲和组g分配到节点n的Pod数量
+	groups     [][]int
+	groupCount [][]int
+	// latSum[n] 节点n到其他节点的延迟之和，即computeAllocBalancePenalty中的1/lw[n]
+	latSum                []float64
+	latSumTotal, latSumSq float64
+
+	totals evalTotals
+
+	undoable   bool
+	undoTotals evalTotals
+	undoMoves  []podMove
+	undoNodes  []nodeSnapshot
+}
+
+// newEvalState 以assign为初始状态构造evalState，assign会被复制
+func newEvalState(latenciesMap model.NodeLatencies, podDependencies model.PodDependencies,
+	pods []model.PodModel, nodeStatuses []model.Node, constraints *Constraints, assign []int) *evalState {
+	podSize, nodeSize := len(pods), len(nodeStatuses)
+	s := &evalState{
+		latencies:   latenciesMap,
+		pods:        pods,
+		nodes:       nodeStatuses,
+		constraints: constraints,
+		assign:      slices.Clone(assign),
+		adj:         make([][]evalEdge, podSize),
+		self:        make([]float64, podSize),
+		usage:       make([]nodeUsage, nodeSize),
+		podCount:    make([]int, nodeSize),
+		groups:      make([][]int, podSize),
+		latSum:      make([]float64, nodeSize),
+	}
+	for i := 0; i < podSize; i++ {
+		s.self[i] = podDependencies[i][i]
+		for j := 0; j < podSize; j++ {
+			if i != j && (podDependencies[i][j] != 0 || podDependencies[j][i] != 0) {
+				s.adj[i] = append(s.adj[i], evalEdge{pod: j, out: podDependencies[i][j], in: podDependencies[j][i]})
+			}
+		}
+	}
+	if constraints != nil {
+		s.groupCount = make([][]int, len(constraints.AntiAffinity))
+		for g, group := range constraints.AntiAffinity {
+			s.groupCount[g] = make([]int, nodeSize)
+			for _, p := range group {
+				s.groups[p] = append(s.groups[p], g)
+			}
+		}
+	}
+	for n := 0; n < nodeSize; n++ {
+		for m := 0; m < nodeSize; m++ {
+			s.latSum[n] += latenciesMap[n][m]
+		}
+		s.latSumTotal += s.latSum[n]
+		s.latSumSq += s.latSum[n] * s.latSum[n]
+	}
+
+	for p, n := range s.assign {
+		s.usage[n].cpu += pods[p].CPUReq
+		s.usage[n].mem += pods[p].MemReq
+		s.podCount[n]++
+		for _, g := range s.groups[p] {
+			s.groupCount[g][n]++
+		}
+	}
+	s.totals.latency = computeTotalLatency(s.assign, latenciesMap, podDependencies, podSize)
+	for n := range s.usage {
+		term, weighted, over := s.nodeTerm(n)
+		s.totals.penalty += term
+		s.totals.weighted += weighted
+		if over {
+			s.totals.overloaded++
+		}
+	}
+	if constraints != nil {
+		s.totals.violations = constraints.Violations(s.assign)
+	}
+	return s
+}
+
+// nodeTerm 返回节点n在computeResourceBalancePenalty中的惩罚项、在computeAllocBalancePenalty中的加权使用率，以及是否超出资源
+func (s *evalState) nodeTerm(n int) (term, weighted float64, over bool) {
+	cpuUsageRatio := usageRatio(s.usage[n].cpu, s.nodes[n].CPUCap)
+	memUsageRatio := usageRatio(s.usage[n].mem, s.nodes[n].MemCap)
+	term = cpuUsageRatio*cpuUsageRatio + memUsageRatio*memUsageRatio
+	weighted = (cpuUsageRatio + memUsageRatio) / 2 * s.latSum[n]
+	over = cpuUsageRatio > 1+usageEpsilon || memUsageRatio > 1+usageEpsilon
+	return
+}
+
+// latencyDelta 返回将Pod p移动到节点to之后computeTotalLatency的变化量
+func (s *evalState) latencyDelta(p, to int) float64 {
+	from := s.assign[p]
+	delta := s.self[p] * (s.latencies[to][to] - s.latencies[from][from])
+	for _, e := range s.adj[p] {
+		m := s.assign[e.pod]
+		delta += e.out*(s.latencies[to][m]-s.latencies[from][m]) + e.in*(s.latencies[m][to]-s.latencies[m][from])
+	}
+	return delta / 2
+}
+
+// moveCounts 更新Pod p从节点from移动到节点to之后的Pod数量与反亲和组计数，返回违反约束次数的变化量
+func (s *evalState) moveCounts(p, from, to int) (delta int) {
+	if s.constraints != nil && s.constraints.MaxPodsPerNode > 0 {
+		if s.podCount[from] > s.constraints.MaxPodsPerNode {
+			delta--
+		}
+		if s.podCount[to] >= s.constraints.MaxPodsPerNode {
+			delta++
+		}
+	}
+	s.podCount[from]--
+	s.podCount[to]++
+	for _, g := range s.groups[p] {
+		if s.groupCount[g][from] > 1 {
+			delta--
+		}
+		if s.groupCount[g][to] > 0 {
+			delta++
+		}
+		s.groupCount[g][from]--
+		s.groupCount[g][to]++
+	}
+	return
+}
+
+// saveNode 在本次操作中第一次修改节点n之前保存它的资源用量
+func (s *evalState) saveNode(n int) {
+	for _, snapshot := range s.undoNodes {
+		if snapshot.node == n {
+			return
+		}
+	}
+	s.undoNodes = append(s.undoNodes, nodeSnapshot{node: n, usage: s.usage[n]})
+}
+
+// updateNode 将节点n的资源用量增加cpu与mem，并更新汇总值
+func (s *evalState) updateNode(n int, cpu, mem float64) {
+	s.saveNode(n)
+	term, weighted, over := s.nodeTerm(n)
+	s.totals.penalty -= term
+	s.totals.weighted -= weighted
+	if over {
+		s.totals.overloaded--
+	}
+	s.usage[n].cpu += cpu
+	s.usage[n].mem += mem
+	term, weighted, over = s.nodeTerm(n)
+	s.totals.penalty += term
+	s.totals.weighted += weighted
+	if over {
+		s.totals.overloaded++
+	}
+}
+
+// apply 将Pod p移动到节点to，不开始新的操作
+func (s *evalState) apply(p, to int) {
+	from := s.assign[p]
+	if from == to {
+		return
+	}
+	s.undoMoves = append(s.undoMoves, podMove{pod: p, from: from})
+	s.totals.latency += s.latencyDelta(p, to)
+	s.updateNode(from, -s.pods[p].CPUReq, -s.pods[p].MemReq)
+	s.updateNode(to, s.pods[p].CPUReq, s.pods[p].MemReq)
+	s.totals.violations += s.moveCounts(p, from, to)
+	s.assign[p] = to
+}
+
+// begin 开始一次新的操作，之前的操作不能再撤销
+func (s *evalState) begin() {
+	s.undoable = true
+	s.undoTotals = s.totals
+	s.undoMoves = s.undoMoves[:0]
+	s.undoNodes = s.undoNodes[:0]
+}
+
+// move 将Pod p移动到节点to
+func (s *evalState) move(p, to int) {
+	s.begin()
+	s.apply(p, to)
+}
+
+// swap 交换Pod p与Pod q所在的节点
+func (s *evalState) swap(p, q int) {
+	s.begin()
+	np, nq := s.assign[p], s.assign[q]
+	s.apply(p, nq)
+	s.apply(q, np)
+}
+
+// undo 撤销最近一次move或者swap，汇总值与节点资源用量恢复为操作之前保存的值，不会累积浮点误差
+func (s *evalState) undo() {
+	if !s.undoable {
+		return
+	}
+	s.undoable = false
+	for i := len(s.undoMoves) - 1; i >= 0; i-- {
+		m := s.undoMoves[i]
+		s.moveCounts(m.pod, s.assign[m.pod], m.from)
+		s.assign[m.pod] = m.from
+	}
+	for _, snapshot := range s.undoNodes {
+		s.usage[snapshot.node] = snapshot.usage
+	}
+	s.totals = s.undoTotals
+}
+
+// infeasible 判断当前assign是否超出节点资源或者违反硬约束，此时目标函数的值为constraintPenalty
+func (s *evalState) infeasible() (bool, float64) {
+	if s.totals.overloaded == 0 && s.totals.violations == 0 {
+		return false, 0
+	}
+	if s.constraints == nil {
+		return true, ResourceLimitConstraint
+	}
+	return true, ResourceLimitConstraint + float64(s.totals.violations)
+}
+
+// objective 当前assign下objectiveFunc的值
+func (s *evalState) objective(alpha, beta float64) float64 {
+	if ok, penalty := s.infeasible(); ok {
+		return penalty
+	}
+	return alpha*s.totals.latency + beta*s.totals.penalty
+}
+
+// allocBalancePenalty 当前assign下computeAllocBalancePenalty的值，
+// sum((latSum[n]-miu)^2)展开为sum(latSum[n]^2) - 2*miu*sum(latSum[n]) + nodeSize*miu^2
+func (s *evalState) allocBalancePenalty() float64 {
+	nodeSize := float64(len(s.nodes))
+	miu := s.totals.weighted / nodeSize
+	return s.latSumSq - 2*miu*s.latSumTotal + nodeSize*miu*miu
+}
+
+// normalizedObjective 当前assign下objectFunc2的值
+func (s *evalState) normalizedObjective(alpha, beta float64,
+	latencyMin, latencyMax float64, imbalanceMin, imbalanceMax float64) (float64, int) {
+	if ok, penalty := s.infeasible(); ok {
+		return penalty, 0
+	}
+	if latencyMax == latencyMin {
+		latencyMax += 1
+	}
+	if imbalanceMax == imbalanceMin {
+		imbalanceMax += 1
+	}
+	lOne := (s.totals.latency - latencyMin) / (latencyMax - latencyMin)
+	pOne := (s.allocBalancePenalty() - imbalanceMin) / (imbalanceMax - imbalanceMin)
+	mode := 2 // 资源均衡占比更大
+	if lOne*alpha > pOne*beta {
+		mode = 1 // 延迟占比更大
+	}
+	return alpha*lOne + beta*pOne, mode
+}
+
+// heuristicOrder heuristicMove使用的排序，只与问题本身有关，在搜索开始前计算一次
+type heuristicOrder struct {
+	// nodes 按照到其他节点的延迟之和从小到大排序的节点下标
+	nodes []int
+	// nodeRank[n] 节点n在nodes中的位置
+	nodeRank []int
+	// pods 按照依赖权重之和从小到大排序的Pod下标
+	pods []int
+}
+
+func newHeuristicOrder(s *evalState, podDependencies model.PodDependencies) *heuristicOrder {
+	order := &heuristicOrder{
+		nodes:    make([]int, len(s.nodes)),
+		nodeRank: make([]int, len(s.nodes)),
+		pods:     make([]int, len(s.pods)),
+	}
+	for n := range order.nodes {
+		order.nodes[n] = n
+	}
+	slices.SortStableFunc(order.nodes, func(a, b int) int { return cmp.Compare(s.latSum[a], s.latSum[b]) })
+	for i, n := range order.nodes {
+		order.nodeRank[n] = i
+	}
+
+	deg := make([]float64, len(s.pods))
+	for i, depi := range podDependencies {
+		for j := range depi {
+			if depi[j] > 0 {
+				deg[i] += depi[j]
+				deg[j] += depi[j]
+			}
+		}
+	}
+	for p := range order.pods {
+		order.pods[p] = p
+	}
+	slices.SortStableFunc(order.pods, func(a, b int) int { return cmp.Compare(deg[a], deg[b]) })
+	return order
+}
+
+// heuristicMove 启发式地选择一个Pod以及它的目标节点，对当前assign进行局部优化。当模拟退火算法降温到某个T时，从完全随机变为启发式局部优化。或者当模拟退火算法收敛后（连续x次迭代没有更优解），启发式局部优化
+// mode = 0表示完全启发式；mode = 1表示上次延迟太高了，本次使用延迟降低为主的启发式； mode = 2表示上次资源不均衡，本次使用资源均衡为主的启发式
+// 没有合适的移动时返回的节点为Pod当前所在的节点
+func heuristicMove(r *rand.Rand, s *evalState, order *heuristicOrder, mode int) (podIdx, nodeIdx int) {
+	nodeSize := len(s.nodes)
+	switch mode {
+	case 1: // 延迟较高，随机一个pod，迁移到邻域延迟更低的节点
+		podIdx = r.Intn(len(s.assign))
+		nodeIdx = s.assign[podIdx]
+		if rank := order.nodeRank[nodeIdx]; rank > 0 {
+			nodeIdx = order.nodes[rank-1]
+		}
+		return
+	case 2: // 选度数最低的pod，且该pod分配的节点有重复的，进行变更
+		balance := float64(len(s.pods)) / float64(nodeSize)
+		for _, p := range order.pods[:max(len(order.pods)-2, 0)] {
+			if float64(s.podCount[s.assign[p]]) >= balance+1 {
+				return p, r.Intn(nodeSize)
+			}
+		}
+		return 0, s.assign[0]
+	default:
+		return randomMove(r, s.assign, nodeSize)
+	}
+}
+
+// randomMove 随机选择一个Pod以及一个与它当前所在节点不同的节点
+func randomMove(r *rand.Rand, assign []int, nodeSize int) (podIdx, nodeIdx int) {
+	podIdx = r.Intn(len(assign))
+	nodeIdx = r.Intn(nodeSize)
+	for nodeIdx == assign[podIdx] && nodeSize > 1 {
+		nodeIdx = r.Intn(nodeSize)
+	}
+	return
+}
@@ -0,0 +1,70 @@
+package planning
+
+import (
+	"slices"
+
+	"github.com/SMALL-head/podGroup/internal/scheduling/model"
+)
+
+// Migration 将一个Pod从当前节点迁移到目标节点，Cost为迁移完成后整个PodGroup的通信延迟代价
+type Migration struct {
+	PodName  string
+	FromNode string
+	ToNode   string
+	Cost     float64
+}
+
+// NextMigration 在Pod当前位置current的基础上，选择向目标placement target迁移的下一个Pod，每次只迁移一个Pod
+// 候选为当前节点与目标节点不同的Pod，要求其他Pod位置不变时目标节点放得下该Pod并且满足problem.Constraints，
+// 在候选中选择迁移后代价最低的Pod，代价相同时按照problem.Pods的顺序，
+// 没有可以迁移的Pod，或者迁移任何一个Pod都不能使代价低于current时返回false，
+// 即目标placement需要同时迁移多个Pod时，不会先执行使代价升高的一步
+// problem.Nodes的剩余容量中不应扣除这些Pod自身的资源请求，current中的Pod在这里按照当前位置扣除
+func NextMigration(problem *Problem, current, target map[string]string) (Migration, bool) {
+	nodeIndex := make(map[string]int, len(problem.Nodes))
+	for i, node := range problem.Nodes {
+		nodeIndex[node.NodeName] = i
+	}
+	nodes := slices.Clone(problem.Nodes)
+	located := make([]int, len(problem.Pods))
+	for i, pod := range problem.Pods {
+		located[i] = -1
+		if idx, ok := nodeIndex[current[pod.PodName]]; ok {
+			located[i] = idx
+			nodes[idx].Consume(pod.CPUReq, pod.MemReq)
+		}
+	}
+
+	currentCost := problem.Cost(current)
+	best, bestCost := Migration{}, currentCost
+	for i, pod := range problem.Pods {
+		to, ok := nodeIndex[target[pod.PodName]]
+		if !ok || located[i] < 0 || located[i] == to {
+			continue
+		}
+		if !nodes[to].Fits(pod.CPUReq, pod.MemReq) || !problem.Constraints.Allows(located, i, to) {
+			continue
+		}
+		assign := make(map[string]string, len(current))
+		for podName, nodeName := range current {
+			assign[podName] = nodeName
+		}
+		assign[pod.PodName] = problem.Nodes[to].NodeName
+		if cost := problem.Cost(assign); cost < bestCost {
+			best = Migration{PodName: pod.PodName, FromNode: current[pod.PodName], ToNode: problem.Nodes[to].NodeName, Cost: cost}
+			bestCost = cost
+		}
+	}
+	return best, bestCost < currentCost
+}
+
+// ReleasePods 将位于current中节点上的Pod的资源请求归还到problem.Nodes的剩余容量中，
+// 用于在Pod已经运行的情况下重新求解placement，此时Pod可以继续留在当前节点上
+func ReleasePods(problem *Problem, current map[string]string) {
+	for _, pod := range problem.Pods {
+		idx := slices.IndexFunc(problem.Nodes, func(node model.Node) bool { return node.NodeName == current[pod.PodName] })
+		if idx >= 0 {
+			problem.Nodes[idx].Release(pod.CPUReq, pod.MemReq)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package planning
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+)
+
+// ctxCheckInterval 退火链每隔多少次迭代检查一次ctx是否结束
+const ctxCheckInterval = 256
+
+// ChainStopReason 退火链停止的原因
+type ChainStopReason string
+
+const (
+	// ChainCooled 温度降到finalTemp
+	ChainCooled ChainStopReason = "Cooled"
+	// ChainMaxIterations 温度降到finalTemp之前达到maxIter
+	ChainMaxIterations ChainStopReason = "MaxIterations"
+	// ChainTimeLimit 超出时间限制，迭代次数取决于机器负载，使用相同的种子也无法复现该链的结果
+	ChainTimeLimit ChainStopReason = "TimeLimit"
+	// ChainStopped ctx被取消
+	ChainStopped ChainStopReason = "Stopped"
+)
+
+// ChainReport 一条退火链的收敛情况
+type ChainReport struct {
+	// Seed 该链使用的随机数种子
+	Seed int64
+	// Iterations 实际运行的迭代次数
+	Iterations int
+	// BestIteration 找到该链最优assign时的迭代次数，0表示初始assign即为最优
+	BestIteration int
+	// BestScore 该链找到的目标函数最小值
+	BestScore float64
+	// StopReason 该链停止的原因
+	StopReason ChainStopReason
+}
+
+// finish 在退火链结束时记录迭代次数、目标函数最小值以及停止原因，
+// stopErr为提前停止时ctx的错误，hot表示停止时温度仍高于finalTemp
+func (c *ChainReport) finish(iterations int, bestScore float64, stopErr error, hot bool) {
+	c.Iterations = iterations
+	c.BestScore = bestScore
+	switch {
+	case errors.Is(stopErr, context.DeadlineExceeded):
+		c.StopReason = ChainTimeLimit
+	case stopErr != nil:
+		c.StopReason = ChainStopped
+	case hot:
+		c.StopReason = ChainMaxIterations
+	default:
+		c.StopReason = ChainCooled
+	}
+}
+
+// annealFunc 使用r运行一条退火链，每次找到更优的assign时调用improve
+type annealFunc func(ctx context.Context, r *rand.Rand, improve func(assign []int, score float64)) ([]int, float64, ChainReport)
+
+// sharedBest 所有退火链目前找到的最优assign，目标函数的值相同时保留下标较小的链的assign，
+// 使结果与各条链的执行顺序无关
+type sharedBest struct {
+	mu     sync.Mutex
+	assign []int
+	score  float64
+	chain  int
+}
+
+func (b *sharedBest) offer(chain int, assign []int, score float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.assign != nil && (score > b.score || score == b.score && chain >= b.chain) {
+		return
+	}
+	b.assign = append(b.assign[:0], assign...)
+	b.score = score
+	b.chain = chain
+}
+
+// multiStartResult runChains的结果
+type multiStartResult struct {
+	Assign []int
+	Score  float64
+	// Chains 每条退火链的收敛情况，第i条链使用种子seed+i
+	Chains []ChainReport
+}
+
+// runChains 在chains个goroutine中并行运行相互独立的退火链，第i条链使用种子seed+i，返回所有链中目标函数最小的assign
+// 每条链只由迭代次数与温度决定何时停止，因此相同的seed得到相同的结果；
+// ctx结束时所有链在ctxCheckInterval次迭代内停止，返回已经找到的最优assign，此时的结果无法复现
+func runChains(ctx context.Context, chains int, seed int64, anneal annealFunc) *multiStartResult {
+	chains = max(chains, 1)
+	best := &sharedBest{}
+	res := &multiStartResult{Chains: make([]ChainReport, chains)}
+	var wg sync.WaitGroup
+	for i := 0; i < chains; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			chainSeed := seed + int64(i)
+			_, _, report := anneal(ctx, rand.New(rand.NewSource(chainSeed)), func(assign []int, score float64) {
+				best.offer(i, assign, score)
+			})
+			report.Seed = chainSeed
+			res.Chains[i] = report
+		}(i)
+	}
+	wg.Wait()
+	res.Assign, res.Score = best.assign, best.score
+	return res
+}
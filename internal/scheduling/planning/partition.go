@@ -0,0 +1,754 @@
+package planning
+
+import (
+	"cmp"
+	"container/heap"
+	"math"
+	"slices"
+
+	"github.com/SMALL-head/podGroup/internal/scheduling/model"
+)
+
+// 多层划分的参数
+const (
+	// partitionCoarsenTo 顶点数量不超过max(partitionCoarsenTo, 2*节点数量)时停止粗化
+	partitionCoarsenTo = 40
+	// partitionMaxLevels 最多粗化的层数
+	partitionMaxLevels = 20
+	// fmMaxNonImproving FM一轮中连续多少次移动没有使割边权重低于本轮最优值时结束本轮
+	fmMaxNonImproving = 50
+	// partitionEpsilon 比较浮点数时的容差
+	partitionEpsilon = 1e-9
+	// partitionImbalance 分区均衡的容差ε，每个分区的Pod数量不超过(1+ε)·Pod总数/k，k为期望使用的节点数量
+	partitionImbalance = 0.1
+)
+
+// pedge 划分图中的一条边，w为两个顶点之间双向通信权重之和
+type pedge struct {
+	to int
+	w  float64
+}
+
+// pgraph 多层划分中某一层的图，最细的一层每个顶点为一个Pod，粗化后每个顶点为多个Pod合并得到的顶点
+type pgraph struct {
+	cpu, mem []float64
+	// count 顶点包含的Pod数量
+	count []int
+	// groups 顶点包含的Pod所属的反亲和分组下标
+	groups [][]int
+	adj    [][]pedge
+}
+
+func (g *pgraph) size() int {
+	return len(g.count)
+}
+
+// newPodGraph 根据problem构造最细的一层划分图
+func newPodGraph(problem *Problem) *pgraph {
+	podSize := len(problem.Pods)
+	g := &pgraph{
+		cpu:    make([]float64, podSize),
+		mem:    make([]float64, podSize),
+		count:  make([]int, podSize),
+		groups: make([][]int, podSize),
+		adj:    make([][]pedge, podSize),
+	}
+	for i, pod := range problem.Pods {
+		g.cpu[i], g.mem[i], g.count[i] = pod.CPUReq, pod.MemReq, 1
+	}
+	for idx, group := range problem.Constraints.AntiAffinity {
+		for _, p := range group {
+			g.groups[p] = append(g.groups[p], idx)
+		}
+	}
+	for i := 0; i < podSize; i++ {
+		for j := i + 1; j < podSize; j++ {
+			if w := problem.PodDependencies.Get(i, j) + problem.PodDependencies.Get(j, i); w > 0 {
+				g.adj[i] = append(g.adj[i], pedge{to: j, w: w})
+				g.adj[j] = append(g.adj[j], pedge{to: i, w: w})
+			}
+		}
+	}
+	return g
+}
+
+// coarsen 使用heavy-edge matching将顶点两两合并，返回粗化后的图以及每个顶点合并到的顶点
+// 合并后的顶点必须能够放入最大的节点、不超过maxPodsPerNode，并且不包含同一个反亲和分组内的两个Pod
+func (g *pgraph) coarsen(maxCPU, maxMem float64, maxPods int) (*pgraph, []int) {
+	n := g.size()
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	// 较小的顶点优先合并，使粗化后的顶点大小尽量均匀
+	slices.SortStableFunc(order, func(a, b int) int { return cmp.Compare(g.count[a], g.count[b]) })
+
+	compatible := func(u, v int) bool {
+		return (maxCPU <= 0 || g.cpu[u]+g.cpu[v] <= maxCPU) && (maxMem <= 0 || g.mem[u]+g.mem[v] <= maxMem) &&
+			(maxPods <= 0 || g.count[u]+g.count[v] <= maxPods) &&
+			!slices.ContainsFunc(g.groups[u], func(group int) bool { return slices.Contains(g.groups[v], group) })
+	}
+	cmap := make([]int, n)
+	for i := range cmap {
+		cmap[i] = -1
+	}
+	coarse := &pgraph{}
+	for _, u := range order {
+		if cmap[u] >= 0 {
+			continue
+		}
+		match, bestW := -1, 0.0
+		for _, e := range g.adj[u] {
+			if cmap[e.to] < 0 && e.w > bestW && compatible(u, e.to) {
+				match, bestW = e.to, e.w
+			}
+		}
+		id := coarse.size()
+		cmap[u] = id
+		coarse.cpu = append(coarse.cpu, g.cpu[u])
+		coarse.mem = append(coarse.mem, g.mem[u])
+		coarse.count = append(coarse.count, g.count[u])
+		coarse.groups = append(coarse.groups, slices.Clone(g.groups[u]))
+		if match >= 0 {
+			cmap[match] = id
+			coarse.cpu[id] += g.cpu[match]
+			coarse.mem[id] += g.mem[match]
+			coarse.count[id] += g.count[match]
+			coarse.groups[id] = append(coarse.groups[id], g.groups[match]...)
+		}
+	}
+
+	// 合并边，两个端点合并到同一个顶点的边被丢弃
+	coarse.adj = make([][]pedge, coarse.size())
+	weight := make([]float64, coarse.size())
+	var touched []int
+	members := make([][]int, coarse.size())
+	for u := range cmap {
+		members[cmap[u]] = append(members[cmap[u]], u)
+	}
+	for c, us := range members {
+		touched = touched[:0]
+		for _, u := range us {
+			for _, e := range g.adj[u] {
+				if to := cmap[e.to]; to != c {
+					if weight[to] == 0 {
+						touched = append(touched, to)
+					}
+					weight[to] += e.w
+				}
+			}
+		}
+		slices.Sort(touched)
+		for _, to := range touched {
+			coarse.adj[c] = append(coarse.adj[c], pedge{to: to, w: weight[to]})
+			weight[to] = 0
+		}
+	}
+	return coarse, cmap
+}
+
+// partBalance 分区的均衡约束，放入分区与FM/KL移动时遵守，所有分区都无法满足时放宽，只保证节点容量与硬约束
+type partBalance struct {
+	// parts 最多使用的分区数量，即spec.nodeNum，未设置时为节点数量
+	parts int
+	// maxCount 每个分区最多包含的Pod数量，即ceil((1+partitionImbalance)·Pod总数/parts)
+	maxCount int
+}
+
+func newPartBalance(problem *Problem) partBalance {
+	parts := problem.NodeBalance
+	if parts <= 0 || parts > len(problem.Nodes) {
+		parts = len(problem.Nodes)
+	}
+	if parts == 0 {
+		return partBalance{}
+	}
+	return partBalance{
+		parts:    parts,
+		maxCount: int(math.Ceil((1 + partitionImbalance) * float64(len(problem.Pods)) / float64(parts))),
+	}
+}
+
+// partState 划分图中每个顶点所属的分区以及分区的负载，分区k的容量为节点k的剩余容量
+type partState struct {
+	g    *pgraph
+	part []int
+	// capCPU capMem 每个分区的容量，小于等于0表示不受限
+	capCPU, capMem []float64
+	maxPods        int
+	balance        partBalance
+	cpu, mem       []float64
+	count          []int
+	// used 包含顶点的分区数量
+	used int
+	// groups groups[k][group]为分区k中属于反亲和分组group的Pod数量
+	groups []map[int]int
+
+	// conn touched 计算顶点与各个分区之间连接权重时使用的缓冲区
+	conn    []float64
+	touched []int
+}
+
+func newPartState(g *pgraph, nodes []model.Node, maxPods int, balance partBalance) *partState {
+	k := len(nodes)
+	st := &partState{
+		g:       g,
+		part:    make([]int, g.size()),
+		capCPU:  make([]float64, k),
+		capMem:  make([]float64, k),
+		maxPods: maxPods,
+		balance: balance,
+		cpu:     make([]float64, k),
+		mem:     make([]float64, k),
+		count:   make([]int, k),
+		groups:  make([]map[int]int, k),
+		conn:    make([]float64, k),
+	}
+	for i, node := range nodes {
+		st.capCPU[i], st.capMem[i] = node.CPUCap, node.MemCap
+		st.groups[i] = make(map[int]int)
+	}
+	for v := range st.part {
+		st.part[v] = -1
+	}
+	return st
+}
+
+// fits 判断顶点v能否放入分区k，v本身已经位于分区k时不重复计算其负载
+func (st *partState) fits(v, k int) bool {
+	if st.part[v] == k {
+		return true
+	}
+	g := st.g
+	if st.capCPU[k] > 0 && st.cpu[k]+g.cpu[v] > st.capCPU[k]+partitionEpsilon ||
+		st.capMem[k] > 0 && st.mem[k]+g.mem[v] > st.capMem[k]+partitionEpsilon ||
+		st.maxPods > 0 && st.count[k]+g.count[v] > st.maxPods {
+		return false
+	}
+	for _, group := range g.groups[v] {
+		if st.groups[k][group] > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// balanced 判断顶点v放入分区k之后是否满足均衡约束，v本身已经位于分区k时返回true
+func (st *partState) balanced(v, k int) bool {
+	if st.part[v] == k || st.balance.parts <= 0 {
+		return true
+	}
+	if st.count[k] == 0 && st.used >= st.balance.parts {
+		return false
+	}
+	return st.count[k]+st.g.count[v] <= st.balance.maxCount
+}
+
+// move 将顶点v移动到分区k，k为-1表示移出所有分区
+func (st *partState) move(v, k int) {
+	g := st.g
+	if from := st.part[v]; from >= 0 {
+		st.cpu[from] -= g.cpu[v]
+		st.mem[from] -= g.mem[v]
+		st.count[from] -= g.count[v]
+		if st.count[from] == 0 {
+			st.used--
+		}
+		for _, group := range g.groups[v] {
+			st.groups[from][group]--
+		}
+	}
+	st.part[v] = k
+	if k >= 0 {
+		if st.count[k] == 0 {
+			st.used++
+		}
+		st.cpu[k] += g.cpu[v]
+		st.mem[k] += g.mem[v]
+		st.count[k] += g.count[v]
+		for _, group := range g.groups[v] {
+			st.groups[k][group]++
+		}
+	}
+}
+
+// connect 计算顶点v与各个分区之间的连接权重，写入st.conn，返回有连接的分区
+func (st *partState) connect(v int) []int {
+	for _, k := range st.touched {
+		st.conn[k] = 0
+	}
+	st.touched = st.touched[:0]
+	for _, e := range st.g.adj[v] {
+		if k := st.part[e.to]; k >= 0 {
+			if st.conn[k] == 0 {
+				st.touched = append(st.touched, k)
+			}
+			st.conn[k] += e.w
+		}
+	}
+	return st.touched
+}
+
+// bestMove 返回顶点v移动到其他有连接、并且满足均衡约束的分区时割边权重减少最多的分区以及减少的权重，没有可以移动的分区时返回false
+func (st *partState) bestMove(v int) (int, float64, bool) {
+	from := st.part[v]
+	touched := st.connect(v)
+	best, bestGain := -1, 0.0
+	for _, k := range touched {
+		if k == from || !st.fits(v, k) || !st.balanced(v, k) {
+			continue
+		}
+		gain := st.conn[k] - st.conn[from]
+		if best < 0 || gain > bestGain || gain == bestGain && k < best {
+			best, bestGain = k, gain
+		}
+	}
+	return best, bestGain, best >= 0
+}
+
+// place 将尚未分配分区的顶点依次放入连接权重最大的分区，连接权重相同时优先已经有顶点的分区，
+// order为放入的顺序；优先选择满足均衡约束的分区，都不满足时只要求放得下，没有分区放得下的顶点保持未分配
+func (st *partState) place(order []int) {
+	for _, v := range order {
+		if st.part[v] >= 0 {
+			continue
+		}
+		st.connect(v)
+		best := -1
+		for _, balanced := range []bool{true, false} {
+			for k := range st.capCPU {
+				if !st.fits(v, k) || balanced && !st.balanced(v, k) {
+					continue
+				}
+				if best < 0 || st.conn[k] > st.conn[best] ||
+					st.conn[k] == st.conn[best] && st.count[k] > 0 && st.count[best] == 0 {
+					best = k
+				}
+			}
+			if best >= 0 {
+				break
+			}
+		}
+		if best >= 0 {
+			st.move(v, best)
+		}
+	}
+}
+
+// fmMove 一次FM移动的候选，version用于丢弃过期的候选
+type fmMove struct {
+	v, to   int
+	gain    float64
+	version int
+}
+
+type fmHeap []fmMove
+
+func (h fmHeap) Len() int { return len(h) }
+func (h fmHeap) Less(i, j int) bool {
+	return h[i].gain > h[j].gain || h[i].gain == h[j].gain && h[i].v < h[j].v
+}
+func (h fmHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *fmHeap) Push(x any)   { *h = append(*h, x.(fmMove)) }
+func (h *fmHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// refine 交替使用FM移动与KL交换降低割边权重，某一轮两者都没有降低割边权重或者达到passes轮时结束
+func (st *partState) refine(passes int) {
+	for pass := 0; pass < passes; pass++ {
+		if !st.fmPass() && !st.swapPass() {
+			return
+		}
+	}
+}
+
+// fmPass 使用Fiduccia–Mattheyses算法降低割边权重，每个顶点最多移动一次，允许暂时增加割边权重的移动，
+// 结束后回退到割边权重最低的位置，返回割边权重是否降低
+func (st *partState) fmPass() bool {
+	n := st.g.size()
+	locked := make([]bool, n)
+	version := make([]int, n)
+	type step struct{ v, from int }
+	h := &fmHeap{}
+	push := func(v int) {
+		version[v]++
+		if to, gain, ok := st.bestMove(v); ok {
+			heap.Push(h, fmMove{v: v, to: to, gain: gain, version: version[v]})
+		}
+	}
+	for v := 0; v < n; v++ {
+		if st.part[v] >= 0 {
+			push(v)
+		}
+	}
+	var steps []step
+	total, bestTotal, bestLen, nonImproving := 0.0, 0.0, 0, 0
+	for h.Len() > 0 && nonImproving < fmMaxNonImproving {
+		m := heap.Pop(h).(fmMove)
+		if locked[m.v] || m.version != version[m.v] {
+			continue
+		}
+		// 其他顶点的移动可能改变了目标分区的负载，重新计算
+		to, gain, ok := st.bestMove(m.v)
+		if !ok {
+			continue
+		}
+		if to != m.to || gain < m.gain-partitionEpsilon {
+			heap.Push(h, fmMove{v: m.v, to: to, gain: gain, version: version[m.v]})
+			continue
+		}
+		steps = append(steps, step{v: m.v, from: st.part[m.v]})
+		st.move(m.v, to)
+		locked[m.v] = true
+		total += gain
+		if total > bestTotal+partitionEpsilon {
+			bestTotal, bestLen, nonImproving = total, len(steps), 0
+		} else {
+			nonImproving++
+		}
+		for _, e := range st.g.adj[m.v] {
+			if !locked[e.to] && st.part[e.to] >= 0 {
+				push(e.to)
+			}
+		}
+	}
+	for i := len(steps) - 1; i >= bestLen; i-- {
+		st.move(steps[i].v, steps[i].from)
+	}
+	return bestTotal > partitionEpsilon
+}
+
+// swapPass 依次尝试将每个顶点与其有连接的分区中的一个顶点交换（Kernighan–Lin），
+// 用于两个分区的容量都已经用满、单个顶点无法移动的情况，返回割边权重是否降低
+func (st *partState) swapPass() bool {
+	members := make([][]int, len(st.capCPU))
+	for v, k := range st.part {
+		if k >= 0 {
+			members[k] = append(members[k], v)
+		}
+	}
+	// gainTo 顶点v从所在分区移动到分区k时割边权重减少的值，以及v与顶点u之间的边权
+	gainTo := func(v, k, u int) (float64, float64) {
+		from := st.part[v]
+		gain, w := 0.0, 0.0
+		for _, e := range st.g.adj[v] {
+			switch st.part[e.to] {
+			case k:
+				gain += e.w
+			case from:
+				gain -= e.w
+			}
+			if e.to == u {
+				w = e.w
+			}
+		}
+		return gain, w
+	}
+	swapFits := func(v, u int) bool {
+		a, b := st.part[v], st.part[u]
+		st.move(v, -1)
+		st.move(u, -1)
+		ok := st.fits(v, b) && st.fits(u, a) && st.balanced(v, b) && st.balanced(u, a)
+		st.move(v, a)
+		st.move(u, b)
+		return ok
+	}
+	improved := false
+	for v, a := range st.part {
+		if a < 0 {
+			continue
+		}
+		targets := slices.Clone(st.connect(v))
+		for _, b := range targets {
+			if b == a || st.part[v] != a {
+				continue
+			}
+			gv, _ := gainTo(v, b, -1)
+			best, bestGain := -1, partitionEpsilon
+			for _, u := range members[b] {
+				gu, w := gainTo(u, a, v)
+				if gain := gv + gu - 2*w; gain > bestGain && swapFits(v, u) {
+					best, bestGain = u, gain
+				}
+			}
+			if best < 0 {
+				continue
+			}
+			st.move(v, b)
+			st.move(best, a)
+			members[a][slices.Index(members[a], v)] = best
+			members[b][slices.Index(members[b], best)] = v
+			improved = true
+			break
+		}
+	}
+	return improved
+}
+
+// placementOrder 返回放入分区的顺序，连接权重之和大的顶点优先，其次是资源请求大的顶点
+func placementOrder(g *pgraph) []int {
+	degree := make([]float64, g.size())
+	order := make([]int, g.size())
+	for v := range order {
+		order[v] = v
+		for _, e := range g.adj[v] {
+			degree[v] += e.w
+		}
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		return cmp.Or(cmp.Compare(degree[b], degree[a]), cmp.Compare(g.cpu[b], g.cpu[a]), cmp.Compare(g.mem[b], g.mem[a]))
+	})
+	return order
+}
+
+// MultilevelPartitionAssign 使用多层图划分求解placement，适用于成员Pod数量较多的PodGroup
+//  1. 以PodDependencies为边权构造无向图，使用heavy-edge matching逐层粗化
+//  2. 在最粗的一层上按照节点容量贪心地划分为与节点数量相同的分区，分区k的容量为节点k的剩余容量，
+//     最多使用spec.nodeNum个分区，每个分区的Pod数量不超过(1+ε)·Pod总数/nodeNum，nodeNum未设置时为节点数量
+//  3. 逐层还原，每一层使用FM算法在满足容量、硬约束与均衡约束的前提下降低分区之间的割边权重
+//  4. 根据节点之间的延迟交换分区对应的节点，降低分区之间通信的延迟代价
+//  5. 最后在Pod粒度上将Pod移动到使延迟代价降低的节点
+//
+// 返回的assign[i]为Pod i分配的节点下标，没有节点放得下的Pod为-1
+func MultilevelPartitionAssign(problem *Problem, passes int) []int {
+	nodeSize := len(problem.Nodes)
+	maxPods := problem.Constraints.MaxPodsPerNode
+	balance := newPartBalance(problem)
+	// 粗化后的顶点不超过均衡约束允许的分区大小
+	coarsenPods := maxPods
+	if balance.maxCount > 0 && (coarsenPods <= 0 || balance.maxCount < coarsenPods) {
+		coarsenPods = balance.maxCount
+	}
+	maxCPU, maxMem := 0.0, 0.0
+	for _, node := range problem.Nodes {
+		// 容量未知的节点不限制合并后的顶点大小
+		if node.CPUCap <= 0 || maxCPU < 0 {
+			maxCPU = -1
+		} else {
+			maxCPU = max(maxCPU, node.CPUCap)
+		}
+		if node.MemCap <= 0 || maxMem < 0 {
+			maxMem = -1
+		} else {
+			maxMem = max(maxMem, node.MemCap)
+		}
+	}
+
+	// 1. 粗化
+	graphs := []*pgraph{newPodGraph(problem)}
+	var cmaps [][]int
+	for len(graphs) <= partitionMaxLevels {
+		g := graphs[len(graphs)-1]
+		if g.size() <= max(partitionCoarsenTo, 2*nodeSize) {
+			break
+		}
+		coarse, cmap := g.coarsen(maxCPU, maxMem, coarsenPods)
+		if float64(coarse.size()) > 0.95*float64(g.size()) {
+			break
+		}
+		graphs = append(graphs, coarse)
+		cmaps = append(cmaps, cmap)
+	}
+
+	// 2. 初始划分
+	level := len(graphs) - 1
+	st := newPartState(graphs[level], problem.Nodes, maxPods, balance)
+	st.place(placementOrder(graphs[level]))
+	st.refine(passes)
+
+	// 3. 逐层还原并优化
+	for level--; level >= 0; level-- {
+		fine := newPartState(graphs[level], problem.Nodes, maxPods, balance)
+		for v, c := range cmaps[level] {
+			if k := st.part[c]; k >= 0 {
+				fine.move(v, k)
+			}
+		}
+		st = fine
+		st.place(placementOrder(graphs[level]))
+		st.refine(passes)
+	}
+
+	// 4. 分区映射到节点
+	perm := mapPartitions(problem, st)
+	nodeState := newPartState(graphs[0], problem.Nodes, maxPods, balance)
+	for p, k := range st.part {
+		if k >= 0 {
+			nodeState.move(p, perm[k])
+		}
+	}
+
+	// 5. Pod粒度的延迟优化
+	refineLatency(problem, nodeState, passes)
+	return nodeState.part
+}
+
+// mapPartitions 返回每个分区对应的节点下标，先由greedyMapping得到初始的对应关系，
+// 再反复交换两个分区对应的节点，直到不存在使分区之间通信的延迟代价降低、并且两个分区都放得下的交换
+func mapPartitions(problem *Problem, st *partState) []int {
+	k := len(problem.Nodes)
+	weight := make([][]float64, k)
+	for a := range weight {
+		weight[a] = make([]float64, k)
+	}
+	for i, a := range st.part {
+		if a < 0 {
+			continue
+		}
+		for j, b := range st.part {
+			if b >= 0 {
+				weight[a][b] += problem.PodDependencies.Get(i, j)
+			}
+		}
+	}
+	fitsNode := func(a, node int) bool {
+		n := problem.Nodes[node]
+		return (n.CPUCap <= 0 || st.cpu[a] <= n.CPUCap+partitionEpsilon) && (n.MemCap <= 0 || st.mem[a] <= n.MemCap+partitionEpsilon)
+	}
+	perm := greedyMapping(problem, st, weight, fitsNode)
+	lat := &problem.NodeLatencies
+	// cost 分区a与b参与的通信的延迟代价，每一对分区之间的通信只计一次
+	cost := func(a, b int) float64 {
+		res := 0.0
+		for x := 0; x < k; x++ {
+			res += weight[a][x] * lat.Get(perm[a], perm[x])
+			if x != a {
+				res += weight[x][a]*lat.Get(perm[x], perm[a]) + weight[b][x]*lat.Get(perm[b], perm[x])
+			}
+			if x != a && x != b {
+				res += weight[x][b] * lat.Get(perm[x], perm[b])
+			}
+		}
+		return res
+	}
+	for improved := true; improved; {
+		improved = false
+		for a := 0; a < k; a++ {
+			if st.count[a] == 0 {
+				continue
+			}
+			for b := 0; b < k; b++ {
+				if b == a || st.count[b] > 0 && b < a || !fitsNode(a, perm[b]) || !fitsNode(b, perm[a]) {
+					continue
+				}
+				before := cost(a, b)
+				perm[a], perm[b] = perm[b], perm[a]
+				if cost(a, b) < before-partitionEpsilon {
+					improved = true
+				} else {
+					perm[a], perm[b] = perm[b], perm[a]
+				}
+			}
+		}
+	}
+	return perm
+}
+
+// greedyMapping 依次将与已经对应到节点的分区通信权重最大的分区对应到使延迟代价最低、并且放得下的空闲节点，
+// 第一个分区对应到其他节点延迟之和最低的节点，某个分区没有放得下的节点时分区k对应节点k
+func greedyMapping(problem *Problem, st *partState, weight [][]float64, fitsNode func(a, node int) bool) []int {
+	k := len(problem.Nodes)
+	lat := &problem.NodeLatencies
+	perm := make([]int, k)
+	for a := range perm {
+		perm[a] = -1
+	}
+	used := make([]bool, k)
+	// attached 每个分区与已经对应到节点的分区之间的通信权重
+	attached := make([]float64, k)
+	for {
+		next := -1
+		for a := 0; a < k; a++ {
+			if perm[a] < 0 && st.count[a] > 0 && (next < 0 || attached[a] > attached[next]) {
+				next = a
+			}
+		}
+		if next < 0 {
+			break
+		}
+		best, bestCost := -1, 0.0
+		for node := 0; node < k; node++ {
+			if used[node] || !fitsNode(next, node) {
+				continue
+			}
+			cost := 0.0
+			for b := 0; b < k; b++ {
+				if attached[next] == 0 {
+					// 第一个分区以及与已经对应的分区没有通信的分区，选择到其他节点延迟之和最低的节点
+					cost += lat.Get(node, b)
+				} else if perm[b] >= 0 {
+					cost += weight[next][b]*lat.Get(node, perm[b]) + weight[b][next]*lat.Get(perm[b], node)
+				}
+			}
+			if best < 0 || cost < bestCost {
+				best, bestCost = node, cost
+			}
+		}
+		if best < 0 {
+			for a := range perm {
+				perm[a] = a
+			}
+			return perm
+		}
+		perm[next], used[best] = best, true
+		for a := 0; a < k; a++ {
+			attached[a] += weight[a][next] + weight[next][a]
+		}
+	}
+	// 空的分区对应剩余的节点
+	node := 0
+	for a := range perm {
+		if perm[a] >= 0 {
+			continue
+		}
+		for used[node] {
+			node++
+		}
+		perm[a], used[node] = node, true
+	}
+	return perm
+}
+
+// refineLatency 在Pod粒度上将Pod移动到使延迟代价降低最多、放得下并且满足硬约束与均衡约束的节点，
+// 某一轮没有任何移动或者达到passes轮时结束，st中分区k即为节点k
+func refineLatency(problem *Problem, st *partState, passes int) {
+	lat := &problem.NodeLatencies
+	dep := &problem.PodDependencies
+	contribution := func(p, n int) float64 {
+		res := 0.0
+		for _, e := range st.g.adj[p] {
+			if m := st.part[e.to]; m >= 0 {
+				res += dep.Get(p, e.to)*lat.Get(n, m) + dep.Get(e.to, p)*lat.Get(m, n)
+			}
+		}
+		return res
+	}
+	for pass := 0; pass < passes; pass++ {
+		moved := false
+		for p, from := range st.part {
+			if from < 0 || len(st.g.adj[p]) == 0 {
+				continue
+			}
+			best, bestCost := from, contribution(p, from)
+			for n := range problem.Nodes {
+				if n == from || !st.fits(p, n) || !st.balanced(p, n) {
+					continue
+				}
+				if c := contribution(p, n); c < bestCost-partitionEpsilon {
+					best, bestCost = n, c
+				}
+			}
+			if best != from {
+				st.move(p, best)
+				moved = true
+			}
+		}
+		if !moved {
+			return
+		}
+	}
+}
@@ -1,18 +1,18 @@
 package planning
 
 import (
-	"fmt"
+	"context"
 	"math"
 	"math/rand"
 	"path/filepath"
 	"slices"
-	"time"
 
 	"github.com/SMALL-head/podGroup/internal/scheduling/model"
 	"gonum.org/v1/plot"
 	"gonum.org/v1/plot/plotter"
 	"gonum.org/v1/plot/plotutil"
 	"gonum.org/v1/plot/vg"
+	"k8s.io/klog/v2"
 )
 
 const (
@@ -21,7 +21,7 @@ const (
 
 // computeTotalLatency计算当前给定状态的延迟分数
 // assign[i]表示Pod i 被分配到的节点编号
-// dependencies[i][j]表示Pod i 和 Pod j 之间的通信需求，可以假设该数组是一个对称矩阵，且对角线为0（pod依赖关系不存在自环）
+// dependencies[i][j]表示Pod i 发往 Pod j 的通信需求，双向依赖时矩阵对称，且对角线为0（pod依赖关系不存在自环）
 func computeTotalLatency(assign []int, latencies model.NodeLatencies, dependencies model.PodDependencies, size int) (score float64) {
 	for i := 0; i < size; i++ {
 		for j := 0; j < size; j++ {
@@ -48,8 +48,8 @@ func computeResourceBalancePenalty(assign []int, podModel []model.PodModel, node
 
 	// 计算惩罚分数
 	for i := range nodeStatus {
-		cpuUsageRatio := usedCPU[i] / nodeStatus[i].CPUCap
-		memUsageRatio := usedMem[i] / nodeStatus[i].MemCap
+		cpuUsageRatio := usageRatio(usedCPU[i], nodeStatus[i].CPUCap)
+		memUsageRatio := usageRatio(usedMem[i], nodeStatus[i].MemCap)
 
 		if cpuUsageRatio > 1 || memUsageRatio > 1 {
 			ok = false
@@ -63,6 +63,14 @@ func computeResourceBalancePenalty(assign []int, podModel []model.PodModel, node
 	return
 }
 
+// usageRatio 计算资源使用率，容量未知(<=0)时不计入该维度
+func usageRatio(used, capacity float64) float64 {
+	if capacity <= 0 {
+		return 0
+	}
+	return used / capacity
+}
+
 func computeAllocBalancePenalty(assign []int, podModel []model.PodModel, nodeStatus []model.Node, latencyMap model.NodeLatencies) (score float64) {
 	nodeSize := len(nodeStatus)
 	lw := make([]float64, nodeSize)
@@ -88,7 +96,7 @@ func computeAllocBalancePenalty(assign []int, podModel []model.PodModel, nodeSta
 
 	miu := 0.0
 	for i := 0; i < nodeSize; i++ {
-		ratio := (resourceCondition[i].usedCPU/resourceCondition[i].CPUCap + resourceCondition[i].usedMem/resourceCondition[i].MemCap) / 2
+		ratio := (usageRatio(resourceCondition[i].usedCPU, resourceCondition[i].CPUCap) + usageRatio(resourceCondition[i].usedMem, resourceCondition[i].MemCap)) / 2
 		miu += ratio / lw[i]
 	}
 	miu /= float64(nodeSize)
@@ -98,15 +106,28 @@ func computeAllocBalancePenalty(assign []int, podModel []model.PodModel, nodeSta
 	return
 }
 
+// constraintPenalty 违反资源或者硬约束时目标函数的值，违反约束的次数越多值越大，使搜索向违反更少的方向移动
+func constraintPenalty(constraints *Constraints, assign []int) float64 {
+	if constraints == nil {
+		return ResourceLimitConstraint
+	}
+	return ResourceLimitConstraint + float64(constraints.Violations(assign))
+}
+
+// violates 判断assign是否违反硬约束，constraints为nil时不检查
+func violates(constraints *Constraints, assign []int) bool {
+	return constraints != nil && constraints.Violations(assign) > 0
+}
+
 // objectiveFunc 计算目标方程的值
 func objectiveFunc(alpha float64, beta float64,
 	latenciesMap model.NodeLatencies, podDependencies model.PodDependencies,
-	pods []model.PodModel, nodeStatuses []model.Node,
+	pods []model.PodModel, nodeStatuses []model.Node, constraints *Constraints,
 	assign []int, podSize int) float64 {
 	l := computeTotalLatency(assign, latenciesMap, podDependencies, podSize)
 	p, ok := computeResourceBalancePenalty(assign, pods, nodeStatuses)
-	if !ok {
-		return ResourceLimitConstraint
+	if !ok || violates(constraints, assign) {
+		return constraintPenalty(constraints, assign)
 	}
 	//return l * p
 	return alpha*l + beta*p
@@ -114,18 +135,18 @@ func objectiveFunc(alpha float64, beta float64,
 
 func objectFunc2(alpha float64, beta float64,
 	latenciesMap model.NodeLatencies, podDependencies model.PodDependencies,
-	pods []model.PodModel, nodeStatuses []model.Node,
+	pods []model.PodModel, nodeStatuses []model.Node, constraints *Constraints,
 	assign []int, podSize int,
 	latencyMin, latencyMax float64, imbalanceMin, imbalanceMax float64,
 ) (float64, int) {
 	l := computeTotalLatency(assign, latenciesMap, podDependencies, podSize)
 	_, ok := computeResourceBalancePenalty(assign, pods, nodeStatuses)
-	if !ok {
-		return ResourceLimitConstraint, 0
+	if !ok || violates(constraints, assign) {
+		return constraintPenalty(constraints, assign), 0
 	}
 	p2 := computeAllocBalancePenalty(assign, pods, nodeStatuses, latenciesMap)
 
-	fmt.Printf("assign: %v, latency factor: %f, imbalance factor: %f\n", assign, l, p2)
+	klog.V(5).Infof("assign: %v, latency factor: %f, imbalance factor: %f", assign, l, p2)
 
 	// min-max归一化
 	// 避免除0
@@ -137,41 +158,55 @@ func objectFunc2(alpha float64, beta float64,
 	}
 	lOne := (l - latencyMin) / (latencyMax - latencyMin)
 	pOne := (p2 - imbalanceMin) / (imbalanceMax - imbalanceMin)
-	fmt.Printf("assign: %v, lOne factor: %f, pOne: %f\n", assign, lOne, pOne)
+	klog.V(5).Infof("assign: %v, lOne factor: %f, pOne: %f", assign, lOne, pOne)
 	var mode int
 	if lOne*alpha > pOne*beta {
 		mode = 1 // 延迟占比更大
 	} else {
 		mode = 2 // 资源均衡占比更大
 	}
-	return lOne + pOne, mode
+	return alpha*lOne + beta*pOne, mode
 }
 
 // FindOptimalAssign 暴力枚举所有可能的assign，返回目标函数最小的分配方案
-func FindOptimalAssign(
+// constraints不为nil时，违反约束的assign与超出节点资源的assign一样会被施加ResourceLimitConstraint惩罚
+// 枚举过程中ctx结束时停止并返回ctx.Err()，此时没有完成枚举，不返回assign
+func FindOptimalAssign(ctx context.Context,
 	alpha float64, beta float64,
 	latenciesMap model.NodeLatencies, podDependencies model.PodDependencies,
-	pods []model.PodModel, nodeStatuses []model.Node,
-) (bestAssign []int, bestScore float64) {
+	pods []model.PodModel, nodeStatuses []model.Node, constraints *Constraints,
+) (bestAssign []int, bestScore float64, err error) {
 	podSize := len(pods)
 	nodeSize := len(nodeStatuses)
 	if podSize == 0 || nodeSize == 0 {
-		return nil, 0
+		return nil, 0, nil
 	}
 
 	assign := make([]int, podSize)
 	bestAssign = make([]int, podSize)
 	bestScore = 1e100 // 足够大的初始值
 
+	evaluated := 0
 	var dfs func(idx int)
 	dfs = func(idx int) {
+		if err != nil {
+			return
+		}
 		if idx == podSize {
-			curScore := objectiveFunc(0.4, 0.6, latenciesMap, podDependencies, pods, nodeStatuses, assign, podSize)
-			bestScore = min(curScore, bestScore)
+			if evaluated++; evaluated%1024 == 0 {
+				if err = ctx.Err(); err != nil {
+					return
+				}
+			}
+			curScore := objectiveFunc(alpha, beta, latenciesMap, podDependencies, pods, nodeStatuses, constraints, assign, podSize)
+			if curScore < bestScore {
+				bestScore = curScore
+				copy(bestAssign, assign)
+			}
 			return
 		}
 
-		for i, _ := range nodeStatuses {
+		for i := range nodeStatuses {
 			assign[idx] = i
 			dfs(idx + 1)
 		}
@@ -179,33 +214,55 @@ func FindOptimalAssign(
 	}
 
 	dfs(0)
-	return bestAssign, bestScore
+	if err != nil {
+		return nil, 0, err
+	}
+	return bestAssign, bestScore, nil
 }
 
-// SimulatedAnnealingAssign 使用模拟退火算法搜索局部最优assign
+// SimulatedAnnealingAssign 使用模拟退火算法搜索局部最优assign，constraints的含义与FindOptimalAssign相同
+// 所有随机数取自r，相同的输入与相同种子的r得到相同的结果
 func SimulatedAnnealingAssign(
 	alpha float64, beta float64,
 	latenciesMap model.NodeLatencies, podDependencies model.PodDependencies,
-	pods []model.PodModel, nodeStatuses []model.Node,
+	pods []model.PodModel, nodeStatuses []model.Node, constraints *Constraints,
 	maxIter int, initTemp, finalTemp, coolingRate float64,
-	debugMode bool,
+	r *rand.Rand, debugMode bool,
 ) (bestAssign []int, bestScore float64) {
+	bestAssign, bestScore, _ = simulatedAnnealingChain(context.Background(), alpha, beta,
+		latenciesMap, podDependencies, pods, nodeStatuses, constraints,
+		maxIter, initTemp, finalTemp, coolingRate, r, nil, debugMode)
+	return
+}
+
+// simulatedAnnealingChain 运行一条SimulatedAnnealingAssign退火链，ctx结束时停止并返回已经找到的最优assign
+// improve不为nil时，每次找到更优的assign都会调用improve，assign在调用之后会被修改，需要复制后保存
+func simulatedAnnealingChain(ctx context.Context,
+	alpha float64, beta float64,
+	latenciesMap model.NodeLatencies, podDependencies model.PodDependencies,
+	pods []model.PodModel, nodeStatuses []model.Node, constraints *Constraints,
+	maxIter int, initTemp, finalTemp, coolingRate float64,
+	r *rand.Rand, improve func(assign []int, score float64), debugMode bool,
+) (bestAssign []int, bestScore float64, report ChainReport) {
 	podSize := len(pods)
 	nodeSize := len(nodeStatuses)
 	if podSize == 0 || nodeSize == 0 {
-		return nil, 0
+		return nil, 0, ChainReport{StopReason: ChainCooled}
 	}
 
-	rand.Seed(time.Now().UnixNano())
 	// 初始化assign
 	assign := make([]int, podSize)
 	for i := range assign {
-		assign[i] = rand.Intn(nodeSize)
+		assign[i] = r.Intn(nodeSize)
 	}
 	bestAssign = make([]int, podSize)
 	copy(bestAssign, assign)
-	bestScore = objectiveFunc(alpha, beta, latenciesMap, podDependencies, pods, nodeStatuses, assign, podSize)
+	state := newEvalState(latenciesMap, podDependencies, pods, nodeStatuses, constraints, assign)
+	bestScore = state.objective(alpha, beta)
 	currScore := bestScore
+	if improve != nil {
+		improve(bestAssign, bestScore)
+	}
 
 	temp := initTemp
 
@@ -214,18 +271,17 @@ func SimulatedAnnealingAssign(
 		scoreCurve = append(scoreCurve, currScore)
 	}
 
-	for iter := 0; iter < maxIter && temp > finalTemp; iter++ {
-		// 生成新解：随机选一个Pod，分配到另一个Node
-		newAssign := make([]int, podSize)
-		copy(newAssign, assign)
-		podIdx := rand.Intn(podSize)
-		newNode := rand.Intn(nodeSize)
-		for newNode == assign[podIdx] && nodeSize > 1 {
-			newNode = rand.Intn(nodeSize)
+	iter := 0
+	var stopErr error
+	for ; iter < maxIter && temp > finalTemp; iter++ {
+		if iter%ctxCheckInterval == 0 {
+			if stopErr = ctx.Err(); stopErr != nil {
+				break
+			}
 		}
-		newAssign[podIdx] = newNode
-
-		newScore := objectiveFunc(alpha, beta, latenciesMap, podDependencies, pods, nodeStatuses, newAssign, podSize)
+		// 生成新解：随机选一个Pod，分配到另一个Node，不接受时撤销
+		state.move(randomMove(r, state.assign, nodeSize))
+		newScore := state.objective(alpha, beta)
 		delta := newScore - currScore
 
 		accept := false
@@ -233,18 +289,23 @@ func SimulatedAnnealingAssign(
 			accept = true
 		} else {
 			prob := math.Exp(-delta / temp)
-			if rand.Float64() < prob {
+			if r.Float64() < prob {
 				accept = true
 			}
 		}
 
 		if accept {
-			copy(assign, newAssign)
 			currScore = newScore
 			if currScore < bestScore {
 				bestScore = currScore
-				copy(bestAssign, assign)
+				copy(bestAssign, state.assign)
+				report.BestIteration = iter + 1
+				if improve != nil {
+					improve(bestAssign, bestScore)
+				}
 			}
+		} else {
+			state.undo()
 		}
 
 		if debugMode {
@@ -258,62 +319,80 @@ func SimulatedAnnealingAssign(
 		_ = plotScoreCurve(scoreCurve)
 	}
 
-	return bestAssign, bestScore
+	report.finish(iter, bestScore, stopErr, temp > finalTemp)
+	return bestAssign, bestScore, report
 }
 
 // RelativeImprovementAssign 使用baseline归一化目标函数，返回相对改进���优的分配方案
+// constraints的含义与FindOptimalAssign相同，所有随机数取自r
 func RelativeImprovementAssign(
 	alpha float64, beta float64,
 	latenciesMap model.NodeLatencies, podDependencies model.PodDependencies,
-	pods []model.PodModel, nodeStatuses []model.Node,
+	pods []model.PodModel, nodeStatuses []model.Node, constraints *Constraints,
 	maxIter int, initTemp, finalTemp, coolingRate float64,
-	debugMode bool) (bestAssign []int, bestScore float64) {
+	r *rand.Rand, debugMode bool) (bestAssign []int, bestScore float64) {
+	bestAssign, bestScore, _ = relativeImprovementChain(context.Background(), alpha, beta,
+		latenciesMap, podDependencies, pods, nodeStatuses, constraints,
+		maxIter, initTemp, finalTemp, coolingRate, r, nil, debugMode)
+	return
+}
+
+// relativeImprovementChain 运行一条RelativeImprovementAssign退火链，ctx与improve的含义与simulatedAnnealingChain相同
+func relativeImprovementChain(ctx context.Context,
+	alpha float64, beta float64,
+	latenciesMap model.NodeLatencies, podDependencies model.PodDependencies,
+	pods []model.PodModel, nodeStatuses []model.Node, constraints *Constraints,
+	maxIter int, initTemp, finalTemp, coolingRate float64,
+	r *rand.Rand, improve func(assign []int, score float64), debugMode bool,
+) (bestAssign []int, bestScore float64, report ChainReport) {
 	podSize := len(pods)
 	nodeSize := len(nodeStatuses)
 	if podSize == 0 || nodeSize == 0 {
-		return nil, 0
+		return nil, 0, ChainReport{StopReason: ChainCooled}
 	}
 	laMin, laMax := computeLatencyMinMax(latenciesMap, podDependencies)
 	pMin, pMax := computePenaltyMinMax(pods, nodeStatuses, latenciesMap)
 
-	rand.Seed(time.Now().UnixNano())
 	// 初始化assign
 	assign := make([]int, podSize)
 	for i := range assign {
-		assign[i] = rand.Intn(nodeSize)
+		assign[i] = r.Intn(nodeSize)
 	}
-	assign[3] = 4
-	assign[4] = 4
-	assign[6] = 4
-	assign[7] = 4
 	bestAssign = make([]int, podSize)
 	copy(bestAssign, assign)
-	bestScore, mode := objectFunc2(alpha, beta, latenciesMap, podDependencies, pods, nodeStatuses, assign, podSize, laMin, laMax, pMin, pMax)
+	state := newEvalState(latenciesMap, podDependencies, pods, nodeStatuses, constraints, assign)
+	order := newHeuristicOrder(state, podDependencies)
+	bestScore, mode := state.normalizedObjective(alpha, beta, laMin, laMax, pMin, pMax)
 	currScore := bestScore
+	if improve != nil {
+		improve(bestAssign, bestScore)
+	}
 
 	temp := initTemp
 
 	var scoreCurve []float64
 	repeatTime := 0
 
-	for iter := 0; iter < maxIter && temp > finalTemp; iter++ {
+	iter := 0
+	var stopErr error
+	for ; iter < maxIter && temp > finalTemp; iter++ {
+		if iter%ctxCheckInterval == 0 {
+			if stopErr = ctx.Err(); stopErr != nil {
+				break
+			}
+		}
 		// 温度高于0.4 * initTemp时，完全随机搜索
-		var newAssign []int
+		var podIdx, nodeIdx int
 		if repeatTime > 3 {
 			repeatTime = 0
-			newAssign = heuristicAssign(assign, latenciesMap, podDependencies, pods, nodeStatuses, mode)
+			podIdx, nodeIdx = heuristicMove(r, state, order, mode)
 		} else if temp > 0.3*initTemp {
-			newAssign = randomAssign(assign, nodeStatuses)
+			podIdx, nodeIdx = randomMove(r, state.assign, nodeSize)
 		} else {
-			newAssign = heuristicAssign(assign, latenciesMap, podDependencies, pods, nodeStatuses, mode)
-		}
-		var newScore float64
-		var m int
-		if temp > 0.4*initTemp {
-			newScore, m = objectFunc2(0.7, 0.3, latenciesMap, podDependencies, pods, nodeStatuses, newAssign, podSize, laMin, laMax, pMin, pMax)
-		} else {
-			newScore, m = objectFunc2(0.5, 0.6, latenciesMap, podDependencies, pods, nodeStatuses, newAssign, podSize, laMin, laMax, pMin, pMax)
+			podIdx, nodeIdx = heuristicMove(r, state, order, mode)
 		}
+		state.move(podIdx, nodeIdx)
+		newScore, m := state.normalizedObjective(alpha, beta, laMin, laMax, pMin, pMax)
 		mode = m
 		delta := newScore - currScore
 
@@ -322,19 +401,23 @@ func RelativeImprovementAssign(
 			accept = true
 		} else {
 			prob := math.Exp(-delta / temp)
-			if rand.Float64() < prob {
+			if r.Float64() < prob {
 				accept = true
 			}
 		}
 
 		if accept {
-			copy(assign, newAssign)
 			currScore = newScore
 			if currScore < bestScore {
 				bestScore = currScore
-				copy(bestAssign, assign)
+				copy(bestAssign, state.assign)
+				report.BestIteration = iter + 1
+				if improve != nil {
+					improve(bestAssign, bestScore)
+				}
 			}
 		} else {
+			state.undo()
 			repeatTime++
 		}
 
@@ -349,7 +432,8 @@ func RelativeImprovementAssign(
 		_ = plotScoreCurve(scoreCurve)
 	}
 
-	return bestAssign, bestScore
+	report.finish(iter, bestScore, stopErr, temp > finalTemp)
+	return bestAssign, bestScore, report
 }
 
 func computeLatencyMinMax(latenciesMap model.NodeLatencies, pods model.PodDependencies) (mi, max float64) {
@@ -362,13 +446,14 @@ func computeLatencyMinMax(latenciesMap model.NodeLatencies, pods model.PodDepend
 		}
 	}
 
-	// 统计pod中边之和
+	// 统计pod中边之和，与computeTotalLatency保持一致，两个方向的权重各计一半
 	edgeSum := float64(0)
 	for i := range pods {
-		for j := i + 1; j < len(pods); j++ {
+		for j := range pods[i] {
 			edgeSum += pods[i][j]
 		}
 	}
+	edgeSum /= 2
 
 	return 0, edgeSum * maxLatency / 1.5
 }
@@ -376,7 +461,7 @@ func computeLatencyMinMax(latenciesMap model.NodeLatencies, pods model.PodDepend
 func computePenaltyMinMax(pods []model.PodModel, nodes []model.Node, latencyMap model.NodeLatencies) (mi, ma float64) {
 	var cpuReqSum, memReqSum float64
 	for _, p := range pods {
-		cpuReqSum += p.MemReq
+		cpuReqSum += p.CPUReq
 		memReqSum += p.MemReq
 	}
 	cpuRatio, memRatio := cpuReqSum/float64(len(nodes)), memReqSum/float64(len(nodes))
@@ -414,113 +499,6 @@ func computePenaltyMinMax(pods []model.PodModel, nodes []model.Node, latencyMap
 	return
 }
 
-// heuristicAssign 启发式分配算法，对一个originalAssign进行局部优化。当模拟退火算法降温到某个T时，从完全随机变为启发式局部优化。或者当模拟退火算法收敛后（连续x次迭代没有更优解），启发式局部优化
-// mode = 0表示完全启发式；mode = 1表示上次延迟太高了，本次使用延迟降低为主的启发式； mode = 2表示上次资源不均衡，本次使用资源均衡为主的启发式
-func heuristicAssign(originalAssign []int,
-	latenciesMap model.NodeLatencies, podDependencies model.PodDependencies,
-	pods []model.PodModel, nodeStatuses []model.Node,
-	mode int) (newAssign []int) {
-	// 随机一个pod，分配到其他node延迟更低的节点
-	nodeSize := len(nodeStatuses)
-	type lat struct {
-		nodeIdx int
-		latSum  float64
-	}
-
-	newAssign = make([]int, len(originalAssign))
-	copy(newAssign, originalAssign)
-
-	// 计算所有node 的邻域延迟
-	nodeLatSumUp := make([]lat, nodeSize)
-
-	for i, node := range latenciesMap {
-		nodeLatSumUp[i].nodeIdx = i
-		for j := 0; j < len(node); j++ {
-			nodeLatSumUp[i].latSum += node[j]
-		}
-	}
-
-	slices.SortFunc(nodeLatSumUp, func(a, b lat) int {
-		if a.latSum-b.latSum >= 0 {
-			return 1
-		} else {
-			return -1
-		}
-	})
-
-	if mode == 1 { // 延迟较高，微调度延迟较低的节点
-		podIdx := rand.Intn(len(newAssign))
-		currentNode := newAssign[podIdx]
-		for i, l := range nodeLatSumUp {
-			if l.nodeIdx == currentNode {
-				if i > 0 {
-					newAssign[podIdx] = nodeLatSumUp[i-1].nodeIdx
-					fmt.Printf("启发式assign, mode=1, pod %d 从节点 %d 迁移到节点 %d\n", podIdx, currentNode, newAssign[podIdx])
-					break
-				}
-			}
-		}
-	} else if mode == 2 {
-		podDeg := make([]struct {
-			podIdx int
-			deg    float64
-		}, len(pods))
-		for i, depi := range podDependencies {
-			podDeg[i].podIdx = i
-			for j := i + 1; j < len(depi); j++ {
-				if depi[j] > 0 {
-					podDeg[i].deg += depi[j]
-					podDeg[j].deg += depi[j]
-				}
-			}
-		}
-		slices.SortFunc(podDeg, func(a, b struct {
-			podIdx int
-			deg    float64
-		}) int {
-			if a.deg-b.deg >= 0 {
-				return 1
-			} else {
-				return -1
-			}
-		})
-
-		// 选度数最低的pod，且该pod分配的节点有重复的，进行变更
-
-		// 使用bucket记录每个节点上的pod数量
-		bucket := make([]int, nodeSize)
-
-		for _, n := range newAssign {
-			bucket[n]++
-		}
-
-		balance := float64(len(pods)) / float64(nodeSize)
-
-		for _, each := range podDeg[:len(podDeg)-2] {
-			if float64(bucket[newAssign[each.podIdx]]) >= balance+1 {
-				newAssign[each.podIdx] = nodeLatSumUp[rand.Intn(nodeSize)].nodeIdx
-				fmt.Printf("启发式assign, mode=2, pod %d 从节点 %d 迁移到节点 %d\n", each.podIdx, newAssign[each.podIdx], newAssign[each.podIdx])
-				break
-			}
-		}
-	} else {
-		newAssign = randomAssign(newAssign, nodeStatuses)
-	}
-	return
-}
-
-func randomAssign(originalAssign []int, nodeStatuses []model.Node) (newAssign []int) {
-	newAssign = make([]int, len(originalAssign))
-	copy(newAssign, originalAssign)
-	nodeSize := len(nodeStatuses)
-	podIdx := rand.Intn(len(originalAssign))
-	newNode := rand.Intn(nodeSize)
-	for newNode == newAssign[podIdx] && nodeSize > 1 {
-		newNode = rand.Intn(nodeSize)
-	}
-	return
-}
-
 // plotScoreCurve 绘制score变化曲线并保存到临时目录
 func plotScoreCurve(scores []float64) string {
 	p := plot.New()
@@ -12,25 +12,32 @@ import (
 	"k8s.io/klog/v2"
 )
 
+// DefaultDependencyWeight 依赖未设置weight时使用的通信权重
+const DefaultDependencyWeight float64 = 1
+
 // ParsePodGroup 解析PodGroup中的Pod及其依赖关系，返回PodGroupMap和PodDependencies
-// PodGroupMap为一个map结构，key为pod的name，value为podTemplate
-// PodDependencies为一个二维矩阵，表示Pod之间的通信关系
+// PodGroupMap为一个map结构，key为成员pod的name，value为podTemplate，设置了replicas的模板按照副本展开
+// PodDependencies为一个二维矩阵，表示Pod之间的通信关系，[i][j]为Pod i发往Pod j的通信权重（双向依赖两个方向均计入）
 // Pod的名称列表，顺序与PodDependencies矩阵的行列顺序一致
 // 若没有Pod，则返回nil
 func ParsePodGroup(group *podGroupv1.PodGroup) *model.PodGroupParseResult {
 	// TODO: 解析PodGroup中的Pod及其依赖关系，返回PodGroupMap和PodDependencies
-	if group == nil || len(group.Spec.PodList) == 0 {
+	if group == nil || group.Spec.MemberCount() == 0 {
 		return nil
 	}
-	// 构建PodGroupMap
+	// 构建PodGroupMap，设置了replicas的模板展开为多个成员Pod
 	podGroupMap := make(model.PodGroupMap)
-	for _, podTemplate := range group.Spec.PodList {
+	for _, podTemplate := range group.Spec.Members() {
 		podGroupMap[podTemplate.Metadata.Name] = podTemplate
 	}
-	// 构建PodNameList
+	// 构建PodNameList，按照spec中成员Pod的顺序排列，使相同的PodGroup得到相同的求解输入
 	podNameList := make([]string, 0, len(podGroupMap))
-	for podName := range podGroupMap {
-		podNameList = append(podNameList, podName)
+	listed := make(map[string]bool, len(podGroupMap))
+	for _, podTemplate := range group.Spec.Members() {
+		if podName := podTemplate.Metadata.Name; !listed[podName] {
+			listed[podName] = true
+			podNameList = append(podNameList, podName)
+		}
 	}
 	// 构建PodDependencies矩阵
 	podCount := len(podNameList)
@@ -38,43 +45,67 @@ func ParsePodGroup(group *podGroupv1.PodGroup) *model.PodGroupParseResult {
 	for i := range podDependencies {
 		podDependencies[i] = make([]float64, podCount)
 	}
+	podIndex := make(map[string]int, podCount)
+	for idx, podName := range podNameList {
+		podIndex[podName] = idx
+	}
+	// 指向整个模板的依赖展开为两侧成员Pod两两之间的依赖
 	for _, dep := range group.Spec.Dependencies {
-		var i, j int = -1, -1
-		for idx, podName := range podNameList {
-			if podName == dep.P1 {
-				i = idx
+		w := dependencyWeight(dep)
+		for _, p1 := range group.Spec.ResolveMembers(dep.P1, dep.P1Index) {
+			for _, p2 := range group.Spec.ResolveMembers(dep.P2, dep.P2Index) {
+				i, j := podIndex[p1], podIndex[p2]
+				if i == j {
+					continue
+				}
+				switch dep.Direction {
+				case podGroupv1.DirectionP1ToP2:
+					podDependencies.Set(i, j, podDependencies.Get(i, j)+w)
+				case podGroupv1.DirectionP2ToP1:
+					podDependencies.Set(j, i, podDependencies.Get(j, i)+w)
+				default:
+					podDependencies.Set(i, j, podDependencies.Get(i, j)+w)
+					podDependencies.Set(j, i, podDependencies.Get(j, i)+w)
+				}
 			}
-			if podName == dep.P2 {
-				j = idx
-			}
-		}
-		if i != -1 && j != -1 {
-			podDependencies.Set(i, j, 1)
-			podDependencies.Set(j, i, 1)
 		}
 	}
 	//NodeBalanceFactor
 	nodeBalanceFactor := group.Spec.NodeNum
 
+	// 硬约束
+	antiAffinity := make([][]string, 0, len(group.Spec.AntiAffinity))
+	for _, g := range group.Spec.AntiAffinity {
+		var members []string
+		for _, name := range g.Pods {
+			members = append(members, group.Spec.ResolveMembers(name, nil)...)
+		}
+		antiAffinity = append(antiAffinity, members)
+	}
+	maxPodsPerNode := 0
+	if group.Spec.MaxPodsPerNode != nil {
+		maxPodsPerNode = int(*group.Spec.MaxPodsPerNode)
+	}
+
 	return &model.PodGroupParseResult{
 		PodGroupMap:       podGroupMap,
 		PodDependencies:   podDependencies,
 		PodNameList:       podNameList,
 		NodeBalanceFactor: nodeBalanceFactor,
+		AntiAffinity:      antiAffinity,
+		MaxPodsPerNode:    maxPodsPerNode,
 	}
 	//return nil
 }
 
-// SortPodNameListByDegree 根据Pod的依赖关系对Pod名称列表进行排序，返回排序后的Pod名称列表,按照度数从高到低排序
+// SortPodNameListByDegree 根据Pod的依赖关系对Pod名称列表进行排序，返回排序后的Pod名称列表,按照加权度数从高到低排序
 func SortPodNameListByDegree(dependencies model.PodDependencies, podNameList []string) []string {
-	// TODO
-	// 计算每个Pod的度数
-	degrees := make([]int, len(podNameList))
+	// 计算每个Pod的加权度数，出边与入边的权重均计入
+	degrees := make([]float64, len(podNameList))
 	for i := range dependencies {
 		for j := range dependencies[i] {
-			if dependencies[i][j] == 1 {
-				degrees[i]++
-			}
+			degrees[i] += dependencies[i][j]
+			degrees[j] += dependencies[i][j]
 		}
 	}
 	// 排序
@@ -98,10 +129,18 @@ func SortPodNameListByDegree(dependencies model.PodDependencies, podNameList []s
 	// return nil
 }
 
+// dependencyWeight 返回依赖的通信权重，未设置时返回DefaultDependencyWeight
+func dependencyWeight(dep podGroupv1.Dependency) float64 {
+	if dep.Weight == nil {
+		return DefaultDependencyWeight
+	}
+	return dep.Weight.AsApproximateFloat64()
+}
+
 // NormalSchedule 对PodGroup进行常规调度 one-by-one
 func NormalSchedule(ctx context.Context, c client.Client, group *podGroupv1.PodGroup) error {
 	// TODO
-	if group == nil || len(group.Spec.PodList) == 0 {
+	if group == nil || group.Spec.MemberCount() == 0 {
 		return nil
 	}
 	// 获取PodGroup的GVK信息
@@ -113,7 +152,7 @@ func NormalSchedule(ctx context.Context, c client.Client, group *podGroupv1.PodG
 		Kind:    "PodGroup",
 	}
 
-	for _, podTemplate := range group.Spec.PodList {
+	for _, podTemplate := range group.Spec.Members() {
 		pod := model.CreatePodWithoutAffinity(podTemplate, group.ObjectMeta, gvk)
 
 		klog.Infof("[NormalSchedule] Creating Pod %s/%s without NodeAffinity",
@@ -159,3 +198,77 @@ func GreedyPlacement(podNameList []string, nodeNameList []string, nodeBalance in
 	}
 	return res
 }
+
+/*
+GreedyPlacementWithCapacity 在GreedyPlacement的基础上考虑节点的剩余资源，返回一个PodName - NodeName的映射
+  - Pods - 按照度数从高到低排序的Pod
+  - Nodes - 按照平均延迟从低到高排序的可用节点，CPUCap/MemCap为节点的剩余容量
+  - NodeBalance - 期望使用的节点数量
+
+GreedyPlacement选中的节点放不下该Pod时，按延迟从低到高选择第一个放得下的节点；所有节点都放不下时该Pod不出现在结果中
+*/
+func GreedyPlacementWithCapacity(pods []model.PodModel, nodes []model.Node, nodeBalance int) map[string]string {
+	problem := &Problem{Pods: pods, Nodes: nodes}
+	order := func(n int) []int {
+		res := make([]int, n)
+		for i := range res {
+			res[i] = i
+		}
+		return res
+	}
+	res := make(map[string]string, len(pods))
+	for podIdx, nodeIdx := range greedyAssign(problem, order(len(pods)), order(len(nodes)), nodeBalance) {
+		if nodeIdx >= 0 {
+			res[pods[podIdx].PodName] = nodes[nodeIdx].NodeName
+		}
+	}
+	return res
+}
+
+// greedyAssign GreedyPlacementWithCapacity的实现，podOrder与nodeOrder分别为Pod与节点在problem中的下标
+// 除节点剩余资源外还需要满足problem.Constraints，返回的assign[i]为Pod i分配的节点下标，没有节点可用时为-1
+func greedyAssign(problem *Problem, podOrder, nodeOrder []int, nodeBalance int) []int {
+	podNameList := make([]string, len(podOrder))
+	for i, p := range podOrder {
+		podNameList[i] = problem.Pods[p].PodName
+	}
+	nodeNameList := make([]string, len(nodeOrder))
+	nodeIdx := make(map[string]int, len(nodeOrder))
+	for i, n := range nodeOrder {
+		nodeNameList[i] = problem.Nodes[n].NodeName
+		nodeIdx[problem.Nodes[n].NodeName] = n
+	}
+	preferred := GreedyPlacement(podNameList, nodeNameList, nodeBalance)
+
+	remaining := make([]model.Node, len(problem.Nodes))
+	copy(remaining, problem.Nodes)
+	assign := make([]int, len(problem.Pods))
+	for i := range assign {
+		assign[i] = -1
+	}
+	available := func(podIdx, n int) bool {
+		pod := problem.Pods[podIdx]
+		return remaining[n].Fits(pod.CPUReq, pod.MemReq) && problem.Constraints.Allows(assign, podIdx, n)
+	}
+	for _, podIdx := range podOrder {
+		pod := problem.Pods[podIdx]
+		target := -1
+		if n, ok := nodeIdx[preferred[pod.PodName]]; ok && available(podIdx, n) {
+			target = n
+		} else {
+			for _, n := range nodeOrder {
+				if available(podIdx, n) {
+					target = n
+					break
+				}
+			}
+		}
+		if target == -1 {
+			klog.Warningf("[GreedyPlacementWithCapacity] no node can fit pod %s without violating constraints, leave it to the default scheduler", pod.PodName)
+			continue
+		}
+		remaining[target].Consume(pod.CPUReq, pod.MemReq)
+		assign[podIdx] = target
+	}
+	return assign
+}
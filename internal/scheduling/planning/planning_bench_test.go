@@ -0,0 +1,65 @@
+package planning
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	podGroupv1 "github.com/SMALL-head/podGroup/api/v1"
+)
+
+// BenchmarkSolvers 比较各个求解算法在不同规模下的耗时、得到的延迟代价(cost)以及分配了节点的Pod数量(placed)，
+// exhaustive与branch-and-bound只适用于规模很小的PodGroup，只在最小的规模上运行
+func BenchmarkSolvers(b *testing.B) {
+	sizes := []struct{ clusters, size, nodes int }{
+		{2, 4, 3},
+		{10, 5, 12},
+		{40, 5, 50},
+	}
+	for _, sz := range sizes {
+		problem := clusteredProblem(sz.clusters, sz.size, sz.nodes)
+		for _, strategy := range RegisteredStrategies() {
+			exact := strategy == podGroupv1.ExhaustiveStrategy || strategy == podGroupv1.BranchAndBoundStrategy
+			if exact && len(problem.Pods) > 8 {
+				continue
+			}
+			b.Run(fmt.Sprintf("%s/pods=%d", strategy, len(problem.Pods)), func(b *testing.B) {
+				solver, err := NewSolver(&podGroupv1.PodGroupSpec{PlacementStrategy: strategy})
+				if err != nil {
+					b.Fatal(err)
+				}
+				var plan *Plan
+				for i := 0; i < b.N; i++ {
+					if plan, err = solver.Solve(context.Background(), problem); err != nil {
+						b.Fatal(err)
+					}
+				}
+				b.ReportMetric(problem.Cost(plan.Assign), "cost")
+				b.ReportMetric(float64(len(plan.Assign)), "placed")
+			})
+		}
+	}
+}
+
+// BenchmarkObjective 比较移动一个Pod之后完整计算objectiveFunc与使用evalState增量计算的耗时
+func BenchmarkObjective(b *testing.B) {
+	problem := clusteredProblem(40, 5, 50)
+	podSize, nodeSize := len(problem.Pods), len(problem.Nodes)
+	assign := make([]int, podSize)
+	for i := range assign {
+		assign[i] = i / 5
+	}
+	b.Run("full", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			assign[i%podSize] = (assign[i%podSize] + 1) % nodeSize
+			_ = objectiveFunc(0.5, 0.5, problem.NodeLatencies, problem.PodDependencies, problem.Pods, problem.Nodes, &problem.Constraints, assign, podSize)
+		}
+	})
+	b.Run("incremental", func(b *testing.B) {
+		state := newEvalState(problem.NodeLatencies, problem.PodDependencies, problem.Pods, problem.Nodes, &problem.Constraints, assign)
+		for i := 0; i < b.N; i++ {
+			state.move(i%podSize, (state.assign[i%podSize]+1)%nodeSize)
+			_ = state.objective(0.5, 0.5)
+		}
+	})
+}
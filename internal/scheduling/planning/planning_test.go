@@ -1,12 +1,19 @@
 package planning
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"math"
+	"math/rand"
+	"slices"
 	"testing"
+	"time"
 
+	podGroupv1 "github.com/SMALL-head/podGroup/api/v1"
 	"github.com/SMALL-head/podGroup/internal/scheduling/model"
 	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/resource"
 )
 
 func TestFunc(t *testing.T) {
@@ -83,7 +90,7 @@ func case1(t *testing.T) {
 		0.7,
 		*nodeLatencies,
 		*podDependencies,
-		pods, nodes, 100000, 200, 1, 0.95, true)
+		pods, nodes, nil, 100000, 200, 1, 0.95, rand.New(rand.NewSource(1)), true)
 	fmt.Println("assign: ", assign)
 	fmt.Println("score: ", score)
 
@@ -142,7 +149,7 @@ func TestRelativeImprovementAssign(t *testing.T) {
 		0.3,
 		*nodeLatencies,
 		*podDependencies,
-		pods, nodes, 10000, 1000, 0.1, 0.98, true)
+		pods, nodes, nil, 10000, 1000, 0.1, 0.98, rand.New(rand.NewSource(1)), true)
 	fmt.Println("assign: ", assign)
 	fmt.Println("relative improvement: ", score)
 }
@@ -163,3 +170,983 @@ func symmetryCopy(matrix [][]float64) error {
 	}
 	return nil
 }
+
+func TestParsePodGroupWeightedDependencies(t *testing.T) {
+	weight := resource.MustParse("2500m")
+	group := &podGroupv1.PodGroup{
+		Spec: podGroupv1.PodGroupSpec{
+			PodList: []podGroupv1.PodTemplate{
+				{Metadata: podGroupv1.PodMetadata{Name: "pod1"}},
+				{Metadata: podGroupv1.PodMetadata{Name: "pod2"}},
+				{Metadata: podGroupv1.PodMetadata{Name: "pod3"}},
+			},
+			Dependencies: []podGroupv1.Dependency{
+				{P1: "pod1", P2: "pod2"},
+				{P1: "pod2", P2: "pod3", Weight: &weight, Direction: podGroupv1.DirectionP1ToP2},
+				{P1: "pod1", P2: "pod3", Direction: podGroupv1.DirectionP2ToP1},
+			},
+			NodeNum: 2,
+		},
+	}
+
+	res := ParsePodGroup(group)
+	require.NotNil(t, res)
+
+	idx := make(map[string]int)
+	for i, name := range res.PodNameList {
+		idx[name] = i
+	}
+	dep := res.PodDependencies
+	require.Equal(t, 1.0, dep[idx["pod1"]][idx["pod2"]])
+	require.Equal(t, 1.0, dep[idx["pod2"]][idx["pod1"]])
+	require.Equal(t, 2.5, dep[idx["pod2"]][idx["pod3"]])
+	require.Equal(t, 0.0, dep[idx["pod3"]][idx["pod2"]])
+	require.Equal(t, 1.0, dep[idx["pod3"]][idx["pod1"]])
+	require.Equal(t, 0.0, dep[idx["pod1"]][idx["pod3"]])
+
+	// pod2的加权度数最高
+	sorted := SortPodNameListByDegree(res.PodDependencies, res.PodNameList)
+	require.Equal(t, "pod2", sorted[0])
+}
+
+func TestSolvers(t *testing.T) {
+	podDependencies := new(model.PodDependencies)
+	dependencyMatrix := [][]float64{
+		{0, 5, 0, 0},
+		{0, 0, 0, 0},
+		{0, 0, 0, 3},
+		{0, 0, 0, 0},
+	}
+	require.NoError(t, symmetryCopy(dependencyMatrix))
+	podDependencies.BuildFromMatrix(dependencyMatrix)
+
+	nodeLatencies := new(model.NodeLatencies)
+	nodeLatencies.BuildFromMatrix([][]float64{
+		{0, 10, 50},
+		{10, 0, 50},
+		{50, 50, 0},
+	})
+
+	problem := &Problem{
+		Pods: []model.PodModel{
+			{PodName: "pod1", CPUReq: 2, MemReq: 4},
+			{PodName: "pod2", CPUReq: 2, MemReq: 4},
+			{PodName: "pod3", CPUReq: 2, MemReq: 4},
+			{PodName: "pod4", CPUReq: 2, MemReq: 4},
+		},
+		PodDependencies: *podDependencies,
+		Nodes: []model.Node{
+			{NodeName: "node1", CPUCap: 32, MemCap: 64},
+			{NodeName: "node2", CPUCap: 32, MemCap: 64},
+			{NodeName: "node3", CPUCap: 32, MemCap: 64},
+		},
+		NodeLatencies: *nodeLatencies,
+		NodeBalance:   2,
+	}
+
+	for _, strategy := range RegisteredStrategies() {
+		t.Run(string(strategy), func(t *testing.T) {
+			solver, err := NewSolver(&podGroupv1.PodGroupSpec{PlacementStrategy: strategy})
+			require.NoError(t, err)
+			require.Equal(t, strategy, solver.Name())
+
+			plan, err := solver.Solve(context.Background(), problem)
+			require.NoError(t, err)
+			_, ok := plan2Assign(problem, plan)
+			require.True(t, ok, "every pod should be assigned to a known node: %v", plan.Assign)
+		})
+	}
+
+	_, err := NewSolver(&podGroupv1.PodGroupSpec{PlacementStrategy: "unknown"})
+	require.Error(t, err)
+
+	// exhaustive的最优解中，存在依赖的Pod应当被分配在同一节点
+	solver, err := NewSolver(&podGroupv1.PodGroupSpec{PlacementStrategy: podGroupv1.ExhaustiveStrategy})
+	require.NoError(t, err)
+	plan, err := solver.Solve(context.Background(), problem)
+	require.NoError(t, err)
+	require.Equal(t, plan.Assign["pod1"], plan.Assign["pod2"])
+	require.Equal(t, plan.Assign["pod3"], plan.Assign["pod4"])
+}
+
+func TestExhaustiveSearchSpace(t *testing.T) {
+	problem := &Problem{}
+	for i := 0; i < 100; i++ {
+		problem.Nodes = append(problem.Nodes, model.Node{NodeName: fmt.Sprintf("node%d", i), CPUCap: 8, MemCap: 16})
+	}
+	problem.NodeLatencies = make(model.NodeLatencies, len(problem.Nodes))
+	for i := range problem.NodeLatencies {
+		problem.NodeLatencies[i] = make([]float64, len(problem.Nodes))
+	}
+	for i := 0; i < 8; i++ {
+		problem.Pods = append(problem.Pods, model.PodModel{PodName: fmt.Sprintf("pod%d", i), CPUReq: 1, MemReq: 1})
+	}
+	problem.PodDependencies = make(model.PodDependencies, len(problem.Pods))
+	for i := range problem.PodDependencies {
+		problem.PodDependencies[i] = make([]float64, len(problem.Pods))
+	}
+
+	// 100个节点上的8个Pod不超过maxPods，但是assign的数量远超上限，不进行枚举
+	solver, err := NewSolver(&podGroupv1.PodGroupSpec{PlacementStrategy: podGroupv1.ExhaustiveStrategy})
+	require.NoError(t, err)
+	_, err = solver.Solve(context.Background(), problem)
+	require.ErrorContains(t, err, "assigns")
+	require.True(t, assignsWithin(10, 7, ExhaustiveMaxAssigns))
+	require.False(t, assignsWithin(10, 8, ExhaustiveMaxAssigns))
+
+	// 枚举过程中ctx结束时停止搜索
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assign, _, err := FindOptimalAssign(ctx, DefaultExhaustiveAlpha, DefaultExhaustiveBeta,
+		problem.NodeLatencies, problem.PodDependencies, problem.Pods[:4], problem.Nodes, &problem.Constraints)
+	require.ErrorIs(t, err, context.Canceled)
+	require.Nil(t, assign)
+}
+
+func TestGreedyPlacementWithCapacity(t *testing.T) {
+	pods := []model.PodModel{
+		{PodName: "pod1", CPUReq: 3, MemReq: 1},
+		{PodName: "pod2", CPUReq: 3, MemReq: 1},
+		{PodName: "pod3", CPUReq: 1, MemReq: 1},
+		{PodName: "pod4", CPUReq: 8, MemReq: 1},
+	}
+	nodes := []model.Node{
+		{NodeName: "node1", CPUCap: 4, MemCap: 16},
+		{NodeName: "node2", CPUCap: 4, MemCap: 16},
+	}
+
+	res := GreedyPlacementWithCapacity(pods, nodes, 1)
+	// nodeBalance为1时GreedyPlacement会把所有Pod放到node1，放不下的Pod顺延到node2
+	require.Equal(t, "node1", res["pod1"])
+	require.Equal(t, "node2", res["pod2"])
+	require.Equal(t, "node1", res["pod3"])
+	// 没有节点放得下pod4
+	_, ok := res["pod4"]
+	require.False(t, ok)
+}
+
+func TestReplanPod(t *testing.T) {
+	problem := &Problem{
+		Pods: []model.PodModel{
+			{PodName: "pod1", CPUReq: 1, MemReq: 1},
+			{PodName: "pod2", CPUReq: 1, MemReq: 1},
+			{PodName: "pod3", CPUReq: 2, MemReq: 1},
+		},
+		// pod3与pod1之间的通信量大于与pod2之间的通信量
+		PodDependencies: model.PodDependencies{
+			{0, 0, 5},
+			{0, 0, 0},
+			{5, 1, 0},
+		},
+		Nodes: []model.Node{
+			{NodeName: "node1", CPUCap: 4, MemCap: 16},
+			{NodeName: "node2", CPUCap: 4, MemCap: 16},
+			{NodeName: "node3", CPUCap: 4, MemCap: 16},
+		},
+		NodeLatencies: model.NodeLatencies{
+			{0, 10, 2},
+			{10, 0, 10},
+			{2, 10, 0},
+		},
+	}
+
+	fixed := []int{0, 1, -1}
+	require.Equal(t, 0, ReplanPod(problem, fixed, 2))
+
+	// node1放不下时选择离node1最近的node3
+	problem.Nodes[0].CPUCap = 1
+	require.Equal(t, 2, ReplanPod(problem, fixed, 2))
+
+	// 没有节点放得下
+	problem.Pods[2].CPUReq = 8
+	require.Equal(t, -1, ReplanPod(problem, fixed, 2))
+}
+
+func TestProblemCost(t *testing.T) {
+	problem := &Problem{
+		Pods: []model.PodModel{{PodName: "pod1"}, {PodName: "pod2"}, {PodName: "pod3"}},
+		// pod1 -> pod2 权重为2，pod2 -> pod1 权重为1，pod3 -> pod1 权重为4
+		PodDependencies: model.PodDependencies{
+			{0, 2, 0},
+			{1, 0, 0},
+			{4, 0, 0},
+		},
+		Nodes: []model.Node{{NodeName: "node1"}, {NodeName: "node2"}},
+		NodeLatencies: model.NodeLatencies{
+			{0, 10},
+			{20, 0},
+		},
+	}
+
+	require.Equal(t, 0.0, problem.Cost(map[string]string{"pod1": "node1", "pod2": "node1", "pod3": "node1"}))
+	require.Equal(t, 2*10.0+1*20.0, problem.Cost(map[string]string{"pod1": "node1", "pod2": "node2", "pod3": "node1"}))
+	// 不在Assign中的Pod不计入代价
+	require.Equal(t, 4*20.0, problem.Cost(map[string]string{"pod1": "node1", "pod3": "node2"}))
+}
+
+func TestCountFittingPods(t *testing.T) {
+	pods := []model.PodModel{
+		{PodName: "pod1", CPUReq: 3, MemReq: 1},
+		{PodName: "pod2", CPUReq: 1, MemReq: 1},
+		{PodName: "pod3", CPUReq: 1, MemReq: 1},
+		{PodName: "pod4", CPUReq: 2, MemReq: 1},
+	}
+	nodes := []model.Node{
+		{NodeName: "node1", CPUCap: 2, MemCap: 16},
+		{NodeName: "node2", CPUCap: 2, MemCap: 16},
+	}
+	// 小的Pod优先放入：pod2、pod3放入node1，pod4放入node2，pod1放不下
+	require.Equal(t, 3, CountFittingPods(pods, nodes))
+	require.Equal(t, 0, CountFittingPods(pods, nil))
+	// 入参不应被修改
+	require.Equal(t, 2.0, nodes[0].CPUCap)
+	require.Equal(t, "pod1", pods[0].PodName)
+}
+
+func TestNearestNodes(t *testing.T) {
+	problem := &Problem{
+		Nodes: []model.Node{{NodeName: "node1"}, {NodeName: "node2"}, {NodeName: "node3"}, {NodeName: "node4"}},
+		NodeLatencies: model.NodeLatencies{
+			{0, 30, 10, 10},
+			{30, 0, 5, 5},
+			{10, 5, 0, 1},
+			{10, 5, 1, 0},
+		},
+	}
+
+	require.Equal(t, []string{"node3", "node4", "node2"}, problem.NearestNodes("node1", 3))
+	require.Equal(t, []string{"node3", "node4"}, problem.NearestNodes("node1", 2))
+	require.Equal(t, []string{"node4", "node2", "node1"}, problem.NearestNodes("node3", 10))
+	require.Nil(t, problem.NearestNodes("node5", 3))
+	require.Nil(t, problem.NearestNodes("node1", 0))
+}
+
+func TestHeaviestDependencies(t *testing.T) {
+	pRes := &model.PodGroupParseResult{
+		PodNameList: []string{"a", "b", "c", "d", "e"},
+		// a <-> b 权重为4，c -> a 权重为2，a -> d 权重为2，e -> d 权重为1
+		PodDependencies: model.PodDependencies{
+			{0, 4, 0, 2, 0},
+			{4, 0, 0, 0, 0},
+			{2, 0, 0, 0, 0},
+			{0, 0, 0, 0, 0},
+			{0, 0, 0, 1, 0},
+		},
+	}
+
+	require.Equal(t, []model.PodPreference{{PodName: "b", Weight: 100}, {PodName: "c", Weight: 25}, {PodName: "d", Weight: 25}}, HeaviestDependencies(pRes, "a", 3))
+	require.Equal(t, []model.PodPreference{{PodName: "b", Weight: 100}}, HeaviestDependencies(pRes, "a", 1))
+	require.Equal(t, []model.PodPreference{{PodName: "a", Weight: 100}, {PodName: "e", Weight: 50}}, HeaviestDependencies(pRes, "d", 3))
+	require.Nil(t, HeaviestDependencies(pRes, "x", 3))
+	require.Nil(t, HeaviestDependencies(pRes, "a", 0))
+}
+
+func TestConstraints(t *testing.T) {
+	c := newConstraints([]string{"pod1", "pod2", "pod3", "pod4"}, [][]string{{"pod1", "pod2", "pod5"}, {"pod3"}}, 2)
+	// 只有一个已知Pod的分组没有意义
+	require.Equal(t, [][]int{{0, 1}}, c.AntiAffinity)
+
+	require.Equal(t, 0, c.Violations([]int{0, 1, 0, 1}))
+	// pod1与pod2在同一节点
+	require.Equal(t, 1, c.Violations([]int{0, 0, 1, 1}))
+	// node0上有3个Pod，且pod1与pod2在同一节点
+	require.Equal(t, 2, c.Violations([]int{0, 0, 0, 1}))
+	require.Equal(t, 0, c.Violations([]int{0, -1, -1, 0}))
+
+	assign := []int{0, -1, 0, -1}
+	require.False(t, c.Allows(assign, 1, 0))
+	require.True(t, c.Allows(assign, 1, 1))
+	require.False(t, c.Allows(assign, 3, 0))
+	require.True(t, c.Allows(assign, 3, 1))
+}
+
+// violatingSolver 总是把所有Pod分配到第一个节点
+type violatingSolver struct{}
+
+func (violatingSolver) Name() podGroupv1.PlacementStrategy { return "violating" }
+
+func (violatingSolver) Solve(_ context.Context, problem *Problem) (*Plan, error) {
+	plan := &Plan{Assign: make(map[string]string)}
+	for _, pod := range problem.Pods {
+		plan.Assign[pod.PodName] = problem.Nodes[0].NodeName
+	}
+	return plan, nil
+}
+
+func TestSolversWithConstraints(t *testing.T) {
+	podDependencies := new(model.PodDependencies)
+	podDependencies.BuildFromMatrix([][]float64{
+		{0, 5, 1, 1},
+		{5, 0, 1, 1},
+		{1, 1, 0, 3},
+		{1, 1, 3, 0},
+	})
+	nodeLatencies := new(model.NodeLatencies)
+	nodeLatencies.BuildFromMatrix([][]float64{
+		{0, 10, 50},
+		{10, 0, 50},
+		{50, 50, 0},
+	})
+	pRes := &model.PodGroupParseResult{
+		PodGroupMap:     model.PodGroupMap{},
+		PodDependencies: *podDependencies,
+		PodNameList:     []string{"pod1", "pod2", "pod3", "pod4"},
+		AntiAffinity:    [][]string{{"pod1", "pod2"}},
+		MaxPodsPerNode:  2,
+	}
+	nodes := []model.Node{
+		{NodeName: "node1", CPUCap: 32, MemCap: 64},
+		{NodeName: "node2", CPUCap: 32, MemCap: 64},
+		{NodeName: "node3", CPUCap: 32, MemCap: 64},
+	}
+	problem := NewProblem(pRes, nodes, *nodeLatencies)
+
+	for _, strategy := range RegisteredStrategies() {
+		t.Run(string(strategy), func(t *testing.T) {
+			solver, err := NewSolver(&podGroupv1.PodGroupSpec{PlacementStrategy: strategy})
+			require.NoError(t, err)
+
+			plan, err := solver.Solve(context.Background(), problem)
+			require.NoError(t, err)
+			require.Len(t, plan.Assign, 4)
+			require.NotEqual(t, plan.Assign["pod1"], plan.Assign["pod2"])
+			require.NoError(t, problem.CheckPlan(plan))
+		})
+	}
+
+	// 违反约束的求解结果被拒绝
+	solver := &constrainedSolver{Solver: violatingSolver{}}
+	_, err := solver.Solve(context.Background(), problem)
+	require.ErrorIs(t, err, ErrConstraintViolated)
+
+	// 重新选择节点时同样需要满足约束
+	fixed := []int{0, -1, 1, 1}
+	require.Equal(t, 2, ReplanPod(problem, fixed, 1))
+}
+
+func TestParsePodGroupReplicas(t *testing.T) {
+	workers, idx0, idx2 := int32(3), int32(0), int32(2)
+	group := &podGroupv1.PodGroup{
+		Spec: podGroupv1.PodGroupSpec{
+			PodList: []podGroupv1.PodTemplate{
+				{Metadata: podGroupv1.PodMetadata{Name: "ps"}},
+				{Metadata: podGroupv1.PodMetadata{Name: "worker"}, Replicas: &workers},
+			},
+			Dependencies: []podGroupv1.Dependency{
+				// 每个worker都与ps通信
+				{P1: "worker", P2: "ps", Direction: podGroupv1.DirectionP1ToP2},
+				// worker-0与worker-2之间额外通信
+				{P1: "worker", P1Index: &idx0, P2: "worker", P2Index: &idx2},
+			},
+			AntiAffinity: []podGroupv1.AntiAffinityGroup{{Pods: []string{"worker"}}},
+		},
+	}
+
+	res := ParsePodGroup(group)
+	require.NotNil(t, res)
+	// 成员Pod按照spec中的顺序排列
+	require.Equal(t, []string{"ps", "worker-0", "worker-1", "worker-2"}, res.PodNameList)
+	require.Len(t, res.PodGroupMap, 4)
+	require.Nil(t, res.PodGroupMap["worker-1"].Replicas)
+	require.Equal(t, "worker-1", res.PodGroupMap["worker-1"].Metadata.Name)
+
+	idx := make(map[string]int)
+	for i, name := range res.PodNameList {
+		idx[name] = i
+	}
+	dep := res.PodDependencies
+	for _, w := range []string{"worker-0", "worker-1", "worker-2"} {
+		require.Equal(t, 1.0, dep[idx[w]][idx["ps"]])
+		require.Equal(t, 0.0, dep[idx["ps"]][idx[w]])
+	}
+	require.Equal(t, 1.0, dep[idx["worker-0"]][idx["worker-2"]])
+	require.Equal(t, 1.0, dep[idx["worker-2"]][idx["worker-0"]])
+	require.Equal(t, 0.0, dep[idx["worker-0"]][idx["worker-1"]])
+
+	require.Equal(t, [][]string{{"worker-0", "worker-1", "worker-2"}}, res.AntiAffinity)
+}
+
+func TestNextMigration(t *testing.T) {
+	podDependencies := new(model.PodDependencies)
+	podDependencies.BuildFromMatrix([][]float64{
+		{0, 5, 1},
+		{5, 0, 1},
+		{1, 1, 0},
+	})
+	nodeLatencies := new(model.NodeLatencies)
+	nodeLatencies.BuildFromMatrix([][]float64{
+		{0, 10, 50},
+		{10, 0, 50},
+		{50, 50, 0},
+	})
+	problem := &Problem{
+		Pods: []model.PodModel{
+			{PodName: "pod1", CPUReq: 2, MemReq: 2},
+			{PodName: "pod2", CPUReq: 2, MemReq: 2},
+			{PodName: "pod3", CPUReq: 2, MemReq: 2},
+		},
+		PodDependencies: *podDependencies,
+		Nodes: []model.Node{
+			{NodeName: "node1", CPUCap: 4, MemCap: 4},
+			{NodeName: "node2", CPUCap: 4, MemCap: 4},
+			{NodeName: "node3", CPUCap: 2, MemCap: 2},
+		},
+		NodeLatencies: *nodeLatencies,
+	}
+	current := map[string]string{"pod1": "node1", "pod2": "node3", "pod3": "node2"}
+	target := map[string]string{"pod1": "node1", "pod2": "node1", "pod3": "node2"}
+
+	// pod2迁移到node1之后与pod1之间的延迟为0
+	migration, ok := NextMigration(problem, current, target)
+	require.True(t, ok)
+	require.Equal(t, "pod2", migration.PodName)
+	require.Equal(t, "node3", migration.FromNode)
+	require.Equal(t, "node1", migration.ToNode)
+	require.InDelta(t, problem.Cost(target), migration.Cost, 1e-9)
+	require.Less(t, migration.Cost, problem.Cost(current))
+
+	// 其他Pod位置不变时目标节点放不下
+	problem.Nodes[0].CPUCap = 3
+	_, ok = NextMigration(problem, current, target)
+	require.False(t, ok)
+	problem.Nodes[0].CPUCap = 4
+
+	// 迁移违反反亲和约束
+	problem.Constraints = newConstraints([]string{"pod1", "pod2", "pod3"}, [][]string{{"pod1", "pod2"}}, 0)
+	_, ok = NextMigration(problem, current, target)
+	require.False(t, ok)
+
+	// 已经位于目标位置时不需要迁移
+	_, ok = NextMigration(problem, target, target)
+	require.False(t, ok)
+
+	ReleasePods(problem, current)
+	require.Equal(t, 6.0, problem.Nodes[0].CPUCap)
+	require.Equal(t, 4.0, problem.Nodes[2].CPUCap)
+
+	// 目标placement需要同时迁移pod1与pod2，只迁移其中一个时代价比当前更高，不进行迁移
+	podDependencies.BuildFromMatrix([][]float64{
+		{0, 10, 1},
+		{10, 0, 1},
+		{1, 1, 0},
+	})
+	problem = &Problem{
+		Pods:            problem.Pods,
+		PodDependencies: *podDependencies,
+		Nodes: []model.Node{
+			{NodeName: "node1", CPUCap: 8, MemCap: 8},
+			{NodeName: "node2", CPUCap: 8, MemCap: 8},
+		},
+		NodeLatencies: model.NodeLatencies{{0, 10}, {10, 0}},
+	}
+	current = map[string]string{"pod1": "node1", "pod2": "node1", "pod3": "node2"}
+	target = map[string]string{"pod1": "node2", "pod2": "node2", "pod3": "node2"}
+	require.Less(t, problem.Cost(target), problem.Cost(current))
+	_, ok = NextMigration(problem, current, target)
+	require.False(t, ok)
+}
+
+// bruteForceCost 枚举所有assign，返回放得下并且满足约束的assign中最低的通信延迟代价，不存在时返回-1
+func bruteForceCost(problem *Problem) float64 {
+	assign := make([]int, len(problem.Pods))
+	best := -1.0
+	var dfs func(idx int)
+	dfs = func(idx int) {
+		if idx == len(assign) {
+			nodes := slices.Clone(problem.Nodes)
+			for p, n := range assign {
+				if !nodes[n].Fits(problem.Pods[p].CPUReq, problem.Pods[p].MemReq) {
+					return
+				}
+				nodes[n].Consume(problem.Pods[p].CPUReq, problem.Pods[p].MemReq)
+			}
+			if problem.Constraints.Violations(assign) > 0 {
+				return
+			}
+			if cost := computeTotalLatency(assign, problem.NodeLatencies, problem.PodDependencies, len(assign)) * 2; best < 0 || cost < best {
+				best = cost
+			}
+			return
+		}
+		for n := range problem.Nodes {
+			assign[idx] = n
+			dfs(idx + 1)
+		}
+	}
+	dfs(0)
+	return best
+}
+
+func TestBranchAndBoundAssign(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for round := 0; round < 20; round++ {
+		podSize, nodeSize := 4+rnd.Intn(3), 2+rnd.Intn(3)
+		deps := make([][]float64, podSize)
+		for i := range deps {
+			deps[i] = make([]float64, podSize)
+		}
+		for i := 0; i < podSize; i++ {
+			for j := i + 1; j < podSize; j++ {
+				if rnd.Intn(2) == 0 {
+					deps[i][j] = float64(1 + rnd.Intn(5))
+					deps[j][i] = deps[i][j]
+				}
+			}
+		}
+		lats := make([][]float64, nodeSize)
+		for i := range lats {
+			lats[i] = make([]float64, nodeSize)
+		}
+		for i := 0; i < nodeSize; i++ {
+			for j := i + 1; j < nodeSize; j++ {
+				lats[i][j] = float64(1 + rnd.Intn(20))
+				lats[j][i] = lats[i][j]
+			}
+		}
+		problem := &Problem{PodDependencies: deps, NodeLatencies: lats}
+		for i := 0; i < podSize; i++ {
+			problem.Pods = append(problem.Pods, model.PodModel{PodName: fmt.Sprintf("pod%d", i), CPUReq: float64(1 + rnd.Intn(2)), MemReq: 1})
+		}
+		for i := 0; i < nodeSize; i++ {
+			problem.Nodes = append(problem.Nodes, model.Node{NodeName: fmt.Sprintf("node%d", i), CPUCap: float64(2 + rnd.Intn(4)), MemCap: 16})
+		}
+		if round%2 == 0 {
+			problem.Constraints = Constraints{AntiAffinity: [][]int{{0, 1}}, MaxPodsPerNode: 3}
+		}
+
+		expected := bruteForceCost(problem)
+		res, err := BranchAndBoundAssign(context.Background(), problem, DefaultBranchAndBoundMaxNodes)
+		if expected < 0 {
+			require.ErrorIs(t, err, ErrNoFeasiblePlacement, "round %d", round)
+			continue
+		}
+		require.NoError(t, err, "round %d", round)
+		require.InDelta(t, expected, res.Cost, 1e-9, "round %d", round)
+		require.Zero(t, res.Gap)
+		plan := assign2Plan(problem, res.Assign, res.Cost)
+		require.Len(t, plan.Assign, podSize)
+		require.NoError(t, problem.CheckPlan(plan))
+		require.InDelta(t, res.Cost, problem.Cost(plan.Assign), 1e-9)
+	}
+}
+
+func TestBranchAndBoundSameNodeLatency(t *testing.T) {
+	// 节点自身的延迟高于部分节点之间的延迟时，共存的Pod之间的代价下界不能使用节点自身的延迟
+	rnd := rand.New(rand.NewSource(2))
+	for round := 0; round < 20; round++ {
+		podSize, nodeSize := 4+rnd.Intn(3), 2+rnd.Intn(3)
+		deps := make([][]float64, podSize)
+		for i := range deps {
+			deps[i] = make([]float64, podSize)
+		}
+		for i := 0; i < podSize; i++ {
+			for j := i + 1; j < podSize; j++ {
+				deps[i][j] = float64(1 + rnd.Intn(5))
+				deps[j][i] = deps[i][j]
+			}
+		}
+		lats := make([][]float64, nodeSize)
+		for i := range lats {
+			lats[i] = make([]float64, nodeSize)
+			lats[i][i] = float64(10 + rnd.Intn(20))
+		}
+		for i := 0; i < nodeSize; i++ {
+			for j := i + 1; j < nodeSize; j++ {
+				lats[i][j] = float64(1 + rnd.Intn(20))
+				lats[j][i] = lats[i][j]
+			}
+		}
+		problem := &Problem{PodDependencies: deps, NodeLatencies: lats}
+		for i := 0; i < podSize; i++ {
+			problem.Pods = append(problem.Pods, model.PodModel{PodName: fmt.Sprintf("pod%d", i), CPUReq: 1, MemReq: 1})
+		}
+		for i := 0; i < nodeSize; i++ {
+			problem.Nodes = append(problem.Nodes, model.Node{NodeName: fmt.Sprintf("node%d", i), CPUCap: float64(podSize), MemCap: 16})
+		}
+
+		expected := bruteForceCost(problem)
+		require.GreaterOrEqual(t, expected, 0.0, "round %d", round)
+		res, err := BranchAndBoundAssign(context.Background(), problem, DefaultBranchAndBoundMaxNodes)
+		require.NoError(t, err, "round %d", round)
+		require.InDelta(t, expected, res.Cost, 1e-9, "round %d", round)
+		require.Zero(t, res.Gap)
+	}
+}
+
+func TestBranchAndBoundBudget(t *testing.T) {
+	// 10个两两依赖的Pod，8个可以互换的节点，每个节点只能放下2个Pod
+	const podSize, nodeSize = 10, 8
+	problem := &Problem{PodDependencies: make(model.PodDependencies, podSize), NodeLatencies: make(model.NodeLatencies, nodeSize)}
+	for i := 0; i < podSize; i++ {
+		problem.PodDependencies[i] = make([]float64, podSize)
+		for j := 0; j < podSize; j++ {
+			if i != j {
+				problem.PodDependencies[i][j] = float64(1 + (i*j)%3)
+			}
+		}
+		problem.Pods = append(problem.Pods, model.PodModel{PodName: fmt.Sprintf("pod%d", i), CPUReq: 1, MemReq: 1})
+	}
+	for i := 0; i < nodeSize; i++ {
+		problem.NodeLatencies[i] = make([]float64, nodeSize)
+		for j := 0; j < nodeSize; j++ {
+			if i != j {
+				problem.NodeLatencies[i][j] = 10
+			}
+		}
+		problem.Nodes = append(problem.Nodes, model.Node{NodeName: fmt.Sprintf("node%d", i), CPUCap: 2, MemCap: 16})
+	}
+	// 可以互换的节点只尝试一个，完整搜索也只需要很少的搜索树节点
+	twins := twinNodes(problem)
+	require.Len(t, twins[0], nodeSize-1)
+	full, err := BranchAndBoundAssign(context.Background(), problem, DefaultBranchAndBoundMaxNodes)
+	require.NoError(t, err)
+	require.Zero(t, full.Gap)
+
+	// 超出预算时返回目前找到的最优assign，下界不超过最优解
+	partial, err := BranchAndBoundAssign(context.Background(), problem, podSize+2)
+	require.NoError(t, err)
+	require.LessOrEqual(t, partial.Explored, podSize+3)
+	require.GreaterOrEqual(t, partial.Cost, full.Cost)
+	require.GreaterOrEqual(t, partial.Gap, 0.0)
+	require.LessOrEqual(t, partial.Cost*(1-partial.Gap), full.Cost+1e-9)
+
+	// 还没有得到完整的assign时停止搜索
+	_, err = BranchAndBoundAssign(context.Background(), problem, 1)
+	require.ErrorIs(t, err, ErrSearchBudgetExceeded)
+
+	// 通过Solver求解时记录最优性差距
+	solver, err := NewSolver(&podGroupv1.PodGroupSpec{PlacementStrategy: podGroupv1.BranchAndBoundStrategy})
+	require.NoError(t, err)
+	plan, err := solver.Solve(context.Background(), problem)
+	require.NoError(t, err)
+	require.NotNil(t, plan.Gap)
+	require.Zero(t, *plan.Gap)
+	require.InDelta(t, full.Cost, problem.Cost(plan.Assign), 1e-9)
+}
+
+// clusteredProblem 构造clusters个各有size个Pod的簇，簇内两两依赖权重为5，相邻簇之间只有一条权重为1的依赖，
+// nodeSize个节点各自恰好放得下一个簇，节点i与节点j之间的延迟为1+|i-j|
+func clusteredProblem(clusters, size, nodeSize int) *Problem {
+	podSize := clusters * size
+	problem := &Problem{PodDependencies: make(model.PodDependencies, podSize), NodeLatencies: make(model.NodeLatencies, nodeSize)}
+	for i := 0; i < podSize; i++ {
+		problem.PodDependencies[i] = make([]float64, podSize)
+		problem.Pods = append(problem.Pods, model.PodModel{PodName: fmt.Sprintf("pod%d", i), CPUReq: 1, MemReq: 1})
+	}
+	for c := 0; c < clusters; c++ {
+		for i := c * size; i < (c+1)*size; i++ {
+			for j := c * size; j < (c+1)*size; j++ {
+				if i != j {
+					problem.PodDependencies[i][j] = 5
+				}
+			}
+		}
+		if c > 0 {
+			problem.PodDependencies[c*size][c*size-1] = 1
+			problem.PodDependencies[c*size-1][c*size] = 1
+		}
+	}
+	for i := 0; i < nodeSize; i++ {
+		problem.NodeLatencies[i] = make([]float64, nodeSize)
+		for j := 0; j < nodeSize; j++ {
+			if i != j {
+				problem.NodeLatencies[i][j] = float64(1 + max(i-j, j-i))
+			}
+		}
+		problem.Nodes = append(problem.Nodes, model.Node{NodeName: fmt.Sprintf("node%d", i), CPUCap: float64(size), MemCap: 64})
+	}
+	return problem
+}
+
+func TestMultilevelPartitionAssign(t *testing.T) {
+	// 每个簇都被完整地放在一个节点上，簇之间只剩下权重为1的依赖
+	problem := clusteredProblem(40, 5, 50)
+	assign := MultilevelPartitionAssign(problem, DefaultPartitioningRefinementPasses)
+	for c := 0; c < 40; c++ {
+		for i := c * 5; i < (c+1)*5; i++ {
+			require.Equal(t, assign[c*5], assign[i], "pod%d should be placed together with its cluster", i)
+		}
+	}
+	solver, err := NewSolver(&podGroupv1.PodGroupSpec{PlacementStrategy: podGroupv1.PartitioningStrategy})
+	require.NoError(t, err)
+	plan, err := solver.Solve(context.Background(), problem)
+	require.NoError(t, err)
+	require.Len(t, plan.Assign, 200)
+	require.InDelta(t, problem.Cost(plan.Assign), plan.Score, 1e-9)
+	// 39条簇之间的依赖，每条双向计入代价，延迟至少为2
+	require.LessOrEqual(t, plan.Score, 39*2*3.0)
+
+	// 节点容量与反亲和约束
+	problem = clusteredProblem(10, 6, 12)
+	problem.Constraints = Constraints{AntiAffinity: [][]int{{0, 1, 2}}, MaxPodsPerNode: 5}
+	assign = MultilevelPartitionAssign(problem, DefaultPartitioningRefinementPasses)
+	require.NotContains(t, assign, -1)
+	require.Zero(t, problem.Constraints.Violations(assign))
+	perNode := make(map[int]int)
+	for _, n := range assign {
+		perNode[n]++
+	}
+	for n, cnt := range perNode {
+		require.LessOrEqual(t, float64(cnt), problem.Nodes[n].CPUCap)
+	}
+
+	// 放不下的Pod不分配节点
+	problem = clusteredProblem(3, 4, 2)
+	assign = MultilevelPartitionAssign(problem, DefaultPartitioningRefinementPasses)
+	require.Equal(t, 4, slices.Index(slices.Sorted(slices.Values(assign)), 0))
+	require.Equal(t, 4, len(assign)-len(slices.DeleteFunc(slices.Clone(assign), func(n int) bool { return n < 0 })))
+}
+
+func TestMultilevelPartitionBalance(t *testing.T) {
+	// 所有Pod两两依赖并且每个节点都放得下全部Pod，不考虑均衡时所有Pod会被放在同一个节点上
+	problem := clusteredProblem(1, 12, 4)
+	for i := range problem.Nodes {
+		problem.Nodes[i].CPUCap = 12
+	}
+	perNode := func(assign []int) map[int]int {
+		res := make(map[int]int)
+		for _, n := range assign {
+			require.GreaterOrEqual(t, n, 0)
+			res[n]++
+		}
+		return res
+	}
+
+	// 未设置nodeNum时使用全部4个节点，每个节点不超过ceil(1.1*12/4)=4个Pod
+	for _, cnt := range perNode(MultilevelPartitionAssign(problem, DefaultPartitioningRefinementPasses)) {
+		require.LessOrEqual(t, cnt, 4)
+	}
+
+	// nodeNum为3时最多使用3个节点，每个节点不超过ceil(1.1*12/3)=5个Pod
+	problem.NodeBalance = 3
+	counts := perNode(MultilevelPartitionAssign(problem, DefaultPartitioningRefinementPasses))
+	require.LessOrEqual(t, len(counts), 3)
+	for _, cnt := range counts {
+		require.LessOrEqual(t, cnt, 5)
+	}
+
+	// 节点容量不足以满足均衡约束时放宽均衡约束，所有Pod仍然被分配节点
+	problem.NodeBalance = 2
+	problem.Nodes[0].CPUCap, problem.Nodes[1].CPUCap, problem.Nodes[2].CPUCap = 2, 2, 2
+	assign := MultilevelPartitionAssign(problem, DefaultPartitioningRefinementPasses)
+	require.NotContains(t, assign, -1)
+	for n, cnt := range perNode(assign) {
+		require.LessOrEqual(t, float64(cnt), problem.Nodes[n].CPUCap)
+	}
+}
+
+// randomEvalProblem 随机生成节点资源紧张、依赖与延迟不对称并带有约束的问题，用于比较增量计算与完整计算的目标函数
+func randomEvalProblem(r *rand.Rand, podSize, nodeSize int) (model.NodeLatencies, model.PodDependencies, []model.PodModel, []model.Node, *Constraints) {
+	latencies := make(model.NodeLatencies, nodeSize)
+	for i := range latencies {
+		latencies[i] = make([]float64, nodeSize)
+		for j := range latencies[i] {
+			if i != j {
+				latencies[i][j] = 1 + r.Float64()*10
+			}
+		}
+	}
+	deps := make(model.PodDependencies, podSize)
+	pods := make([]model.PodModel, podSize)
+	for i := range deps {
+		deps[i] = make([]float64, podSize)
+		for j := range deps[i] {
+			if i != j && r.Intn(3) == 0 {
+				deps[i][j] = float64(r.Intn(5))
+			}
+		}
+		pods[i] = model.PodModel{PodName: fmt.Sprintf("pod%d", i), CPUReq: 0.1 + r.Float64(), MemReq: 0.1 + r.Float64()*2}
+	}
+	nodes := make([]model.Node, nodeSize)
+	for i := range nodes {
+		nodes[i] = model.Node{NodeName: fmt.Sprintf("node%d", i), CPUCap: 1 + r.Float64()*4, MemCap: 2 + r.Float64()*8}
+	}
+	constraints := &Constraints{
+		AntiAffinity:   [][]int{{0, 1, 2}, {2, 3}, {4, 5, 6, 7}},
+		MaxPodsPerNode: 4,
+	}
+	return latencies, deps, pods, nodes, constraints
+}
+
+func TestEvalState(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for round := 0; round < 10; round++ {
+		podSize, nodeSize := 8+r.Intn(12), 2+r.Intn(5)
+		latencies, deps, pods, nodes, constraints := randomEvalProblem(r, podSize, nodeSize)
+		if round%2 == 1 {
+			constraints = nil
+		}
+		assign := make([]int, podSize)
+		for i := range assign {
+			assign[i] = r.Intn(nodeSize)
+		}
+		laMin, laMax := computeLatencyMinMax(latencies, deps)
+		pMin, pMax := computePenaltyMinMax(pods, nodes, latencies)
+		state := newEvalState(latencies, deps, pods, nodes, constraints, assign)
+
+		for step := 0; step < 500; step++ {
+			switch r.Intn(3) {
+			case 0:
+				state.move(r.Intn(podSize), r.Intn(nodeSize))
+			case 1:
+				state.swap(r.Intn(podSize), r.Intn(podSize))
+			default:
+				before := slices.Clone(state.assign)
+				totals := state.totals
+				state.move(r.Intn(podSize), r.Intn(nodeSize))
+				state.undo()
+				require.Equal(t, before, state.assign)
+				require.Equal(t, totals, state.totals)
+			}
+
+			current := slices.Clone(state.assign)
+			require.InDelta(t, computeTotalLatency(current, latencies, deps, podSize), state.totals.latency, 1e-6)
+			penalty, _ := computeResourceBalancePenalty(current, pods, nodes)
+			require.InDelta(t, penalty, state.totals.penalty, 1e-6)
+			require.InDelta(t, computeAllocBalancePenalty(current, pods, nodes, latencies), state.allocBalancePenalty(), 1e-6)
+			require.InDelta(t, objectiveFunc(0.3, 0.7, latencies, deps, pods, nodes, constraints, current, podSize), state.objective(0.3, 0.7), 1e-6)
+			want, wantMode := objectFunc2(0.7, 0.3, latencies, deps, pods, nodes, constraints, current, podSize, laMin, laMax, pMin, pMax)
+			got, mode := state.normalizedObjective(0.7, 0.3, laMin, laMax, pMin, pMax)
+			require.InDelta(t, want, got, 1e-6)
+			require.Equal(t, wantMode, mode)
+		}
+	}
+}
+
+func TestNormalizedObjectiveWeights(t *testing.T) {
+	problem := clusteredProblem(3, 2, 3)
+	assign := []int{0, 0, 1, 2, 2, 1}
+	state := newEvalState(problem.NodeLatencies, problem.PodDependencies, problem.Pods, problem.Nodes, &problem.Constraints, assign)
+	laMin, laMax := computeLatencyMinMax(problem.NodeLatencies, problem.PodDependencies)
+	pMin, pMax := computePenaltyMinMax(problem.Pods, problem.Nodes, problem.NodeLatencies)
+
+	// 返回值为归一化后的延迟项与资源均衡项按照alpha、beta加权之和
+	lOne, _ := state.normalizedObjective(1, 0, laMin, laMax, pMin, pMax)
+	pOne, _ := state.normalizedObjective(0, 1, laMin, laMax, pMin, pMax)
+	require.Positive(t, lOne)
+	for _, w := range [][2]float64{{0.7, 0.3}, {0.5, 0.6}, {0.2, 0.9}} {
+		got, _ := state.normalizedObjective(w[0], w[1], laMin, laMax, pMin, pMax)
+		require.InDelta(t, w[0]*lOne+w[1]*pOne, got, 1e-9)
+		want, _ := objectFunc2(w[0], w[1], problem.NodeLatencies, problem.PodDependencies, problem.Pods, problem.Nodes, &problem.Constraints,
+			assign, len(assign), laMin, laMax, pMin, pMax)
+		require.InDelta(t, want, got, 1e-9)
+	}
+}
+
+func TestSolverSeed(t *testing.T) {
+	problem := clusteredProblem(4, 3, 6)
+	for _, strategy := range []podGroupv1.PlacementStrategy{podGroupv1.AnnealingStrategy, podGroupv1.RelativeImprovementStrategy} {
+		t.Run(string(strategy), func(t *testing.T) {
+			solver, err := NewSolver(&podGroupv1.PodGroupSpec{PlacementStrategy: strategy})
+			require.NoError(t, err)
+			// 相同的输入与种子得到相同的placement
+			problem.Seed = 42
+			plan, err := solver.Solve(context.Background(), problem)
+			require.NoError(t, err)
+			for i := 0; i < 3; i++ {
+				replay, err := solver.Solve(context.Background(), problem)
+				require.NoError(t, err)
+				require.Equal(t, plan, replay)
+			}
+		})
+	}
+}
+
+func TestProblemDigest(t *testing.T) {
+	problem := clusteredProblem(2, 3, 3)
+	digest := problem.Digest()
+	require.Len(t, digest, 16)
+	// 种子不属于求解输入
+	problem.Seed = 7
+	require.Equal(t, digest, problem.Digest())
+	require.Equal(t, digest, clusteredProblem(2, 3, 3).Digest())
+
+	problem.NodeLatencies[0][1] += 0.5
+	require.NotEqual(t, digest, problem.Digest())
+	problem.NodeLatencies[0][1] -= 0.5
+	problem.Nodes[2].CPUCap--
+	require.NotEqual(t, digest, problem.Digest())
+}
+
+func TestRunChains(t *testing.T) {
+	problem := clusteredProblem(4, 3, 6)
+	anneal := func(maxIter int) annealFunc {
+		return func(ctx context.Context, r *rand.Rand, improve func([]int, float64)) ([]int, float64, ChainReport) {
+			return simulatedAnnealingChain(ctx, DefaultAnnealingAlpha, DefaultAnnealingBeta,
+				problem.NodeLatencies, problem.PodDependencies, problem.Pods, problem.Nodes, &problem.Constraints,
+				maxIter, DefaultAnnealingInitTemp, DefaultAnnealingFinalTemp, DefaultAnnealingCoolingRate, r, improve, false)
+		}
+	}
+
+	// 结果取所有链中目标函数最小的assign，并且与各条链的执行顺序无关
+	res := runChains(context.Background(), 4, 100, anneal(DefaultAnnealingMaxIter))
+	require.Len(t, res.Chains, 4)
+	best := res.Chains[0].BestScore
+	for i, c := range res.Chains {
+		require.Equal(t, int64(100+i), c.Seed)
+		require.Equal(t, ChainCooled, c.StopReason)
+		require.LessOrEqual(t, c.BestIteration, c.Iterations)
+		best = min(best, c.BestScore)
+	}
+	require.Equal(t, best, res.Score)
+	require.InDelta(t, res.Score, objectiveFunc(DefaultAnnealingAlpha, DefaultAnnealingBeta, problem.NodeLatencies, problem.PodDependencies,
+		problem.Pods, problem.Nodes, &problem.Constraints, res.Assign, len(problem.Pods)), 1e-9)
+	for i := 0; i < 3; i++ {
+		require.Equal(t, res, runChains(context.Background(), 4, 100, anneal(DefaultAnnealingMaxIter)))
+	}
+
+	res = runChains(context.Background(), 2, 100, anneal(50))
+	for _, c := range res.Chains {
+		require.Equal(t, ChainMaxIterations, c.StopReason)
+		require.Equal(t, 50, c.Iterations)
+	}
+
+	// ctx结束时返回已经找到的最优assign
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	res = runChains(ctx, 3, 100, anneal(DefaultAnnealingMaxIter))
+	require.Len(t, res.Assign, len(problem.Pods))
+	for _, c := range res.Chains {
+		require.Equal(t, ChainStopped, c.StopReason)
+		require.Zero(t, c.Iterations)
+	}
+}
+
+func TestAnnealingTimeLimit(t *testing.T) {
+	problem := clusteredProblem(10, 5, 12)
+	maxIter, timeLimit, chains := int32(math.MaxInt32), int32(1), int32(2)
+	rate := resource.MustParse("0.9999999")
+	solver, err := NewSolver(&podGroupv1.PodGroupSpec{
+		PlacementStrategy: podGroupv1.RelativeImprovementStrategy,
+		SolverOptions: &podGroupv1.SolverOptions{RelativeImprovement: &podGroupv1.AnnealingOptions{
+			MaxIter: &maxIter, CoolingRate: &rate, Chains: &chains, TimeLimitSeconds: &timeLimit,
+		}},
+	})
+	require.NoError(t, err)
+
+	start := time.Now()
+	plan, err := solver.Solve(context.Background(), problem)
+	require.NoError(t, err)
+	require.Less(t, time.Since(start), 5*time.Second)
+	require.Len(t, plan.Chains, 2)
+	for _, c := range plan.Chains {
+		require.Equal(t, ChainTimeLimit, c.StopReason)
+		require.Positive(t, c.Iterations)
+	}
+	require.False(t, plan.Replayable())
+
+	// 调用方的ctx结束时返回ctx的错误
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = solver.Solve(ctx, problem)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	// 默认参数下每条链在降温结束时停止，与时间无关，相同的种子得到相同的placement
+	solver, err = NewSolver(&podGroupv1.PodGroupSpec{PlacementStrategy: podGroupv1.RelativeImprovementStrategy})
+	require.NoError(t, err)
+	plan, err = solver.Solve(context.Background(), problem)
+	require.NoError(t, err)
+	require.True(t, plan.Replayable())
+	again, err := solver.Solve(context.Background(), problem)
+	require.NoError(t, err)
+	require.Equal(t, plan, again)
+}
@@ -0,0 +1,38 @@
+package planning
+
+import "math"
+
+// ReplanPod 在其他Pod位置固定的情况下，为problem.Pods[podIdx]选择与其依赖之间通信代价最低、且剩余资源放得下的节点
+// fixed[i]为Pod i当前所在的节点下标，-1表示该Pod没有确定的位置，不参与代价计算
+// 代价相同时选择到其他节点延迟之和更低的节点，没有节点放得下或者所有节点都违反problem.Constraints时返回-1
+func ReplanPod(problem *Problem, fixed []int, podIdx int) int {
+	pod := problem.Pods[podIdx]
+	best, bestCost, bestLatSum := -1, math.Inf(1), math.Inf(1)
+	for n := range problem.Nodes {
+		if !problem.Nodes[n].Fits(pod.CPUReq, pod.MemReq) || !problem.Constraints.Allows(fixed, podIdx, n) {
+			continue
+		}
+		cost := podCostOnNode(problem, fixed, podIdx, n)
+		latSum := 0.0
+		for m := range problem.Nodes {
+			latSum += problem.NodeLatencies.Get(n, m)
+		}
+		if cost < bestCost || (cost == bestCost && latSum < bestLatSum) {
+			best, bestCost, bestLatSum = n, cost, latSum
+		}
+	}
+	return best
+}
+
+// podCostOnNode 计算Pod podIdx位于节点n时与其依赖之间的通信代价，两个方向的流量分别计入
+func podCostOnNode(problem *Problem, fixed []int, podIdx int, n int) float64 {
+	cost := 0.0
+	for j, m := range fixed {
+		if j == podIdx || m < 0 {
+			continue
+		}
+		cost += problem.PodDependencies.Get(podIdx, j) * problem.NodeLatencies.Get(n, m)
+		cost += problem.PodDependencies.Get(j, podIdx) * problem.NodeLatencies.Get(m, n)
+	}
+	return cost
+}
@@ -0,0 +1,238 @@
+package planning
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+
+	podGroupv1 "github.com/SMALL-head/podGroup/api/v1"
+	"github.com/SMALL-head/podGroup/internal/scheduling/model"
+	"k8s.io/klog/v2"
+)
+
+// Problem 描述一次placement求解的输入
+type Problem struct {
+	// Pods 顺序与PodDependencies矩阵的行列顺序一致
+	Pods            []model.PodModel
+	PodDependencies model.PodDependencies
+	// Nodes 顺序与NodeLatencies矩阵的行列顺序一致
+	Nodes         []model.Node
+	NodeLatencies model.NodeLatencies
+	// NodeBalance 期望使用的节点数量，对应spec.nodeNum
+	NodeBalance int
+	// Constraints 所有求解算法都必须满足的硬约束，对应spec.antiAffinity与spec.maxPodsPerNode
+	Constraints Constraints
+	// Seed 随机求解算法使用的随机数种子，相同的输入与种子得到相同的Plan
+	Seed int64
+}
+
+// NewProblem 根据PodGroup的解析结果以及候选节点构造求解输入，Pod的资源请求量取自PodTemplate.Spec
+func NewProblem(pRes *model.PodGroupParseResult, nodes []model.Node, latencies model.NodeLatencies) *Problem {
+	return &Problem{
+		Pods:            PodModels(pRes),
+		PodDependencies: pRes.PodDependencies,
+		Nodes:           nodes,
+		NodeLatencies:   latencies,
+		NodeBalance:     pRes.NodeBalanceFactor,
+		Constraints:     newConstraints(pRes.PodNameList, pRes.AntiAffinity, pRes.MaxPodsPerNode),
+	}
+}
+
+// Cost 计算placement的通信延迟代价，即Pod i发往Pod j的通信权重与两者所在节点之间延迟的乘积之和
+// assign中没有的Pod或者不在Nodes中的节点不计入代价
+func (p *Problem) Cost(assign map[string]string) float64 {
+	nodeIdx := make(map[string]int, len(p.Nodes))
+	for i, n := range p.Nodes {
+		nodeIdx[n.NodeName] = i
+	}
+	located := make([]int, len(p.Pods))
+	for i, pod := range p.Pods {
+		located[i] = -1
+		if idx, ok := nodeIdx[assign[pod.PodName]]; ok {
+			located[i] = idx
+		}
+	}
+	cost := 0.0
+	for i := range located {
+		for j := range located {
+			if located[i] < 0 || located[j] < 0 {
+				continue
+			}
+			cost += p.PodDependencies.Get(i, j) * p.NodeLatencies.Get(located[i], located[j])
+		}
+	}
+	return cost
+}
+
+// Digest 返回求解输入的摘要，不包括Seed，用于判断复现placement时的输入是否与原来的输入相同
+func (p *Problem) Digest() string {
+	h := sha256.New()
+	_, _ = fmt.Fprintf(h, "%v|%v|%v|%v|%d|%v", p.Pods, p.PodDependencies, p.Nodes, p.NodeLatencies, p.NodeBalance, p.Constraints)
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// NearestNodes 返回与nodeName之间延迟最低的至多n个其他节点，按照延迟从低到高排列，延迟相同时按照节点名称排列
+// nodeName不在候选节点中时返回nil
+func (p *Problem) NearestNodes(nodeName string, n int) []string {
+	src := slices.IndexFunc(p.Nodes, func(node model.Node) bool { return node.NodeName == nodeName })
+	if src < 0 || n <= 0 {
+		return nil
+	}
+	others := make([]int, 0, len(p.Nodes)-1)
+	for i := range p.Nodes {
+		if i != src {
+			others = append(others, i)
+		}
+	}
+	slices.SortFunc(others, func(a, b int) int {
+		return cmp.Or(
+			cmp.Compare(p.NodeLatencies.Get(src, a), p.NodeLatencies.Get(src, b)),
+			cmp.Compare(p.Nodes[a].NodeName, p.Nodes[b].NodeName),
+		)
+	})
+	res := make([]string, 0, min(n, len(others)))
+	for _, i := range others[:min(n, len(others))] {
+		res = append(res, p.Nodes[i].NodeName)
+	}
+	return res
+}
+
+// PodModels 按照PodNameList的顺序返回每个Pod的资源请求量
+func PodModels(pRes *model.PodGroupParseResult) []model.PodModel {
+	pods := make([]model.PodModel, len(pRes.PodNameList))
+	for i, podName := range pRes.PodNameList {
+		podTemplate := pRes.PodGroupMap[podName]
+		cpu, mem := model.PodRequests(&podTemplate.Spec)
+		pods[i] = model.PodModel{PodName: podName, CPUReq: cpu, MemReq: mem}
+	}
+	return pods
+}
+
+// CountFittingPods 估计节点剩余资源最多能够同时放下多少个Pod
+// 按照资源请求量从小到大依次放入第一个放得下的节点，用于在创建Pod之前检查PodGroup能否满足minMember
+func CountFittingPods(pods []model.PodModel, nodes []model.Node) int {
+	sorted := slices.Clone(pods)
+	slices.SortStableFunc(sorted, func(a, b model.PodModel) int {
+		if c := cmp.Compare(a.CPUReq, b.CPUReq); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.MemReq, b.MemReq)
+	})
+	remaining := slices.Clone(nodes)
+	count := 0
+	for _, pod := range sorted {
+		for i := range remaining {
+			if remaining[i].Fits(pod.CPUReq, pod.MemReq) {
+				remaining[i].Consume(pod.CPUReq, pod.MemReq)
+				count++
+				break
+			}
+		}
+	}
+	return count
+}
+
+// Plan 为求解结果
+type Plan struct {
+	// Assign key为pod名称，value为node名称
+	// 节点剩余资源放不下的Pod不会出现在Assign中，由默认调度器决定其位置
+	Assign map[string]string
+	// Score 求解算法目标函数的值，不同算法之间不可比较
+	Score float64
+	// Gap 精确求解算法给出的最优性差距，即(Score-下界)/Score，0表示Score已被证明最优，其他求解算法为nil
+	Gap *float64
+	// Chains 退火求解算法每条退火链的收敛情况，其他求解算法为nil
+	Chains []ChainReport
+}
+
+// Replayable 没有退火链因为超出时间限制或者ctx结束而提前停止，此时使用相同的种子与输入可以复现该结果
+func (p *Plan) Replayable() bool {
+	for _, c := range p.Chains {
+		if c.StopReason == ChainTimeLimit || c.StopReason == ChainStopped {
+			return false
+		}
+	}
+	return true
+}
+
+// Solver placement求解算法
+type Solver interface {
+	Name() podGroupv1.PlacementStrategy
+	Solve(ctx context.Context, problem *Problem) (*Plan, error)
+}
+
+// SolverFactory 根据PodGroup中的参数构造Solver
+type SolverFactory func(spec *podGroupv1.PodGroupSpec) Solver
+
+var solverRegistry = make(map[podGroupv1.PlacementStrategy]SolverFactory)
+
+// RegisterSolver 注册求解算法，应当只在init中调用
+func RegisterSolver(strategy podGroupv1.PlacementStrategy, factory SolverFactory) {
+	if _, exists := solverRegistry[strategy]; exists {
+		panic(fmt.Sprintf("solver %s is already registered", strategy))
+	}
+	solverRegistry[strategy] = factory
+}
+
+// RegisteredStrategies 返回所有已注册的求解算法名称
+func RegisteredStrategies() []podGroupv1.PlacementStrategy {
+	res := make([]podGroupv1.PlacementStrategy, 0, len(solverRegistry))
+	for k := range solverRegistry {
+		res = append(res, k)
+	}
+	slices.Sort(res)
+	return res
+}
+
+// NewSolver 根据spec.placementStrategy构造Solver，未设置时使用greedy
+// 返回的Solver会拒绝违反problem.Constraints的求解结果
+func NewSolver(spec *podGroupv1.PodGroupSpec) (Solver, error) {
+	strategy := spec.PlacementStrategy
+	if strategy == "" {
+		strategy = podGroupv1.GreedyStrategy
+	}
+	factory, ok := solverRegistry[strategy]
+	if !ok {
+		return nil, fmt.Errorf("unknown placement strategy %s", strategy)
+	}
+	return &constrainedSolver{Solver: factory(spec)}, nil
+}
+
+// constrainedSolver 检查求解结果是否满足硬约束，违反约束时返回ErrConstraintViolated
+type constrainedSolver struct {
+	Solver
+}
+
+func (s *constrainedSolver) Solve(ctx context.Context, problem *Problem) (*Plan, error) {
+	plan, err := s.Solver.Solve(ctx, problem)
+	if err != nil {
+		return nil, err
+	}
+	if err := problem.CheckPlan(plan); err != nil {
+		return nil, fmt.Errorf("solver %s: %w", s.Name(), err)
+	}
+	return plan, nil
+}
+
+// assign2Plan 将求解算法得到的assign数组转换为Plan，超出节点剩余容量的Pod不会被写入Plan
+func assign2Plan(problem *Problem, assign []int, score float64) *Plan {
+	res := &Plan{
+		Assign: make(map[string]string, len(assign)),
+		Score:  score,
+	}
+	nodes := make([]model.Node, len(problem.Nodes))
+	copy(nodes, problem.Nodes)
+	for podIdx, nodeIdx := range assign {
+		pod := problem.Pods[podIdx]
+		if !nodes[nodeIdx].Fits(pod.CPUReq, pod.MemReq) {
+			klog.Warningf("Pod %s does not fit into node %s, leave it to the default scheduler", pod.PodName, nodes[nodeIdx].NodeName)
+			continue
+		}
+		nodes[nodeIdx].Consume(pod.CPUReq, pod.MemReq)
+		res.Assign[pod.PodName] = nodes[nodeIdx].NodeName
+	}
+	return res
+}
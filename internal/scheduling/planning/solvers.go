@@ -0,0 +1,404 @@
+package planning
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"slices"
+	"time"
+
+	podGroupv1 "github.com/SMALL-head/podGroup/api/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/klog/v2"
+)
+
+// 各求解算法参数的默认值
+const (
+	DefaultAnnealingMaxIter     = 100000
+	DefaultAnnealingInitTemp    = 200
+	DefaultAnnealingFinalTemp   = 1
+	DefaultAnnealingCoolingRate = 0.995
+	DefaultAnnealingAlpha       = 0.3
+	DefaultAnnealingBeta        = 0.7
+	// annealing与relative-improvement共用
+	DefaultAnnealingChains           = 4
+	DefaultAnnealingTimeLimitSeconds = 2
+
+	DefaultRelativeImprovementMaxIter     = 10000
+	DefaultRelativeImprovementInitTemp    = 1000
+	DefaultRelativeImprovementFinalTemp   = 0.1
+	DefaultRelativeImprovementCoolingRate = 0.98
+	DefaultRelativeImprovementAlpha       = 0.7
+	DefaultRelativeImprovementBeta        = 0.3
+
+	DefaultExhaustiveAlpha   = 0.4
+	DefaultExhaustiveBeta    = 0.6
+	DefaultExhaustiveMaxPods = 8
+	// ExhaustiveMaxAssigns exhaustive允许枚举的assign数量上限，即节点数量的Pod数量次方，与节点数量无关的maxPods不足以限制搜索规模
+	ExhaustiveMaxAssigns = 10000000
+
+	DefaultBranchAndBoundMaxNodes         = 1000000
+	DefaultBranchAndBoundTimeLimitSeconds = 5
+
+	DefaultPartitioningRefinementPasses = 10
+)
+
+var ErrNoAvailableNode = errors.New("no available node for placement")
+
+func init() {
+	RegisterSolver(podGroupv1.GreedyStrategy, newGreedySolver)
+	RegisterSolver(podGroupv1.AnnealingStrategy, newAnnealingSolver)
+	RegisterSolver(podGroupv1.RelativeImprovementStrategy, newRelativeImprovementSolver)
+	RegisterSolver(podGroupv1.ExhaustiveStrategy, newExhaustiveSolver)
+	RegisterSolver(podGroupv1.BranchAndBoundStrategy, newBranchAndBoundSolver)
+	RegisterSolver(podGroupv1.PartitioningStrategy, newPartitioningSolver)
+}
+
+// greedySolver 按度数从高到低，将Pod依次分配到平均延迟最低、放得下且不违反约束的节点上
+type greedySolver struct{}
+
+func newGreedySolver(_ *podGroupv1.PodGroupSpec) Solver {
+	return &greedySolver{}
+}
+
+func (s *greedySolver) Name() podGroupv1.PlacementStrategy {
+	return podGroupv1.GreedyStrategy
+}
+
+func (s *greedySolver) Solve(ctx context.Context, problem *Problem) (*Plan, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if len(problem.Nodes) == 0 {
+		return nil, ErrNoAvailableNode
+	}
+	podNameList := make([]string, len(problem.Pods))
+	podIdx := make(map[string]int, len(problem.Pods))
+	for i, p := range problem.Pods {
+		podNameList[i] = p.PodName
+		podIdx[p.PodName] = i
+	}
+	podNameListByDegree := SortPodNameListByDegree(problem.PodDependencies, podNameList)
+	podOrder := make([]int, len(podNameListByDegree))
+	for i, podName := range podNameListByDegree {
+		podOrder[i] = podIdx[podName]
+	}
+
+	// 节点按照到其他节点的延迟之和从低到高排序
+	nodeIdx := make([]int, len(problem.Nodes))
+	nodeLatSum := make([]float64, len(problem.Nodes))
+	for i := range problem.Nodes {
+		nodeIdx[i] = i
+		for j := range problem.Nodes {
+			nodeLatSum[i] += problem.NodeLatencies.Get(i, j)
+		}
+	}
+	slices.SortStableFunc(nodeIdx, func(a, b int) int {
+		if nodeLatSum[a] < nodeLatSum[b] {
+			return -1
+		} else if nodeLatSum[a] > nodeLatSum[b] {
+			return 1
+		}
+		return 0
+	})
+
+	nodeBalance := problem.NodeBalance
+	if nodeBalance <= 0 || nodeBalance > len(nodeIdx) {
+		nodeBalance = len(nodeIdx)
+	}
+
+	assign := greedyAssign(problem, podOrder, nodeIdx, nodeBalance)
+	plan := &Plan{Assign: make(map[string]string, len(assign))}
+	for i, n := range assign {
+		if n >= 0 {
+			plan.Assign[problem.Pods[i].PodName] = problem.Nodes[n].NodeName
+		}
+	}
+	if assign, ok := plan2Assign(problem, plan); ok {
+		plan.Score = computeTotalLatency(assign, problem.NodeLatencies, problem.PodDependencies, len(assign))
+	}
+	return plan, nil
+}
+
+// annealingSolver 使用SimulatedAnnealingAssign求解，并行运行chains条退火链
+type annealingSolver struct {
+	alpha, beta                      float64
+	maxIter                          int
+	initTemp, finalTemp, coolingRate float64
+	chains                           int
+	timeLimit                        time.Duration
+}
+
+func newAnnealingSolver(spec *podGroupv1.PodGroupSpec) Solver {
+	var opts *podGroupv1.AnnealingOptions
+	if spec.SolverOptions != nil {
+		opts = spec.SolverOptions.Annealing
+	}
+	if opts == nil {
+		opts = &podGroupv1.AnnealingOptions{}
+	}
+	return &annealingSolver{
+		alpha:       quantityOrDefault(opts.Alpha, DefaultAnnealingAlpha),
+		beta:        quantityOrDefault(opts.Beta, DefaultAnnealingBeta),
+		maxIter:     int32OrDefault(opts.MaxIter, DefaultAnnealingMaxIter),
+		initTemp:    quantityOrDefault(opts.InitTemp, DefaultAnnealingInitTemp),
+		finalTemp:   quantityOrDefault(opts.FinalTemp, DefaultAnnealingFinalTemp),
+		coolingRate: quantityOrDefault(opts.CoolingRate, DefaultAnnealingCoolingRate),
+		chains:      int32OrDefault(opts.Chains, DefaultAnnealingChains),
+		timeLimit:   time.Duration(int32OrDefault(opts.TimeLimitSeconds, DefaultAnnealingTimeLimitSeconds)) * time.Second,
+	}
+}
+
+func (s *annealingSolver) Name() podGroupv1.PlacementStrategy {
+	return podGroupv1.AnnealingStrategy
+}
+
+func (s *annealingSolver) Solve(ctx context.Context, problem *Problem) (*Plan, error) {
+	return s.solve(ctx, problem, func(ctx context.Context, r *rand.Rand, improve func([]int, float64)) ([]int, float64, ChainReport) {
+		return simulatedAnnealingChain(ctx, s.alpha, s.beta,
+			problem.NodeLatencies, problem.PodDependencies,
+			problem.Pods, problem.Nodes, &problem.Constraints,
+			s.maxIter, s.initTemp, s.finalTemp, s.coolingRate, r, improve, false)
+	})
+}
+
+// solve 以problem.Seed为起始种子并行运行chains条退火链，每条链由maxIter与温度决定何时停止，
+// timeLimit只是上限，超出时使用已经找到的最优assign，这些链的StopReason为TimeLimit；ctx结束时返回ctx的错误
+func (s *annealingSolver) solve(ctx context.Context, problem *Problem, anneal annealFunc) (*Plan, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if len(problem.Nodes) == 0 {
+		return nil, ErrNoAvailableNode
+	}
+	searchCtx, cancel := context.WithTimeout(ctx, s.timeLimit)
+	defer cancel()
+	res := runChains(searchCtx, s.chains, problem.Seed, anneal)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	for i, c := range res.Chains {
+		klog.V(2).Infof("Annealing chain %d (seed %d) stopped by %s after %d iterations, best score %f found at iteration %d",
+			i, c.Seed, c.StopReason, c.Iterations, c.BestScore, c.BestIteration)
+	}
+	plan := assign2Plan(problem, res.Assign, res.Score)
+	plan.Chains = res.Chains
+	return plan, nil
+}
+
+// relativeImprovementSolver 使用RelativeImprovementAssign求解，并行运行chains条退火链
+type relativeImprovementSolver struct {
+	annealingSolver
+}
+
+func newRelativeImprovementSolver(spec *podGroupv1.PodGroupSpec) Solver {
+	var opts *podGroupv1.AnnealingOptions
+	if spec.SolverOptions != nil {
+		opts = spec.SolverOptions.RelativeImprovement
+	}
+	if opts == nil {
+		opts = &podGroupv1.AnnealingOptions{}
+	}
+	return &relativeImprovementSolver{annealingSolver{
+		alpha:       quantityOrDefault(opts.Alpha, DefaultRelativeImprovementAlpha),
+		beta:        quantityOrDefault(opts.Beta, DefaultRelativeImprovementBeta),
+		maxIter:     int32OrDefault(opts.MaxIter, DefaultRelativeImprovementMaxIter),
+		initTemp:    quantityOrDefault(opts.InitTemp, DefaultRelativeImprovementInitTemp),
+		finalTemp:   quantityOrDefault(opts.FinalTemp, DefaultRelativeImprovementFinalTemp),
+		coolingRate: quantityOrDefault(opts.CoolingRate, DefaultRelativeImprovementCoolingRate),
+		chains:      int32OrDefault(opts.Chains, DefaultAnnealingChains),
+		timeLimit:   time.Duration(int32OrDefault(opts.TimeLimitSeconds, DefaultAnnealingTimeLimitSeconds)) * time.Second,
+	}}
+}
+
+func (s *relativeImprovementSolver) Name() podGroupv1.PlacementStrategy {
+	return podGroupv1.RelativeImprovementStrategy
+}
+
+func (s *relativeImprovementSolver) Solve(ctx context.Context, problem *Problem) (*Plan, error) {
+	return s.solve(ctx, problem, func(ctx context.Context, r *rand.Rand, improve func([]int, float64)) ([]int, float64, ChainReport) {
+		return relativeImprovementChain(ctx, s.alpha, s.beta,
+			problem.NodeLatencies, problem.PodDependencies,
+			problem.Pods, problem.Nodes, &problem.Constraints,
+			s.maxIter, s.initTemp, s.finalTemp, s.coolingRate, r, improve, false)
+	})
+}
+
+// exhaustiveSolver 使用FindOptimalAssign暴力枚举求解
+type exhaustiveSolver struct {
+	alpha, beta float64
+	maxPods     int
+}
+
+func newExhaustiveSolver(spec *podGroupv1.PodGroupSpec) Solver {
+	var opts *podGroupv1.ExhaustiveOptions
+	if spec.SolverOptions != nil {
+		opts = spec.SolverOptions.Exhaustive
+	}
+	if opts == nil {
+		opts = &podGroupv1.ExhaustiveOptions{}
+	}
+	return &exhaustiveSolver{
+		alpha:   quantityOrDefault(opts.Alpha, DefaultExhaustiveAlpha),
+		beta:    quantityOrDefault(opts.Beta, DefaultExhaustiveBeta),
+		maxPods: int32OrDefault(opts.MaxPods, DefaultExhaustiveMaxPods),
+	}
+}
+
+func (s *exhaustiveSolver) Name() podGroupv1.PlacementStrategy {
+	return podGroupv1.ExhaustiveStrategy
+}
+
+func (s *exhaustiveSolver) Solve(ctx context.Context, problem *Problem) (*Plan, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if len(problem.Nodes) == 0 {
+		return nil, ErrNoAvailableNode
+	}
+	if len(problem.Pods) > s.maxPods {
+		return nil, fmt.Errorf("exhaustive search supports at most %d pods, got %d", s.maxPods, len(problem.Pods))
+	}
+	if !assignsWithin(len(problem.Nodes), len(problem.Pods), ExhaustiveMaxAssigns) {
+		return nil, fmt.Errorf("exhaustive search supports at most %d assigns, got %d nodes ^ %d pods", ExhaustiveMaxAssigns, len(problem.Nodes), len(problem.Pods))
+	}
+	assign, score, err := FindOptimalAssign(ctx, s.alpha, s.beta,
+		problem.NodeLatencies, problem.PodDependencies,
+		problem.Pods, problem.Nodes, &problem.Constraints)
+	if err != nil {
+		return nil, err
+	}
+	return assign2Plan(problem, assign, score), nil
+}
+
+// assignsWithin 判断nodeSize个节点上分配podSize个Pod的全部assign数量，即nodeSize^podSize，是否不超过limit
+func assignsWithin(nodeSize, podSize, limit int) bool {
+	total := 1
+	for i := 0; i < podSize; i++ {
+		if total > limit/nodeSize {
+			return false
+		}
+		total *= nodeSize
+	}
+	return true
+}
+
+// branchAndBoundSolver 使用BranchAndBoundAssign求解，超出预算时返回目前找到的最优placement以及最优性差距
+type branchAndBoundSolver struct {
+	maxNodes  int
+	timeLimit time.Duration
+}
+
+func newBranchAndBoundSolver(spec *podGroupv1.PodGroupSpec) Solver {
+	var opts *podGroupv1.BranchAndBoundOptions
+	if spec.SolverOptions != nil {
+		opts = spec.SolverOptions.BranchAndBound
+	}
+	if opts == nil {
+		opts = &podGroupv1.BranchAndBoundOptions{}
+	}
+	return &branchAndBoundSolver{
+		maxNodes:  int32OrDefault(opts.MaxNodes, DefaultBranchAndBoundMaxNodes),
+		timeLimit: time.Duration(int32OrDefault(opts.TimeLimitSeconds, DefaultBranchAndBoundTimeLimitSeconds)) * time.Second,
+	}
+}
+
+func (s *branchAndBoundSolver) Name() podGroupv1.PlacementStrategy {
+	return podGroupv1.BranchAndBoundStrategy
+}
+
+func (s *branchAndBoundSolver) Solve(ctx context.Context, problem *Problem) (*Plan, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if len(problem.Nodes) == 0 {
+		return nil, ErrNoAvailableNode
+	}
+	searchCtx, cancel := context.WithTimeout(ctx, s.timeLimit)
+	defer cancel()
+	res, err := BranchAndBoundAssign(searchCtx, problem, s.maxNodes)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, ctxErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	if res.Gap > 0 {
+		klog.Infof("Branch-and-bound stopped after exploring %d nodes, best cost %f, optimality gap %.2f%%", res.Explored, res.Cost, res.Gap*100)
+	}
+	plan := assign2Plan(problem, res.Assign, res.Cost)
+	plan.Gap = &res.Gap
+	return plan, nil
+}
+
+// partitioningSolver 使用MultilevelPartitionAssign求解
+type partitioningSolver struct {
+	passes int
+}
+
+func newPartitioningSolver(spec *podGroupv1.PodGroupSpec) Solver {
+	var opts *podGroupv1.PartitioningOptions
+	if spec.SolverOptions != nil {
+		opts = spec.SolverOptions.Partitioning
+	}
+	if opts == nil {
+		opts = &podGroupv1.PartitioningOptions{}
+	}
+	return &partitioningSolver{passes: int32OrDefault(opts.RefinementPasses, DefaultPartitioningRefinementPasses)}
+}
+
+func (s *partitioningSolver) Name() podGroupv1.PlacementStrategy {
+	return podGroupv1.PartitioningStrategy
+}
+
+func (s *partitioningSolver) Solve(ctx context.Context, problem *Problem) (*Plan, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if len(problem.Nodes) == 0 {
+		return nil, ErrNoAvailableNode
+	}
+	assign := MultilevelPartitionAssign(problem, s.passes)
+	plan := &Plan{Assign: make(map[string]string, len(assign))}
+	for i, n := range assign {
+		if n < 0 {
+			klog.Warningf("Pod %s does not fit into any node, leave it to the default scheduler", problem.Pods[i].PodName)
+			continue
+		}
+		plan.Assign[problem.Pods[i].PodName] = problem.Nodes[n].NodeName
+	}
+	plan.Score = problem.Cost(plan.Assign)
+	return plan, nil
+}
+
+// plan2Assign 将Plan转换为assign数组，Plan中缺少某个Pod或者节点未知时返回false
+func plan2Assign(problem *Problem, plan *Plan) ([]int, bool) {
+	nodeIdx := make(map[string]int, len(problem.Nodes))
+	for i, n := range problem.Nodes {
+		nodeIdx[n.NodeName] = i
+	}
+	assign := make([]int, len(problem.Pods))
+	for i, p := range problem.Pods {
+		idx, ok := nodeIdx[plan.Assign[p.PodName]]
+		if !ok {
+			return nil, false
+		}
+		assign[i] = idx
+	}
+	return assign, true
+}
+
+func quantityOrDefault(q *resource.Quantity, def float64) float64 {
+	if q == nil {
+		return def
+	}
+	return q.AsApproximateFloat64()
+}
+
+func int32OrDefault(v *int32, def int) int {
+	if v == nil {
+		return def
+	}
+	return int(*v)
+}
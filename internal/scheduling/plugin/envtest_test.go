@@ -0,0 +1,107 @@
+package plugin
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	podGroupv1 "github.com/SMALL-head/podGroup/api/v1"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+)
+
+// envtestBinaryDir 返回envtest使用的kube-apiserver与etcd所在的目录，与controller的测试一致，
+// 优先使用KUBEBUILDER_ASSETS（make test设置），其次是make setup-envtest下载到bin/k8s中的第一个版本，都不存在时返回空
+func envtestBinaryDir() string {
+	if dir := os.Getenv("KUBEBUILDER_ASSETS"); dir != "" {
+		return dir
+	}
+	basePath := filepath.Join("..", "..", "..", "bin", "k8s")
+	entries, err := os.ReadDir(basePath)
+	if err != nil {
+		return ""
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			return filepath.Join(basePath, entry.Name())
+		}
+	}
+	return ""
+}
+
+// TestManagerEnvtest 使用envtest启动的API Server运行Manager，覆盖fake client不检查的CRD校验与status子资源
+// 调度框架本身需要完整的kube-scheduler，不在这里启动，各扩展点只是将Manager的结果转换为framework.Status
+func TestManagerEnvtest(t *testing.T) {
+	dir := envtestBinaryDir()
+	if dir == "" {
+		t.Skip("envtest binaries not found, run make setup-envtest or make test")
+	}
+	testEnv := &envtest.Environment{
+		CRDDirectoryPaths:     []string{filepath.Join("..", "..", "..", "config", "crd", "bases")},
+		ErrorIfCRDPathMissing: true,
+		BinaryAssetsDirectory: dir,
+	}
+	cfg, err := testEnv.Start()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, testEnv.Stop())
+	})
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, podGroupv1.AddToScheme(scheme))
+	c, err := client.New(cfg, client.Options{Scheme: scheme})
+	require.NoError(t, err)
+	m := NewManager(c)
+
+	ctx := context.Background()
+	pg := newTestPodGroup(3)
+	pg.UID = ""
+	pg.Spec.PodList[1].Workload = podGroupv1.DeploymentWorkload
+	container := v1.Container{Name: "c", Image: "busybox"}
+	for i := range pg.Spec.PodList {
+		pg.Spec.PodList[i].Spec.Containers = []v1.Container{container}
+	}
+	status := pg.Status
+	require.NoError(t, c.Create(ctx, pg))
+	pg.Status = status
+	require.NoError(t, c.Status().Update(ctx, pg))
+
+	pod1, pod3 := newTestMember(pg, "pod1", ""), newTestMember(pg, "pod3", "")
+	pod2 := newTestWorkloadMember(pg, "pod2", "pod2-abc", "")
+	for _, pod := range []*v1.Pod{pod1, pod2, pod3} {
+		pod.UID = ""
+		pod.Spec.Containers = []v1.Container{container}
+		require.NoError(t, c.Create(ctx, pod))
+	}
+
+	// 直接创建的Pod与工作负载创建的Pod都按照成员名称取得计划节点
+	node, err := m.PreFilter(ctx, pod1)
+	require.NoError(t, err)
+	require.Equal(t, "node1", node)
+	node, err = m.PreFilter(ctx, pod2)
+	require.NoError(t, err)
+	require.Equal(t, "node2", node)
+
+	// pod1与工作负载创建的pod2正在等待时，pod3进入Permit后达到minMember
+	allow, _, err := m.Permit(ctx, pod3, nil)
+	require.NoError(t, err)
+	require.False(t, allow)
+	allow, _, err = m.Permit(ctx, pod3, []*v1.Pod{pod1, pod2})
+	require.NoError(t, err)
+	require.True(t, allow)
+
+	// 绑定节点写入status子资源
+	require.NoError(t, m.PostBind(ctx, pod2, "node2"))
+	got := &podGroupv1.PodGroup{}
+	require.NoError(t, c.Get(ctx, client.ObjectKeyFromObject(pg), got))
+	binding := got.Status.ScheduleResult[1]
+	require.Equal(t, "pod2", binding.PodName)
+	require.Equal(t, "node2", binding.NodeName)
+	require.Equal(t, string(pod2.UID), binding.PodUID)
+	require.True(t, *binding.Matched)
+}
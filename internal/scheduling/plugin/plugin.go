@@ -0,0 +1,135 @@
+//go:build scheduler
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	podGroupv1 "github.com/SMALL-head/podGroup/api/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// PodGroupPlacement 将Manager接入kube-scheduler调度框架（v1.33）
+//   - PreFilter: 成员Pod不足minMember个时拒绝调度，并记录计划节点
+//   - Score: 计划节点得分最高
+//   - Permit: 同一PodGroup中达到minMember个Pod后一起进入绑定阶段
+//   - PostBind: 将实际绑定的节点写入PodGroup的status
+type PodGroupPlacement struct {
+	handle  framework.Handle
+	manager *Manager
+}
+
+var (
+	_ framework.PreFilterPlugin = &PodGroupPlacement{}
+	_ framework.ScorePlugin     = &PodGroupPlacement{}
+	_ framework.PermitPlugin    = &PodGroupPlacement{}
+	_ framework.PostBindPlugin  = &PodGroupPlacement{}
+)
+
+const stateKey framework.StateKey = Name
+
+// plannedNodeState 在一个调度周期内保存PreFilter得到的计划节点
+type plannedNodeState struct {
+	node string
+}
+
+func (s *plannedNodeState) Clone() framework.StateData {
+	return s
+}
+
+// New 调度框架的PluginFactory
+func New(_ context.Context, _ runtime.Object, handle framework.Handle) (framework.Plugin, error) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+	if err := podGroupv1.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+	c, err := client.New(handle.KubeConfig(), client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, err
+	}
+	return &PodGroupPlacement{handle: handle, manager: NewManager(c)}, nil
+}
+
+func (p *PodGroupPlacement) Name() string {
+	return Name
+}
+
+func (p *PodGroupPlacement) PreFilter(ctx context.Context, state *framework.CycleState, pod *v1.Pod) (*framework.PreFilterResult, *framework.Status) {
+	node, err := p.manager.PreFilter(ctx, pod)
+	if errors.Is(err, ErrNotEnoughMembers) {
+		return nil, framework.NewStatus(framework.UnschedulableAndUnresolvable, err.Error())
+	}
+	if err != nil {
+		return nil, framework.AsStatus(err)
+	}
+	state.Write(stateKey, &plannedNodeState{node: node})
+	return nil, nil
+}
+
+func (p *PodGroupPlacement) PreFilterExtensions() framework.PreFilterExtensions {
+	return nil
+}
+
+func (p *PodGroupPlacement) Score(_ context.Context, state *framework.CycleState, _ *v1.Pod, nodeInfo *framework.NodeInfo) (int64, *framework.Status) {
+	data, err := state.Read(stateKey)
+	if err != nil {
+		return 0, nil
+	}
+	return Score(data.(*plannedNodeState).node, nodeInfo.Node().Name), nil
+}
+
+func (p *PodGroupPlacement) ScoreExtensions() framework.ScoreExtensions {
+	return nil
+}
+
+func (p *PodGroupPlacement) Permit(ctx context.Context, _ *framework.CycleState, pod *v1.Pod, _ string) (*framework.Status, time.Duration) {
+	key, ok := podGroupKey(pod)
+	if !ok {
+		return framework.NewStatus(framework.Success), 0
+	}
+	var waiting []*v1.Pod
+	p.iterateWaitingMembers(key, func(wp framework.WaitingPod) {
+		waiting = append(waiting, wp.GetPod())
+	})
+
+	allow, timeout, err := p.manager.Permit(ctx, pod, waiting)
+	if err != nil {
+		return framework.AsStatus(err), 0
+	}
+	if !allow {
+		klog.V(4).Infof("Pod %s/%s waits for other members of its PodGroup", pod.Namespace, pod.Name)
+		return framework.NewStatus(framework.Wait), timeout
+	}
+	// 达到minMember后，同一PodGroup中正在等待的Pod一起进入绑定阶段
+	p.iterateWaitingMembers(key, func(wp framework.WaitingPod) {
+		wp.Allow(Name)
+	})
+	return framework.NewStatus(framework.Success), 0
+}
+
+func (p *PodGroupPlacement) PostBind(ctx context.Context, _ *framework.CycleState, pod *v1.Pod, nodeName string) {
+	if err := p.manager.PostBind(ctx, pod, nodeName); err != nil {
+		klog.Errorf("Failed to record binding of Pod %s/%s, err: %v", pod.Namespace, pod.Name, err)
+	}
+}
+
+// iterateWaitingMembers 遍历Permit阶段中属于key对应PodGroup的Pod
+// 成员的工作负载创建的Pod由ReplicaSet、StatefulSet或者Job控制，因此按照podGroupKey而不是controller分组
+func (p *PodGroupPlacement) iterateWaitingMembers(key types.NamespacedName, fn func(framework.WaitingPod)) {
+	p.handle.IterateOverWaitingPods(func(wp framework.WaitingPod) {
+		if k, ok := podGroupKey(wp.GetPod()); ok && k == key {
+			fn(wp)
+		}
+	})
+}
@@ -0,0 +1,72 @@
+//go:build scheduler
+
+package plugin
+
+import (
+	"context"
+	"testing"
+
+	podGroupv1 "github.com/SMALL-head/podGroup/api/v1"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+// fakeWaitingPod 记录Permit阶段等待的Pod是否被允许绑定
+type fakeWaitingPod struct {
+	pod     *v1.Pod
+	allowed bool
+}
+
+func (w *fakeWaitingPod) GetPod() *v1.Pod             { return w.pod }
+func (w *fakeWaitingPod) GetPendingPlugins() []string { return []string{Name} }
+func (w *fakeWaitingPod) Allow(string)                { w.allowed = true }
+func (w *fakeWaitingPod) Reject(string, string)       {}
+
+// fakeHandle 只实现Permit用到的IterateOverWaitingPods
+type fakeHandle struct {
+	framework.Handle
+	waiting []*fakeWaitingPod
+}
+
+func (h *fakeHandle) add(pod *v1.Pod) *fakeWaitingPod {
+	wp := &fakeWaitingPod{pod: pod}
+	h.waiting = append(h.waiting, wp)
+	return wp
+}
+
+func (h *fakeHandle) IterateOverWaitingPods(callback func(framework.WaitingPod)) {
+	for _, wp := range h.waiting {
+		callback(wp)
+	}
+}
+
+func TestPermitWaitingMembers(t *testing.T) {
+	ctx := context.Background()
+	pg := newTestPodGroup(3)
+	pg.Spec.PodList[1].Workload = podGroupv1.DeploymentWorkload
+	pod1, pod3 := newTestMember(pg, "pod1", ""), newTestMember(pg, "pod3", "")
+	pod2 := newTestWorkloadMember(pg, "pod2", "pod2-abc", "")
+	other := newTestPodGroup(3)
+	other.Name, other.UID = "other", "other-uid"
+	stranger := newTestMember(other, "pod1", "")
+	stranger.Name = "other-pod1"
+	m, _ := newTestManager(t, pg, pod1, pod2, pod3, other, stranger)
+	handle := &fakeHandle{}
+	p := &PodGroupPlacement{handle: handle, manager: m}
+
+	// 只有pod1在等待时，工作负载创建的pod2不足minMember
+	waiting1 := handle.add(pod1)
+	waitingStranger := handle.add(stranger)
+	status, timeout := p.Permit(ctx, nil, pod2, "node2")
+	require.Equal(t, framework.Wait, status.Code())
+	require.Equal(t, DefaultPermitTimeout, timeout)
+
+	// 直接创建的pod1与工作负载创建的pod2一起等待，pod3进入Permit后达到minMember，同一PodGroup中等待的Pod一起放行
+	waiting2 := handle.add(pod2)
+	status, _ = p.Permit(ctx, nil, pod3, "node3")
+	require.True(t, status.IsSuccess())
+	require.True(t, waiting1.allowed)
+	require.True(t, waiting2.allowed)
+	require.False(t, waitingStranger.allowed)
+}
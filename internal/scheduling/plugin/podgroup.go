@@ -0,0 +1,227 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	podGroupv1 "github.com/SMALL-head/podGroup/api/v1"
+	"github.com/SMALL-head/podGroup/internal/scheduling/model"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// Name 调度插件名称
+	Name = "PodGroupPlacement"
+	// MaxNodeScore 与调度框架中framework.MaxNodeScore一致
+	MaxNodeScore int64 = 100
+	// DefaultPermitTimeout PodGroup未设置scheduleTimeoutSeconds时，Permit阶段等待其他成员Pod的时间
+	DefaultPermitTimeout = 60 * time.Second
+)
+
+// ErrNotEnoughMembers 已经创建的成员Pod不足minMember个，PodGroup中的Pod暂不调度
+var ErrNotEnoughMembers = errors.New("not enough member pods created")
+
+// Manager 实现podGroup-scheduler中与调度框架无关的逻辑，调度框架的各个扩展点只负责将其结果转换为framework.Status
+// 所有方法只依赖controller-runtime的client，可以直接使用envtest或者fake client进行测试
+type Manager struct {
+	client client.Client
+}
+
+func NewManager(c client.Client) *Manager {
+	return &Manager{client: c}
+}
+
+// podGroupKey 返回Pod可能所属的PodGroup，由PodGroup直接创建的Pod为其controller，
+// 由成员的工作负载创建的Pod通过PodGroupNameLabel确定，没有controller或者两者都不满足时返回false
+// 只根据Pod本身判断，是否为成员Pod还需要通过model.IsMemberPod确认
+func podGroupKey(pod *v1.Pod) (types.NamespacedName, bool) {
+	ref := metav1.GetControllerOf(pod)
+	if ref == nil {
+		return types.NamespacedName{}, false
+	}
+	name := ref.Name
+	if ref.Kind != "PodGroup" || ref.APIVersion != podGroupv1.GroupVersion.String() {
+		name = pod.Labels[model.PodGroupNameLabel]
+	}
+	return types.NamespacedName{Namespace: pod.Namespace, Name: name}, name != ""
+}
+
+// PodGroupOf 返回Pod所属的PodGroup，Pod不是PodGroup的成员Pod时返回nil
+// 由成员的工作负载创建的Pod通过PodGroupNameLabel确定所属的PodGroup
+func (m *Manager) PodGroupOf(ctx context.Context, pod *v1.Pod) (*podGroupv1.PodGroup, error) {
+	key, ok := podGroupKey(pod)
+	if !ok {
+		return nil, nil
+	}
+	podGroup := &podGroupv1.PodGroup{}
+	if err := m.client.Get(ctx, key, podGroup); err != nil {
+		return nil, client.IgnoreNotFound(err)
+	}
+	if !model.IsMemberPod(podGroup, pod) {
+		return nil, nil
+	}
+	return podGroup, nil
+}
+
+// PreFilter 已经创建的成员Pod不足minMember个时返回ErrNotEnoughMembers，否则返回该Pod的计划节点
+// 计划节点为空表示placement降级为默认调度器或者该Pod不属于任何PodGroup
+func (m *Manager) PreFilter(ctx context.Context, pod *v1.Pod) (string, error) {
+	podGroup, err := m.PodGroupOf(ctx, pod)
+	if err != nil || podGroup == nil {
+		return "", err
+	}
+	members, err := m.listMembers(ctx, podGroup)
+	if err != nil {
+		return "", err
+	}
+	if created, quorum := len(members), minMember(podGroup); created < quorum {
+		return "", fmt.Errorf("%w: %d/%d pods of PodGroup %s created", ErrNotEnoughMembers, created, quorum, podGroup.Name)
+	}
+	return plannedNode(podGroup, model.MemberOf(pod)), nil
+}
+
+// Score 计划节点得分最高，其余节点不加分，由其他打分插件决定
+func Score(plannedNode, nodeName string) int64 {
+	if plannedNode != "" && plannedNode == nodeName {
+		return MaxNodeScore
+	}
+	return 0
+}
+
+// Permit 判断Pod是否可以进入绑定阶段，waiting为正在Permit阶段等待的其他Pod，其中不属于该PodGroup的Pod不计入
+// 已经绑定节点的成员、正在等待的成员以及当前Pod所属的成员一共达到minMember个时允许绑定，否则需要等待timeout
+// 由PodGroup直接创建的Pod与由成员的工作负载创建的Pod一起统计，同一成员的多个Pod只计一次
+func (m *Manager) Permit(ctx context.Context, pod *v1.Pod, waiting []*v1.Pod) (allow bool, timeout time.Duration, err error) {
+	podGroup, err := m.PodGroupOf(ctx, pod)
+	if err != nil || podGroup == nil {
+		return true, 0, err
+	}
+	members, err := m.listMembers(ctx, podGroup)
+	if err != nil {
+		return false, 0, err
+	}
+	ready := make(map[string]bool, len(members)+len(waiting))
+	for member, p := range members {
+		if p.Spec.NodeName != "" {
+			ready[member] = true
+		}
+	}
+	for _, w := range waiting {
+		if model.IsMemberPod(podGroup, w) {
+			ready[model.MemberOf(w)] = true
+		}
+	}
+	ready[model.MemberOf(pod)] = true
+	if len(ready) >= minMember(podGroup) {
+		return true, 0, nil
+	}
+	timeout = DefaultPermitTimeout
+	if podGroup.Spec.ScheduleTimeoutSeconds != nil {
+		timeout = time.Duration(*podGroup.Spec.ScheduleTimeoutSeconds) * time.Second
+	}
+	return false, timeout, nil
+}
+
+// PostBind 将Pod实际绑定的节点写入PodGroup的status.scheduleResult，绑定节点的成员Pod达到minMember个时将Phase置为Scheduled
+func (m *Manager) PostBind(ctx context.Context, pod *v1.Pod, nodeName string) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		podGroup, err := m.PodGroupOf(ctx, pod)
+		if err != nil || podGroup == nil {
+			return err
+		}
+		members, err := m.listMembers(ctx, podGroup)
+		if err != nil {
+			return err
+		}
+
+		setBinding(&podGroup.Status, pod, nodeName)
+		bound := boundMembers(members, pod) + 1
+		if podGroup.Status.Phase == podGroupv1.SchedulingPhase && bound >= minMember(podGroup) {
+			klog.Infof("PodGroup %s/%s phase changed: %s -> %s", podGroup.Namespace, podGroup.Name, podGroup.Status.Phase, podGroupv1.ScheduledPhase)
+			podGroup.Status.Phase = podGroupv1.ScheduledPhase
+		}
+		return m.client.Status().Update(ctx, podGroup)
+	})
+}
+
+// listMembers 返回该PodGroup未被删除的成员Pod，key为成员名称
+// 与controller一致，成员Pod包括由PodGroup直接创建的Pod以及由成员的Deployment、StatefulSet、Job创建的Pod，
+// 工作负载滚动更新时同一成员可能同时存在多个Pod，已经绑定节点的Pod优先
+func (m *Manager) listMembers(ctx context.Context, podGroup *podGroupv1.PodGroup) (map[string]*v1.Pod, error) {
+	podList := &v1.PodList{}
+	if err := m.client.List(ctx, podList, client.InNamespace(podGroup.Namespace)); err != nil {
+		return nil, err
+	}
+	res := make(map[string]*v1.Pod, podGroup.Spec.MemberCount())
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		if !model.IsMemberPod(podGroup, pod) || !pod.DeletionTimestamp.IsZero() {
+			continue
+		}
+		member := model.MemberOf(pod)
+		if prev, ok := res[member]; !ok || prev.Spec.NodeName == "" {
+			res[member] = pod
+		}
+	}
+	return res, nil
+}
+
+// boundMembers 返回除pod所属成员之外已经绑定节点的成员数量
+func boundMembers(members map[string]*v1.Pod, pod *v1.Pod) int {
+	self := model.MemberOf(pod)
+	bound := 0
+	for member, p := range members {
+		if member != self && p.Spec.NodeName != "" {
+			bound++
+		}
+	}
+	return bound
+}
+
+// setBinding 更新status中Pod的实际绑定节点
+func setBinding(status *podGroupv1.PodGroupStatus, pod *v1.Pod, nodeName string) {
+	for i := range status.ScheduleResult {
+		binding := &status.ScheduleResult[i]
+		if binding.PodName != model.MemberOf(pod) {
+			continue
+		}
+		binding.PodUID = string(pod.UID)
+		binding.NodeName = nodeName
+		if binding.PlannedNodeName != "" {
+			matched := binding.PlannedNodeName == nodeName
+			binding.Matched = &matched
+		}
+		return
+	}
+	status.ScheduleResult = append(status.ScheduleResult, podGroupv1.PodNodeBinding{
+		PodUID:   string(pod.UID),
+		PodName:  model.MemberOf(pod),
+		NodeName: nodeName,
+	})
+}
+
+// plannedNode 返回status中记录的成员的计划节点
+func plannedNode(podGroup *podGroupv1.PodGroup, member string) string {
+	for _, binding := range podGroup.Status.ScheduleResult {
+		if binding.PodName == member {
+			return binding.PlannedNodeName
+		}
+	}
+	return ""
+}
+
+// minMember 返回spec.minMember，未设置或者超过成员Pod数量时为全部成员Pod
+func minMember(podGroup *podGroupv1.PodGroup) int {
+	total := podGroup.Spec.MemberCount()
+	if podGroup.Spec.MinMember == nil || int(*podGroup.Spec.MinMember) > total {
+		return total
+	}
+	return int(*podGroup.Spec.MinMember)
+}
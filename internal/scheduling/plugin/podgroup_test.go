@@ -0,0 +1,178 @@
+package plugin
+
+import (
+	"context"
+	"testing"
+
+	podGroupv1 "github.com/SMALL-head/podGroup/api/v1"
+	"github.com/SMALL-head/podGroup/internal/scheduling/model"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newTestManager(t *testing.T, objs ...client.Object) (*Manager, client.Client) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, podGroupv1.AddToScheme(scheme))
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).WithStatusSubresource(&podGroupv1.PodGroup{}).Build()
+	return NewManager(c), c
+}
+
+func newTestPodGroup(minMember int32) *podGroupv1.PodGroup {
+	return &podGroupv1.PodGroup{
+		ObjectMeta: metav1.ObjectMeta{Name: "pg", Namespace: "default", UID: "pg-uid"},
+		Spec: podGroupv1.PodGroupSpec{
+			MinMember: &minMember,
+			PodList: []podGroupv1.PodTemplate{
+				{Metadata: podGroupv1.PodMetadata{Name: "pod1"}},
+				{Metadata: podGroupv1.PodMetadata{Name: "pod2"}},
+				{Metadata: podGroupv1.PodMetadata{Name: "pod3"}},
+			},
+		},
+		Status: podGroupv1.PodGroupStatus{
+			Phase: podGroupv1.SchedulingPhase,
+			ScheduleResult: []podGroupv1.PodNodeBinding{
+				{PodName: "pod1", PlannedNodeName: "node1"},
+				{PodName: "pod2", PlannedNodeName: "node2"},
+				{PodName: "pod3"},
+			},
+		},
+	}
+}
+
+func newTestMember(podGroup *podGroupv1.PodGroup, name, nodeName string) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       podGroup.Namespace,
+			UID:             types.UID("uid-" + name),
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(podGroup, podGroupv1.GroupVersion.WithKind("PodGroup"))},
+		},
+		Spec: v1.PodSpec{NodeName: nodeName},
+	}
+}
+
+// newTestWorkloadMember 返回成员name的工作负载创建的Pod，Pod名称与成员名称不同，由ReplicaSet控制
+func newTestWorkloadMember(podGroup *podGroupv1.PodGroup, name, podName, nodeName string) *v1.Pod {
+	isController := true
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      podName,
+			Namespace: podGroup.Namespace,
+			UID:       types.UID("uid-" + podName),
+			Labels:    map[string]string{model.PodGroupNameLabel: podGroup.Name, model.PodGroupMemberLabel: name},
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: "apps/v1", Kind: "ReplicaSet", Name: name + "-rs", UID: types.UID("uid-" + name + "-rs"), Controller: &isController,
+			}},
+		},
+		Spec: v1.PodSpec{NodeName: nodeName},
+	}
+}
+
+func TestPreFilter(t *testing.T) {
+	ctx := context.Background()
+	pg := newTestPodGroup(2)
+	pod1 := newTestMember(pg, "pod1", "")
+	m, c := newTestManager(t, pg, pod1)
+
+	// 只创建了1个成员Pod
+	_, err := m.PreFilter(ctx, pod1)
+	require.ErrorIs(t, err, ErrNotEnoughMembers)
+
+	require.NoError(t, c.Create(ctx, newTestMember(pg, "pod2", "")))
+	node, err := m.PreFilter(ctx, pod1)
+	require.NoError(t, err)
+	require.Equal(t, "node1", node)
+
+	// 不属于PodGroup的Pod不受影响
+	node, err = m.PreFilter(ctx, &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "default"}})
+	require.NoError(t, err)
+	require.Empty(t, node)
+
+	require.Equal(t, MaxNodeScore, Score("node1", "node1"))
+	require.Equal(t, int64(0), Score("node1", "node2"))
+	require.Equal(t, int64(0), Score("", "node2"))
+}
+
+func TestPermit(t *testing.T) {
+	ctx := context.Background()
+	pg := newTestPodGroup(3)
+	pod1, pod2, pod3 := newTestMember(pg, "pod1", "node1"), newTestMember(pg, "pod2", ""), newTestMember(pg, "pod3", "")
+	m, _ := newTestManager(t, pg, pod1, pod2, pod3)
+
+	// pod1已经绑定，pod2与pod3都还没有进入Permit
+	allow, timeout, err := m.Permit(ctx, pod2, nil)
+	require.NoError(t, err)
+	require.False(t, allow)
+	require.Equal(t, DefaultPermitTimeout, timeout)
+
+	// pod2正在等待时pod3进入Permit，达到minMember
+	allow, _, err = m.Permit(ctx, pod3, []*v1.Pod{pod2})
+	require.NoError(t, err)
+	require.True(t, allow)
+}
+
+func TestPostBind(t *testing.T) {
+	ctx := context.Background()
+	pg := newTestPodGroup(2)
+	pod1, pod2 := newTestMember(pg, "pod1", "node1"), newTestMember(pg, "pod2", "")
+	m, c := newTestManager(t, pg, pod1, pod2)
+
+	require.NoError(t, m.PostBind(ctx, pod2, "node3"))
+	got := &podGroupv1.PodGroup{}
+	require.NoError(t, c.Get(ctx, client.ObjectKeyFromObject(pg), got))
+	require.Equal(t, podGroupv1.ScheduledPhase, got.Status.Phase)
+	binding := got.Status.ScheduleResult[1]
+	require.Equal(t, "node3", binding.NodeName)
+	require.Equal(t, "uid-pod2", binding.PodUID)
+	require.False(t, *binding.Matched)
+}
+
+func TestWorkloadMember(t *testing.T) {
+	ctx := context.Background()
+	pg := newTestPodGroup(3)
+	pg.Spec.PodList[1].Workload = podGroupv1.DeploymentWorkload
+	pod1, pod3 := newTestMember(pg, "pod1", "node1"), newTestMember(pg, "pod3", "")
+	pod2 := newTestWorkloadMember(pg, "pod2", "pod2-abc", "")
+	// pod3的模板不是工作负载，带有PodGroup标签但不由PodGroup控制的Pod不是成员Pod
+	stray := newTestWorkloadMember(pg, "pod3", "pod3-abc", "node3")
+	m, c := newTestManager(t, pg, pod1, pod2, pod3, stray)
+
+	got, err := m.PodGroupOf(ctx, pod2)
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	got, err = m.PodGroupOf(ctx, stray)
+	require.NoError(t, err)
+	require.Nil(t, got)
+
+	// 工作负载创建的Pod按照成员名称取得计划节点
+	node, err := m.PreFilter(ctx, pod2)
+	require.NoError(t, err)
+	require.Equal(t, "node2", node)
+
+	// pod1已经绑定，pod3正在等待，工作负载创建的pod2进入Permit后达到minMember
+	allow, _, err := m.Permit(ctx, pod2, []*v1.Pod{pod3})
+	require.NoError(t, err)
+	require.True(t, allow)
+
+	require.NoError(t, m.PostBind(ctx, pod2, "node2"))
+	res := &podGroupv1.PodGroup{}
+	require.NoError(t, c.Get(ctx, client.ObjectKeyFromObject(pg), res))
+	require.Len(t, res.Status.ScheduleResult, 3)
+	binding := res.Status.ScheduleResult[1]
+	require.Equal(t, "pod2", binding.PodName)
+	require.Equal(t, "uid-pod2-abc", binding.PodUID)
+	require.True(t, *binding.Matched)
+
+	// 工作负载滚动更新时同一成员的多个Pod只统计一次
+	require.NoError(t, c.Create(ctx, newTestWorkloadMember(pg, "pod2", "pod2-def", "")))
+	require.NoError(t, c.Delete(ctx, pod3))
+	_, err = m.PreFilter(ctx, pod1)
+	require.ErrorIs(t, err, ErrNotEnoughMembers)
+}
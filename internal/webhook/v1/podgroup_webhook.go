@@ -19,8 +19,14 @@ package v1
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
 	ctrl "sigs.k8s.io/controller-runtime"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
@@ -63,17 +69,7 @@ func (v *PodGroupCustomValidator) ValidateCreate(_ context.Context, obj runtime.
 	}
 	podgrouplog.Info("Validation for PodGroup upon creation", "name", podgroup.GetName())
 
-	m := make(map[string]bool)
-
-	for _, pod := range podgroup.Spec.PodList {
-		if _, exists := m[pod.Metadata.Name]; exists {
-			return nil, fmt.Errorf("pod name %s is duplicated in PodGroup %s", pod.Metadata.Name, podgroup.GetName())
-		} else {
-			m[pod.Metadata.Name] = true
-		}
-	}
-
-	return nil, nil
+	return nil, validatePodGroup(podgroup)
 }
 
 // ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type PodGroup.
@@ -84,10 +80,278 @@ func (v *PodGroupCustomValidator) ValidateUpdate(_ context.Context, oldObj, newO
 	}
 	podgrouplog.Info("Validation for PodGroup upon update", "name", podgroup.GetName())
 
-	return nil, nil
+	if err := validatePodGroup(podgroup); err != nil {
+		return nil, err
+	}
+	oldPodGroup, ok := oldObj.(*corev1.PodGroup)
+	if !ok {
+		return nil, fmt.Errorf("expected a PodGroup object for the oldObj but got %T", oldObj)
+	}
+	return nil, validateJobTemplates(oldPodGroup, podgroup)
+}
+
+// validateJobTemplates Job的Pod模板不可修改，因此拒绝修改Job成员的模板，replicas可以修改
+// 需要修改时先删除该模板或者将workload改为其他类型，由controller重新创建工作负载
+func validateJobTemplates(oldPodGroup, podgroup *corev1.PodGroup) error {
+	for i := range podgroup.Spec.PodList {
+		template := &podgroup.Spec.PodList[i]
+		if template.Workload != corev1.JobWorkload {
+			continue
+		}
+		for j := range oldPodGroup.Spec.PodList {
+			old := &oldPodGroup.Spec.PodList[j]
+			if old.Metadata.Name != template.Metadata.Name || old.Workload != corev1.JobWorkload {
+				continue
+			}
+			if !equality.Semantic.DeepEqual(old.Metadata, template.Metadata) || !equality.Semantic.DeepEqual(old.Spec, template.Spec) {
+				return fmt.Errorf("template %s of Job members cannot be changed, remove it or change its workload to recreate the Jobs", template.Metadata.Name)
+			}
+		}
+	}
+	return nil
 }
 
 // ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type PodGroup.
 func (v *PodGroupCustomValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
 	return nil, nil
 }
+
+// validatePodGroup 校验PodGroup中的Pod名称以及依赖关系
+func validatePodGroup(podgroup *corev1.PodGroup) error {
+	m := make(map[string]bool)
+
+	for _, pod := range podgroup.Spec.PodList {
+		if _, exists := m[pod.Metadata.Name]; exists {
+			return fmt.Errorf("pod name %s is duplicated in PodGroup %s", pod.Metadata.Name, podgroup.GetName())
+		} else {
+			m[pod.Metadata.Name] = true
+		}
+	}
+	// 副本展开后的成员Pod名称同样不能重复，例如模板worker的副本worker-0与名为worker-0的模板
+	members := make(map[string]bool)
+	for _, pod := range podgroup.Spec.PodList {
+		if pod.Replicas != nil && *pod.Replicas < 0 {
+			return fmt.Errorf("pod %s: replicas must not be negative, got %d", pod.Metadata.Name, *pod.Replicas)
+		}
+		for _, name := range pod.MemberNames() {
+			if members[name] {
+				return fmt.Errorf("member pod name %s of pod %s is duplicated in PodGroup %s", name, pod.Metadata.Name, podgroup.GetName())
+			}
+			members[name] = true
+		}
+	}
+
+	for _, pod := range podgroup.Spec.PodList {
+		if err := validateWorkload(podgroup, &pod); err != nil {
+			return err
+		}
+	}
+
+	if mm := podgroup.Spec.MinMember; mm != nil && (*mm < 1 || int(*mm) > len(members)) {
+		return fmt.Errorf("minMember must be in range [1, %d], got %d", len(members), *mm)
+	}
+	if timeout := podgroup.Spec.ScheduleTimeoutSeconds; timeout != nil && *timeout < 1 {
+		return fmt.Errorf("scheduleTimeoutSeconds must be positive, got %d", *timeout)
+	}
+	if key := podgroup.Spec.TopologyKey; key != "" {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return fmt.Errorf("topologyKey %s is not a valid label key: %s", key, strings.Join(errs, "; "))
+		}
+	}
+	// podAffinity渲染方式下PodGroup名称与Pod名称会作为成员Pod的标签值
+	if podgroup.Spec.PlacementRendering == corev1.PodAffinityRendering {
+		if errs := validation.IsValidLabelValue(podgroup.GetName()); len(errs) > 0 {
+			return fmt.Errorf("PodGroup name %s can not be used as a label value with podAffinity rendering: %s", podgroup.GetName(), strings.Join(errs, "; "))
+		}
+		for name := range members {
+			if errs := validation.IsValidLabelValue(name); len(errs) > 0 {
+				return fmt.Errorf("pod name %s can not be used as a label value with podAffinity rendering: %s", name, strings.Join(errs, "; "))
+			}
+		}
+	}
+	if err := validateServiceMode(podgroup, m, members); err != nil {
+		return err
+	}
+
+	for i, dep := range podgroup.Spec.Dependencies {
+		if !m[dep.P1] {
+			return fmt.Errorf("dependencies[%d]: pod %s is not found in podList", i, dep.P1)
+		}
+		if !m[dep.P2] {
+			return fmt.Errorf("dependencies[%d]: pod %s is not found in podList", i, dep.P2)
+		}
+		if dep.P1Index != nil && podgroup.Spec.ResolveMembers(dep.P1, dep.P1Index) == nil {
+			return fmt.Errorf("dependencies[%d]: p1Index %d is invalid for pod %s", i, *dep.P1Index, dep.P1)
+		}
+		if dep.P2Index != nil && podgroup.Spec.ResolveMembers(dep.P2, dep.P2Index) == nil {
+			return fmt.Errorf("dependencies[%d]: p2Index %d is invalid for pod %s", i, *dep.P2Index, dep.P2)
+		}
+		// 同一模板的不同副本之间可以互相依赖
+		if dep.P1 == dep.P2 && (!podgroup.Spec.FindTemplate(dep.P1).IsReplicated() ||
+			dep.P1Index != nil && dep.P2Index != nil && *dep.P1Index == *dep.P2Index) {
+			return fmt.Errorf("dependencies[%d]: pod %s can not depend on itself", i, dep.P1)
+		}
+		if dep.Weight != nil && dep.Weight.Sign() <= 0 {
+			return fmt.Errorf("dependencies[%d]: weight must be positive, got %s", i, dep.Weight.String())
+		}
+		switch dep.Direction {
+		case "", corev1.DirectionBoth, corev1.DirectionP1ToP2, corev1.DirectionP2ToP1:
+		default:
+			return fmt.Errorf("dependencies[%d]: unknown direction %s", i, dep.Direction)
+		}
+	}
+
+	for i, group := range podgroup.Spec.AntiAffinity {
+		for _, podName := range group.Pods {
+			if !m[podName] {
+				return fmt.Errorf("antiAffinity[%d]: pod %s is not found in podList", i, podName)
+			}
+		}
+	}
+	if maxPods := podgroup.Spec.MaxPodsPerNode; maxPods != nil && *maxPods < 1 {
+		return fmt.Errorf("maxPodsPerNode must be positive, got %d", *maxPods)
+	}
+
+	if opts := podgroup.Spec.SolverOptions; opts != nil {
+		if err := validateAnnealingOptions("solverOptions.annealing", opts.Annealing); err != nil {
+			return err
+		}
+		if err := validateAnnealingOptions("solverOptions.relativeImprovement", opts.RelativeImprovement); err != nil {
+			return err
+		}
+		if ex := opts.Exhaustive; ex != nil {
+			if ex.Alpha != nil && ex.Alpha.Sign() < 0 {
+				return fmt.Errorf("solverOptions.exhaustive.alpha must not be negative")
+			}
+			if ex.Beta != nil && ex.Beta.Sign() < 0 {
+				return fmt.Errorf("solverOptions.exhaustive.beta must not be negative")
+			}
+		}
+	}
+
+	if rs := podgroup.Spec.Rescheduling; rs != nil {
+		if t := rs.ImprovementThreshold; t != nil {
+			if v := t.AsApproximateFloat64(); v <= 0 || v >= 1 {
+				return fmt.Errorf("rescheduling.improvementThreshold must be in range (0, 1), got %s", t.String())
+			}
+		}
+		if n := rs.MaxUnavailable; n != nil && *n < 1 {
+			return fmt.Errorf("rescheduling.maxUnavailable must be positive, got %d", *n)
+		}
+	}
+
+	// 复现placement的注解
+	if value, ok := podgroup.Annotations[corev1.ReplaySeedAnnotation]; ok {
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return fmt.Errorf("annotation %s must be an integer, got %s", corev1.ReplaySeedAnnotation, value)
+		}
+	}
+	if value, ok := podgroup.Annotations[corev1.ReplayWindowEndAnnotation]; ok {
+		if _, err := time.Parse(time.RFC3339, value); err != nil {
+			return fmt.Errorf("annotation %s must be an RFC3339 time, got %s", corev1.ReplayWindowEndAnnotation, value)
+		}
+	}
+	if _, ok := podgroup.Annotations[corev1.ReplayInputDigestAnnotation]; ok {
+		if _, ok := podgroup.Annotations[corev1.ReplaySeedAnnotation]; !ok {
+			return fmt.Errorf("annotation %s requires annotation %s", corev1.ReplayInputDigestAnnotation, corev1.ReplaySeedAnnotation)
+		}
+	}
+
+	return nil
+}
+
+// validateWorkload 校验成员的工作负载类型，工作负载与成员同名，并通过PodGroup名称与成员名称两个标签选择成员Pod
+// Deployment与StatefulSet只支持Always重启策略，Job不支持Always重启策略
+func validateWorkload(podgroup *corev1.PodGroup, pod *corev1.PodTemplate) error {
+	restartPolicy := pod.Spec.RestartPolicy
+	switch pod.Workload {
+	case "", corev1.PodWorkload:
+		return nil
+	case corev1.DeploymentWorkload, corev1.StatefulSetWorkload:
+		if restartPolicy != "" && restartPolicy != v1.RestartPolicyAlways {
+			return fmt.Errorf("pod %s: restartPolicy %s is not supported by workload %s", pod.Metadata.Name, restartPolicy, pod.Workload)
+		}
+	case corev1.JobWorkload:
+		if restartPolicy == v1.RestartPolicyAlways {
+			return fmt.Errorf("pod %s: restartPolicy %s is not supported by workload %s", pod.Metadata.Name, restartPolicy, pod.Workload)
+		}
+	default:
+		return fmt.Errorf("pod %s: unknown workload %s", pod.Metadata.Name, pod.Workload)
+	}
+	if errs := validation.IsValidLabelValue(podgroup.GetName()); len(errs) > 0 {
+		return fmt.Errorf("PodGroup name %s can not be used as a label value with workload members: %s", podgroup.GetName(), strings.Join(errs, "; "))
+	}
+	for _, name := range pod.MemberNames() {
+		if errs := validation.IsDNS1123Label(name); len(errs) > 0 {
+			return fmt.Errorf("pod name %s can not be used as a %s name: %s", name, pod.Workload, strings.Join(errs, "; "))
+		}
+	}
+	return nil
+}
+
+// validateServiceMode 启用spec.serviceMode时，PodGroup名称、模板名称与成员Pod名称会作为成员Pod的标签值，
+// perMember模式下成员Pod名称、perRole模式下模板名称会作为Service的名称
+func validateServiceMode(podgroup *corev1.PodGroup, templates, members map[string]bool) error {
+	var serviceNames map[string]bool
+	switch podgroup.Spec.ServiceMode {
+	case "", corev1.NoneServiceMode:
+		return nil
+	case corev1.PerMemberServiceMode:
+		serviceNames = members
+	case corev1.PerRoleServiceMode:
+		serviceNames = templates
+	default:
+		return fmt.Errorf("unknown serviceMode %s", podgroup.Spec.ServiceMode)
+	}
+	if errs := validation.IsValidLabelValue(podgroup.GetName()); len(errs) > 0 {
+		return fmt.Errorf("PodGroup name %s can not be used as a label value with serviceMode %s: %s", podgroup.GetName(), podgroup.Spec.ServiceMode, strings.Join(errs, "; "))
+	}
+	for _, names := range []map[string]bool{templates, members} {
+		for name := range names {
+			if errs := validation.IsValidLabelValue(name); len(errs) > 0 {
+				return fmt.Errorf("pod name %s can not be used as a label value with serviceMode %s: %s", name, podgroup.Spec.ServiceMode, strings.Join(errs, "; "))
+			}
+		}
+	}
+	for name := range serviceNames {
+		if errs := validation.IsDNS1035Label(name); len(errs) > 0 {
+			return fmt.Errorf("pod name %s can not be used as a Service name with serviceMode %s: %s", name, podgroup.Spec.ServiceMode, strings.Join(errs, "; "))
+		}
+	}
+	return nil
+}
+
+// validateAnnealingOptions 校验模拟退火参数，path为参数在spec中的路径
+func validateAnnealingOptions(path string, opts *corev1.AnnealingOptions) error {
+	if opts == nil {
+		return nil
+	}
+	if opts.InitTemp != nil && opts.InitTemp.Sign() <= 0 {
+		return fmt.Errorf("%s.initTemp must be positive", path)
+	}
+	if opts.FinalTemp != nil && opts.FinalTemp.Sign() <= 0 {
+		return fmt.Errorf("%s.finalTemp must be positive", path)
+	}
+	if opts.InitTemp != nil && opts.FinalTemp != nil && opts.FinalTemp.Cmp(*opts.InitTemp) >= 0 {
+		return fmt.Errorf("%s.finalTemp must be less than initTemp", path)
+	}
+	if opts.CoolingRate != nil {
+		rate := opts.CoolingRate.AsApproximateFloat64()
+		if rate <= 0 || rate >= 1 {
+			return fmt.Errorf("%s.coolingRate must be in range (0, 1), got %s", path, opts.CoolingRate.String())
+		}
+	}
+	if opts.Alpha != nil && opts.Alpha.Sign() < 0 {
+		return fmt.Errorf("%s.alpha must not be negative", path)
+	}
+	if opts.Beta != nil && opts.Beta.Sign() < 0 {
+		return fmt.Errorf("%s.beta must not be negative", path)
+	}
+	if opts.Chains != nil && (*opts.Chains < 1 || *opts.Chains > 64) {
+		return fmt.Errorf("%s.chains must be in range [1, 64], got %d", path, *opts.Chains)
+	}
+	if opts.TimeLimitSeconds != nil && *opts.TimeLimitSeconds < 1 {
+		return fmt.Errorf("%s.timeLimitSeconds must be positive, got %d", path, *opts.TimeLimitSeconds)
+	}
+	return nil
+}
@@ -17,8 +17,12 @@ limitations under the License.
 package v1
 
 import (
+	"strings"
+
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 
 	corev1 "github.com/SMALL-head/podGroup/api/v1"
 	// TODO (user): Add any additional imports if needed
@@ -46,26 +50,227 @@ var _ = Describe("PodGroup Webhook", func() {
 	})
 
 	Context("When creating or updating PodGroup under Validating Webhook", func() {
-		// TODO (user): Add logic for validating webhooks
-		// Example:
-		// It("Should deny creation if a required field is missing", func() {
-		//     By("simulating an invalid creation scenario")
-		//     obj.SomeRequiredField = ""
-		//     Expect(validator.ValidateCreate(ctx, obj)).Error().To(HaveOccurred())
-		// })
-		//
-		// It("Should admit creation if all required fields are present", func() {
-		//     By("simulating an invalid creation scenario")
-		//     obj.SomeRequiredField = "valid_value"
-		//     Expect(validator.ValidateCreate(ctx, obj)).To(BeNil())
-		// })
-		//
-		// It("Should validate updates correctly", func() {
-		//     By("simulating a valid update scenario")
-		//     oldObj.SomeRequiredField = "updated_value"
-		//     obj.SomeRequiredField = "updated_value"
-		//     Expect(validator.ValidateUpdate(ctx, oldObj, obj)).To(BeNil())
-		// })
+		BeforeEach(func() {
+			obj.Spec.PodList = []corev1.PodTemplate{
+				{Metadata: corev1.PodMetadata{Name: "pod1"}},
+				{Metadata: corev1.PodMetadata{Name: "pod2"}},
+			}
+		})
+
+		It("Should deny creation if a pod name is duplicated", func() {
+			obj.Spec.PodList = append(obj.Spec.PodList, corev1.PodTemplate{Metadata: corev1.PodMetadata{Name: "pod1"}})
+			Expect(validator.ValidateCreate(ctx, obj)).Error().To(HaveOccurred())
+		})
+
+		It("Should deny creation if a dependency refers to an unknown pod", func() {
+			obj.Spec.Dependencies = []corev1.Dependency{{P1: "pod1", P2: "pod3"}}
+			Expect(validator.ValidateCreate(ctx, obj)).Error().To(HaveOccurred())
+		})
+
+		It("Should deny creation if a dependency weight is not positive", func() {
+			weight := resource.MustParse("0")
+			obj.Spec.Dependencies = []corev1.Dependency{{P1: "pod1", P2: "pod2", Weight: &weight}}
+			Expect(validator.ValidateCreate(ctx, obj)).Error().To(HaveOccurred())
+		})
+
+		It("Should admit creation with weighted and directional dependencies", func() {
+			weight := resource.MustParse("1.5k")
+			obj.Spec.Dependencies = []corev1.Dependency{
+				{P1: "pod1", P2: "pod2", Weight: &weight, Direction: corev1.DirectionP1ToP2},
+			}
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should deny creation if the annealing cooling rate is out of range", func() {
+			rate := resource.MustParse("1.5")
+			obj.Spec.PlacementStrategy = corev1.AnnealingStrategy
+			obj.Spec.SolverOptions = &corev1.SolverOptions{
+				Annealing: &corev1.AnnealingOptions{CoolingRate: &rate},
+			}
+			Expect(validator.ValidateCreate(ctx, obj)).Error().To(HaveOccurred())
+		})
+
+		It("Should validate the annealing chains and time limit", func() {
+			chains, timeLimit := int32(8), int32(30)
+			obj.Spec.PlacementStrategy = corev1.RelativeImprovementStrategy
+			obj.Spec.SolverOptions = &corev1.SolverOptions{
+				RelativeImprovement: &corev1.AnnealingOptions{Chains: &chains, TimeLimitSeconds: &timeLimit},
+			}
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+
+			chains = 0
+			Expect(validator.ValidateCreate(ctx, obj)).Error().To(HaveOccurred())
+			chains = 8
+
+			timeLimit = 0
+			Expect(validator.ValidateCreate(ctx, obj)).Error().To(HaveOccurred())
+		})
+
+		It("Should deny creation if minMember exceeds the number of pods", func() {
+			minMember := int32(3)
+			obj.Spec.MinMember = &minMember
+			Expect(validator.ValidateCreate(ctx, obj)).Error().To(HaveOccurred())
+
+			minMember = 2
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should deny podAffinity rendering if a pod name is not a valid label value", func() {
+			obj.Spec.PlacementRendering = corev1.PodAffinityRendering
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+
+			obj.Spec.PodList = append(obj.Spec.PodList, corev1.PodTemplate{Metadata: corev1.PodMetadata{Name: strings.Repeat("p", 64)}})
+			Expect(validator.ValidateCreate(ctx, obj)).Error().To(HaveOccurred())
+		})
+
+		It("Should deny serviceMode if a pod name can not be used as a Service name", func() {
+			obj.Spec.ServiceMode = corev1.PerMemberServiceMode
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+
+			// Service名称不能包含'.'
+			obj.Spec.PodList = append(obj.Spec.PodList, corev1.PodTemplate{Metadata: corev1.PodMetadata{Name: "db.primary"}})
+			Expect(validator.ValidateCreate(ctx, obj)).Error().To(HaveOccurred())
+			obj.Spec.ServiceMode = corev1.NoneServiceMode
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+
+			// perRole模式下只校验模板名称
+			replicas := int32(2)
+			obj.Spec.PodList[len(obj.Spec.PodList)-1] = corev1.PodTemplate{Metadata: corev1.PodMetadata{Name: "db"}, Replicas: &replicas}
+			obj.Spec.ServiceMode = corev1.PerRoleServiceMode
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+
+			obj.Spec.ServiceMode = "perNode"
+			Expect(validator.ValidateCreate(ctx, obj)).Error().To(HaveOccurred())
+		})
+
+		It("Should validate workload members", func() {
+			obj.Spec.PodList[0].Workload = corev1.DeploymentWorkload
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+
+			// Deployment只支持Always重启策略
+			obj.Spec.PodList[0].Spec.RestartPolicy = v1.RestartPolicyNever
+			Expect(validator.ValidateCreate(ctx, obj)).Error().To(HaveOccurred())
+			obj.Spec.PodList[0].Workload = corev1.JobWorkload
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+			obj.Spec.PodList[0].Spec.RestartPolicy = v1.RestartPolicyAlways
+			Expect(validator.ValidateCreate(ctx, obj)).Error().To(HaveOccurred())
+			obj.Spec.PodList[0].Spec.RestartPolicy = ""
+
+			// 工作负载名称即成员名称
+			obj.Spec.PodList = append(obj.Spec.PodList, corev1.PodTemplate{Metadata: corev1.PodMetadata{Name: "db.primary"}, Workload: corev1.StatefulSetWorkload})
+			Expect(validator.ValidateCreate(ctx, obj)).Error().To(HaveOccurred())
+			obj.Spec.PodList[len(obj.Spec.PodList)-1].Workload = corev1.PodWorkload
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+
+			obj.Spec.PodList[0].Workload = "DaemonSet"
+			Expect(validator.ValidateCreate(ctx, obj)).Error().To(HaveOccurred())
+		})
+
+		It("Should deny creation if topologyKey is not a valid label key", func() {
+			obj.Spec.TopologyKey = "topology.kubernetes.io/zone"
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+
+			obj.Spec.TopologyKey = "zone/"
+			Expect(validator.ValidateCreate(ctx, obj)).Error().To(HaveOccurred())
+		})
+
+		It("Should deny creation if an antiAffinity group refers to an unknown pod", func() {
+			obj.Spec.AntiAffinity = []corev1.AntiAffinityGroup{{Name: "replicas", Pods: []string{"pod1", "pod2"}}}
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+
+			obj.Spec.AntiAffinity[0].Pods = append(obj.Spec.AntiAffinity[0].Pods, "pod3")
+			Expect(validator.ValidateCreate(ctx, obj)).Error().To(HaveOccurred())
+		})
+
+		It("Should validate replicated pods and indexed dependencies", func() {
+			replicas, index := int32(2), int32(1)
+			obj.Spec.PodList = append(obj.Spec.PodList, corev1.PodTemplate{Metadata: corev1.PodMetadata{Name: "worker"}, Replicas: &replicas})
+			obj.Spec.Dependencies = []corev1.Dependency{
+				{P1: "worker", P2: "pod1"},
+				{P1: "worker", P2: "worker"},
+				{P1: "worker", P1Index: &index, P2: "pod2"},
+			}
+			minMember := int32(4)
+			obj.Spec.MinMember = &minMember
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+
+			// 下标越界
+			index = 2
+			Expect(validator.ValidateCreate(ctx, obj)).Error().To(HaveOccurred())
+			index = 1
+
+			// 没有设置replicas的模板不能指定下标
+			obj.Spec.Dependencies = append(obj.Spec.Dependencies, corev1.Dependency{P1: "pod1", P1Index: &index, P2: "pod2"})
+			Expect(validator.ValidateCreate(ctx, obj)).Error().To(HaveOccurred())
+			obj.Spec.Dependencies = obj.Spec.Dependencies[:3]
+
+			// 展开后的成员Pod名称与其他模板重复
+			obj.Spec.PodList = append(obj.Spec.PodList, corev1.PodTemplate{Metadata: corev1.PodMetadata{Name: "worker-1"}})
+			Expect(validator.ValidateCreate(ctx, obj)).Error().To(HaveOccurred())
+		})
+
+		It("Should validate rescheduling policy", func() {
+			threshold, maxUnavailable := resource.MustParse("0.3"), int32(1)
+			obj.Spec.Rescheduling = &corev1.ReschedulingPolicy{ImprovementThreshold: &threshold, MaxUnavailable: &maxUnavailable}
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+
+			threshold = resource.MustParse("1")
+			Expect(validator.ValidateCreate(ctx, obj)).Error().To(HaveOccurred())
+			threshold = resource.MustParse("0.3")
+
+			maxUnavailable = 0
+			Expect(validator.ValidateCreate(ctx, obj)).Error().To(HaveOccurred())
+		})
+
+		It("Should validate replay annotations", func() {
+			obj.Annotations = map[string]string{
+				corev1.ReplaySeedAnnotation:      "-42",
+				corev1.ReplayWindowEndAnnotation: "2025-06-01T08:00:00Z",
+			}
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+
+			obj.Annotations[corev1.ReplaySeedAnnotation] = "seed"
+			Expect(validator.ValidateCreate(ctx, obj)).Error().To(HaveOccurred())
+			obj.Annotations[corev1.ReplaySeedAnnotation] = "42"
+
+			obj.Annotations[corev1.ReplayWindowEndAnnotation] = "2025-06-01 08:00"
+			Expect(validator.ValidateCreate(ctx, obj)).Error().To(HaveOccurred())
+			delete(obj.Annotations, corev1.ReplayWindowEndAnnotation)
+
+			// 输入摘要只在复现placement时使用
+			obj.Annotations[corev1.ReplayInputDigestAnnotation] = "0123456789abcdef"
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+			delete(obj.Annotations, corev1.ReplaySeedAnnotation)
+			Expect(validator.ValidateCreate(ctx, obj)).Error().To(HaveOccurred())
+		})
+
+		It("Should validate updates correctly", func() {
+			obj.Spec.Dependencies = []corev1.Dependency{{P1: "pod1", P2: "pod2", Direction: "Unknown"}}
+			Expect(validator.ValidateUpdate(ctx, oldObj, obj)).Error().To(HaveOccurred())
+		})
+
+		It("Should deny updates to the template of Job members", func() {
+			obj.Spec.PodList[1].Workload = corev1.JobWorkload
+			obj.Spec.PodList[1].Spec = v1.PodSpec{
+				RestartPolicy: v1.RestartPolicyNever,
+				Containers:    []v1.Container{{Name: "main", Image: "busybox:1.36"}},
+			}
+			oldObj = obj.DeepCopy()
+			Expect(validator.ValidateUpdate(ctx, oldObj, obj)).Error().NotTo(HaveOccurred())
+
+			// replicas可以修改
+			replicas := int32(2)
+			obj.Spec.PodList[1].Replicas = &replicas
+			Expect(validator.ValidateUpdate(ctx, oldObj, obj)).Error().NotTo(HaveOccurred())
+
+			obj.Spec.PodList[1].Spec.Containers[0].Image = "busybox:1.37"
+			Expect(validator.ValidateUpdate(ctx, oldObj, obj)).Error().To(HaveOccurred())
+
+			// 改为其他工作负载类型时由controller重新创建
+			obj.Spec.PodList[1].Workload = corev1.DeploymentWorkload
+			obj.Spec.PodList[1].Spec.RestartPolicy = v1.RestartPolicyAlways
+			Expect(validator.ValidateUpdate(ctx, oldObj, obj)).Error().NotTo(HaveOccurred())
+		})
 	})
 
 })